@@ -0,0 +1,98 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/rangertaha/hxe/internal"
+	ac "github.com/rangertaha/hxe/internal/agent/client"
+	"github.com/urfave/cli/v3"
+)
+
+var agentCmd *cli.Command = &cli.Command{
+	Name:                  "agent",
+	Usage:                 "Agent management",
+	Description:           `Manage the running agent.`,
+	Version:               internal.VERSION,
+	EnableShellCompletion: true,
+	Suggest:               true,
+	Flags:                 clientFlags(),
+	Before:                connect,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		cli.ShowSubcommandHelpAndExit(cmd, 1)
+		return nil
+	},
+	Commands: []*cli.Command{
+		{
+			Name:  "reload",
+			Usage: "Reload the agent configuration",
+			Description: `Re-read agent.hcl and the programs directory. Added programs are
+started, removed programs are stopped and running programs are restarted
+only when the way they are executed changed.`,
+			Metadata: fleetCommand,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				if len(agents) < 2 {
+					resp, err := hxeClient.Agent.Reload()
+					if err != nil {
+						return fmt.Errorf("failed to reload agent: %w", err)
+					}
+					ac.PrintChanges(resp)
+					return nil
+				}
+
+				failed := 0
+				for i, agent := range agents {
+					if i > 0 {
+						fmt.Println()
+					}
+					fmt.Printf("# %s\n", agent)
+					resp, err := hxeClient.For(agent).Agent.Reload()
+					if err != nil {
+						failed++
+						fmt.Fprintf(os.Stderr, "%s: failed to reload agent: %v\n", agent, err)
+						continue
+					}
+					ac.PrintChanges(resp)
+				}
+				if failed > 0 {
+					return fmt.Errorf("failed to reload %d of %d agents", failed, len(agents))
+				}
+				return nil
+			},
+		},
+		{
+			Name:  "list",
+			Usage: "List the agents of the fleet",
+			Description: `List the agents that answer through the server of the profile: the
+agent itself, and the agents joined to it as a hub by leafnodes or in a
+cluster.`,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				infos, err := hxeClient.Agent.Discover(ac.DiscoverTimeout)
+				if err != nil {
+					return err
+				}
+				ac.PrintAgents(infos)
+				return nil
+			},
+		},
+	},
+}
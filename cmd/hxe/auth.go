@@ -0,0 +1,230 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rangertaha/hxe/internal"
+	"github.com/rangertaha/hxe/internal/auth"
+	"github.com/rangertaha/hxe/internal/config"
+	"github.com/urfave/cli/v3"
+)
+
+var authCmd *cli.Command = &cli.Command{
+	Name:  "auth",
+	Usage: "NATS operator, account and user management",
+	Description: `Manage decentralized authentication: an operator and account trusted by
+the agent, and user JWTs with their nkey seeds in creds files that
+clients connect with. The files are kept in the auth_dir of the server
+block of agent.hcl, or auth next to agent.hcl.`,
+	Version:               internal.VERSION,
+	EnableShellCompletion: true,
+	Suggest:               true,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config",
+			Aliases: []string{"c"},
+			Usage:   "Agent configuration from `FILE`",
+		},
+		&cli.StringFlag{
+			Name:  "dir",
+			Usage: "Auth `DIR` instead of the one of the agent configuration",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		cli.ShowSubcommandHelpAndExit(cmd, 1)
+		return nil
+	},
+	Commands: []*cli.Command{
+		{
+			Name:      "init",
+			Usage:     "Create the operator, accounts and admin user",
+			UsageText: "hxe auth init [--force]",
+			Description: `Create an operator, a system account, the HXE account that agents and
+clients use, and the admin user with every permission. With --force an
+existing operator is replaced, which invalidates all of its users.`,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "force",
+					Usage: "Replace an existing operator",
+				},
+			},
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				store, err := authStore(cmd)
+				if err != nil {
+					return err
+				}
+				if err := store.Init(cmd.Bool("force")); err != nil {
+					return err
+				}
+
+				fmt.Printf("Created operator %s and account %s in %s\n", auth.OPERATOR, auth.ACCOUNT, store.Dir)
+				fmt.Printf("Created user %s: %s/users/%s.creds\n\n", auth.ADMIN, store.Dir, auth.ADMIN)
+				fmt.Printf("Enable it in the server block of agent.hcl and restart the agent:\n\n")
+				fmt.Printf("  auth_dir = %q\n\n", store.Dir)
+				fmt.Printf("and connect with the credentials of a user:\n\n")
+				fmt.Printf("  hxe profile add --creds %s/users/%s.creds --default %s\n", store.Dir, auth.ADMIN, auth.ADMIN)
+				return nil
+			},
+		},
+		{
+			Name:  "user",
+			Usage: "Manage users",
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				cli.ShowSubcommandHelpAndExit(cmd, 1)
+				return nil
+			},
+			Commands: []*cli.Command{
+				{
+					Name:      "add",
+					Usage:     "Add a user",
+					UsageText: "hxe auth user add [options] <name>",
+					Description: `Issue a user JWT and write it with its seed to users/<name>.creds.
+Without permissions the user may use every subject. --read-only limits
+the user to listing programs and reading their state. Users may always
+receive replies to their requests.`,
+					Flags: []cli.Flag{
+						&cli.BoolFlag{
+							Name:  "read-only",
+							Usage: "Only allow listing programs and reading their state",
+						},
+						&cli.StringSliceFlag{
+							Name:  "publish",
+							Usage: "Subject the user may publish to, e.g. program.start",
+						},
+						&cli.StringSliceFlag{
+							Name:  "subscribe",
+							Usage: "Subject the user may subscribe to",
+						},
+						&cli.DurationFlag{
+							Name:  "expires",
+							Usage: "Expire the user after a duration, e.g. 720h",
+						},
+					},
+					Action: func(ctx context.Context, cmd *cli.Command) error {
+						name := cmd.Args().First()
+						if name == "" {
+							return fmt.Errorf("no user name given")
+						}
+						store, err := authStore(cmd)
+						if err != nil {
+							return err
+						}
+
+						perms := auth.Permissions{
+							Publish:   cmd.StringSlice("publish"),
+							Subscribe: cmd.StringSlice("subscribe"),
+						}
+						if cmd.Bool("read-only") {
+							perms.Publish = append(perms.Publish, auth.READ_ONLY...)
+						}
+						file, err := store.AddUser(name, perms, cmd.Duration("expires"))
+						if err != nil {
+							return err
+						}
+						fmt.Printf("Created user %s: %s\n", name, file)
+						return nil
+					},
+				},
+				{
+					Name:      "list",
+					Aliases:   []string{"ls"},
+					Usage:     "List users",
+					UsageText: "hxe auth user list",
+					Action: func(ctx context.Context, cmd *cli.Command) error {
+						store, err := authStore(cmd)
+						if err != nil {
+							return err
+						}
+						users, err := store.Users()
+						if err != nil {
+							return err
+						}
+
+						w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+						fmt.Fprintln(w, "NAME\tPUBLISH\tSUBSCRIBE\tEXPIRES\tREVOKED\tKEY")
+						for _, user := range users {
+							expires := "never"
+							if !user.Expires.IsZero() {
+								expires = user.Expires.Format("2006-01-02 15:04")
+							}
+							fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\n", user.Name, subjects(user.Publish),
+								subjects(user.Subscribe), expires, user.Revoked, user.PublicKey)
+						}
+						return w.Flush()
+					},
+				},
+				{
+					Name:      "remove",
+					Aliases:   []string{"rm"},
+					Usage:     "Revoke a user and delete its credentials",
+					UsageText: "hxe auth user remove <name>",
+					Description: `Revoke the user in the HXE account and delete its creds file. Running
+agents accept the user until they are restarted.`,
+					Action: func(ctx context.Context, cmd *cli.Command) error {
+						name := cmd.Args().First()
+						if name == "" {
+							return fmt.Errorf("no user name given")
+						}
+						store, err := authStore(cmd)
+						if err != nil {
+							return err
+						}
+						if err := store.RemoveUser(name); err != nil {
+							return err
+						}
+						fmt.Printf("Revoked user %s, restart the agent to apply\n", name)
+						return nil
+					},
+				},
+			},
+		},
+	},
+}
+
+// authStore returns the auth directory given with --dir, or the one of
+// the agent configuration
+func authStore(cmd *cli.Command) (*auth.Store, error) {
+	if dir := cmd.String("dir"); dir != "" {
+		return auth.New(dir), nil
+	}
+	conf, err := config.LoadAgentConfig(cmd.String("config"))
+	if err != nil {
+		return nil, err
+	}
+	dir := conf.Server.AuthDir
+	if dir == "" {
+		dir = auth.DIR
+	}
+	return auth.New(conf.Path(dir)), nil
+}
+
+// subjects formats a subject list, where an empty list allows every
+// subject
+func subjects(list []string) string {
+	if len(list) == 0 {
+		return "*"
+	}
+	return strings.Join(list, ",")
+}
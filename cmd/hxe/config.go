@@ -0,0 +1,249 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rangertaha/hxe/internal"
+	"github.com/rangertaha/hxe/internal/config"
+	"github.com/rangertaha/hxe/internal/config/schema"
+	"github.com/rangertaha/hxe/internal/config/validate"
+	"github.com/rangertaha/hxe/internal/services/program/loader"
+	"github.com/urfave/cli/v3"
+)
+
+var configCmd *cli.Command = &cli.Command{
+	Name:                  "config",
+	Usage:                 "Configuration management",
+	Description:           `Check and inspect agent, client and program configuration.`,
+	Version:               internal.VERSION,
+	EnableShellCompletion: true,
+	Suggest:               true,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		cli.ShowSubcommandHelpAndExit(cmd, 1)
+		return nil
+	},
+	Commands: []*cli.Command{
+		{
+			Name:      "show",
+			Usage:     "Show the agent and client configuration",
+			UsageText: "hxe config show [options] [agent|client|program <name>]",
+			Description: `Print the agent and client config files. With --effective, print every
+attribute after HXE_* environment variables and flags are applied, with
+the default, file, environment variable or flag each value came from.
+
+"program <name>" prints a program of the programs directory of the agent
+with its templates, variables and locals expanded.`,
+			Flags: append(clientFlags(),
+				&cli.BoolFlag{
+					Name:  "effective",
+					Usage: "Show the merged configuration and the source of each value",
+				},
+			),
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				if cmd.Args().First() == "program" {
+					return showProgram(cmd.String("config"), cmd.Args().Get(1))
+				}
+
+				show := map[string]bool{"agent": true, "client": true}
+				if what := cmd.Args().First(); what != "" {
+					if !show[what] {
+						return fmt.Errorf("unknown configuration %q, expected agent, client or program", what)
+					}
+					show = map[string]bool{what: true}
+				} else if cmd.String("config") != "" {
+					return fmt.Errorf("--config needs agent or client to show")
+				}
+
+				if show["agent"] {
+					path := ""
+					if cmd.Args().First() == "agent" {
+						path = cmd.String("config")
+					}
+					conf, err := config.LoadAgentConfig(path)
+					if err != nil {
+						return err
+					}
+					if err := printConfig(conf.File(), conf.Effective(), cmd.Bool("effective")); err != nil {
+						return err
+					}
+				}
+				if show["client"] {
+					conf, err := config.LoadClientConfig(
+						config.ClientDefaultOptions(),
+						config.ClientCliOpts(ctx, cmd),
+					)
+					if err != nil {
+						return err
+					}
+					if err := printConfig(conf.File(), conf.Effective(), cmd.Bool("effective")); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name:      "validate",
+			Usage:     "Validate configuration files",
+			UsageText: "hxe config validate [options] [paths...]",
+			Description: `Parse agent.hcl, client.hcl and program files, or programs directories,
+and check that services are known, program names are unique and the
+executables, users and groups of programs exist on this host. Without
+paths the configuration in the user config directory is validated.
+Exits non-zero when there are errors.`,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "json",
+					Usage: "Write the diagnostics as JSON",
+				},
+			},
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				report := validate.Validate(cmd.Args().Slice()...)
+
+				var err error
+				if cmd.Bool("json") {
+					err = report.WriteJSON(os.Stdout)
+				} else {
+					err = report.WriteText(os.Stdout, false)
+				}
+				if err != nil {
+					return err
+				}
+
+				if errors, _ := report.Errors(); errors > 0 {
+					return fmt.Errorf("configuration has %d errors", errors)
+				}
+				return nil
+			},
+		},
+		{
+			Name:      "schema",
+			Usage:     "Print the JSON Schema or attribute reference of config files",
+			UsageText: "hxe config schema [--markdown] agent|client|program > program.schema.json",
+			Description: `Print a JSON Schema of agent.hcl, client.hcl or program files in the HCL
+JSON syntax, to validate *.hcl.json files in editors and CI pipelines.
+With --markdown, print the reference of every block and attribute, of
+all three files when none is given.`,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "markdown",
+					Usage: "Print the attribute reference as markdown",
+				},
+			},
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				kinds := cmd.Args().Slice()
+				if len(kinds) == 0 {
+					if !cmd.Bool("markdown") {
+						return fmt.Errorf("expected %s", strings.Join(schema.Kinds, ", "))
+					}
+					kinds = schema.Kinds
+				}
+
+				for i, kind := range kinds {
+					file, err := schema.Get(kind)
+					if err != nil {
+						return err
+					}
+					if cmd.Bool("markdown") {
+						if i > 0 {
+							fmt.Println()
+						}
+						err = file.WriteMarkdown(os.Stdout)
+					} else {
+						enc := json.NewEncoder(os.Stdout)
+						enc.SetIndent("", "  ")
+						err = enc.Encode(file.JSONSchema())
+					}
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+	},
+}
+
+// printConfig prints a config file, or its effective values
+func printConfig(file string, values []config.Value, effective bool) error {
+	fmt.Printf("# %s\n", file)
+	if !effective {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, value := range values {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", value.Key, value.Value, value.Source)
+	}
+	fmt.Fprintln(w)
+	return w.Flush()
+}
+
+// showProgram prints a program from the programs directory of the agent
+// configured in file, fully expanded
+func showProgram(file, name string) error {
+	if name == "" {
+		return fmt.Errorf("no program name given")
+	}
+	conf, err := config.LoadAgentConfig(file)
+	if err != nil {
+		return err
+	}
+
+	dir := ""
+	for _, svc := range conf.Services {
+		if svc.ID == "programs" && svc.Directory != "" {
+			dir = conf.Path(svc.Directory)
+		}
+	}
+	if dir == "" {
+		return fmt.Errorf("the agent has no programs directory")
+	}
+
+	result := loader.Parse(dir)
+	if len(result.Diags) > 0 {
+		result.WriteDiagnostics(os.Stderr)
+	}
+	for _, prog := range result.Programs {
+		if prog.Name != name {
+			continue
+		}
+		data, err := loader.Encode(prog)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# %s\n", prog.Source)
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return fmt.Errorf("program %q is not defined in %s", name, dir)
+}
@@ -0,0 +1,111 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// stdout runs fn and returns what it printed
+func stdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = old }()
+
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+	err = fn()
+	w.Close()
+	return <-out, err
+}
+
+func TestConfigSchema(t *testing.T) {
+	tests := []struct {
+		args  []string
+		json  []string // titles of the printed JSON Schemas
+		lines []string // lines of the printed markdown
+		err   bool
+	}{
+		{args: []string{"program"}, json: []string{"Program files (programs/*.hcl)"}},
+		{args: []string{"agent", "client"}, json: []string{"Agent configuration (agent.hcl)", "Client configuration (client.hcl)"}},
+		{args: []string{"--markdown", "client"}, lines: []string{"# Client configuration"}},
+		{args: []string{"--markdown"}, lines: []string{"# Agent configuration", "# Client configuration", "# Program files"}},
+		{args: []string{}, err: true},
+		{args: []string{"server"}, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			out, err := stdout(t, func() error {
+				return configCmd.Run(context.Background(), append([]string{"config", "schema"}, tt.args...))
+			})
+			if tt.err {
+				if err == nil {
+					t.Error("schema succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var titles []string
+			dec := json.NewDecoder(strings.NewReader(out))
+			for tt.json != nil && dec.More() {
+				var schema struct {
+					Schema string `json:"$schema"`
+					Title  string `json:"title"`
+				}
+				if err := dec.Decode(&schema); err != nil {
+					t.Fatal(err)
+				}
+				if schema.Schema == "" {
+					t.Errorf("%s has no $schema", schema.Title)
+				}
+				titles = append(titles, schema.Title)
+			}
+			if strings.Join(titles, "\n") != strings.Join(tt.json, "\n") {
+				t.Errorf("schemas = %q, want %q", titles, tt.json)
+			}
+
+			var headings []string
+			for _, line := range strings.Split(out, "\n") {
+				if strings.HasPrefix(line, "# ") {
+					headings = append(headings, line)
+				}
+			}
+			if strings.Join(headings, "\n") != strings.Join(tt.lines, "\n") {
+				t.Errorf("references = %q, want %q", headings, tt.lines)
+			}
+		})
+	}
+}
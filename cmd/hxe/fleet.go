@@ -0,0 +1,131 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	ac "github.com/rangertaha/hxe/internal/agent/client"
+	"github.com/rangertaha/hxe/internal/client"
+	prog "github.com/rangertaha/hxe/internal/services/program/client"
+	"github.com/urfave/cli/v3"
+)
+
+// agents are the agents selected with --agent or --all-agents
+var agents []string
+
+// fleetCommand is the metadata of commands that fan out to several agents
+var fleetCommand = map[string]any{"fleet": true}
+
+// agentFlags select the agents of a fleet that commands run on
+func agentFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "agent",
+			Usage: "Run on the agent with this `ID`, reached through the server of the profile",
+		},
+		&cli.BoolFlag{
+			Name:  "all-agents",
+			Usage: "Run on every agent reached through the server of the profile",
+		},
+	}
+}
+
+// selectAgents selects the agents of --agent, or discovers every agent
+// with --all-agents. Commands that don't fan out refuse several agents.
+func selectAgents(ctx context.Context, cmd *cli.Command) error {
+	agents = cmd.StringSlice("agent")
+	if cmd.Bool("all-agents") {
+		if len(agents) > 0 {
+			return fmt.Errorf("--agent can not be combined with --all-agents")
+		}
+		infos, err := hxeClient.Agent.Discover(ac.DiscoverTimeout)
+		if err != nil {
+			return err
+		}
+		if len(infos) == 0 {
+			return fmt.Errorf("no agents answered through %s", clientConfig.URL())
+		}
+		for _, info := range infos {
+			agents = append(agents, info.ID)
+		}
+	}
+
+	switch len(agents) {
+	case 0:
+	case 1:
+		hxeClient = hxeClient.For(agents[0])
+	default:
+		target := cmd
+		if sub := cmd.Command(cmd.Args().First()); sub != nil {
+			target = sub
+		}
+		if target.Metadata["fleet"] != true {
+			return fmt.Errorf("%s runs on a single agent, select it with --agent", target.FullName())
+		}
+	}
+	return nil
+}
+
+// fanout calls fn with a client of every selected agent at once, or
+// with the client of the profile, and collects the responses in the
+// order of the agents. Agents that fail are reported on stderr.
+func fanout(fn func(c *client.Client) (*prog.Response, error)) (prog.Fleet, error) {
+	if len(agents) < 2 {
+		res, err := fn(hxeClient)
+		if res == nil {
+			return nil, err
+		}
+		return prog.Fleet{res}, err
+	}
+
+	responses := make([]*prog.Response, len(agents))
+	errs := make([]error, len(agents))
+	var wg sync.WaitGroup
+	for i, agent := range agents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i], errs[i] = fn(hxeClient.For(agent))
+			if responses[i] != nil {
+				responses[i].Agent = agent
+			}
+		}()
+	}
+	wg.Wait()
+
+	fleet := prog.Fleet{}
+	failed := 0
+	for i, agent := range agents {
+		if responses[i] != nil {
+			fleet = append(fleet, responses[i])
+		}
+		if errs[i] != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "%s: %v\n", agent, errs[i])
+		}
+	}
+	if failed > 0 {
+		return fleet, fmt.Errorf("failed on %d of %d agents", failed, len(agents))
+	}
+	return fleet, nil
+}
@@ -0,0 +1,195 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rangertaha/hxe/internal/agent"
+	"github.com/rangertaha/hxe/internal/client"
+	"github.com/rangertaha/hxe/internal/config"
+	prog "github.com/rangertaha/hxe/internal/services/program/client"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+	"github.com/urfave/cli/v3"
+)
+
+// freePort returns a port that nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// startFleet starts a hub agent and leafnode agents joined to it, and
+// connects hxeClient to the hub. Their servers only shut down in Start,
+// so they run until the tests exit, on ports of their own.
+func startFleet(t *testing.T, ids ...string) {
+	t.Helper()
+	port, leafPort := freePort(t), freePort(t)
+	servers := []config.Server{{
+		Host: "127.0.0.1", Port: port,
+		Leafnode: &config.ServerLeafnode{Host: "127.0.0.1", Port: leafPort},
+	}}
+	for range ids {
+		servers = append(servers, config.Server{
+			Host: "127.0.0.1", Port: -1,
+			Leafnode: &config.ServerLeafnode{Remotes: []string{fmt.Sprintf("nats-leaf://127.0.0.1:%d", leafPort)}},
+		})
+	}
+	for i, id := range append([]string{"hub"}, ids...) {
+		servers[i].Name = id
+		a, err := agent.New(&config.AgentConfig{ID: id, Server: servers[i]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(a.Stop)
+	}
+
+	var err error
+	hxeClient, err = client.New(&config.Client{Url: fmt.Sprintf("nats://127.0.0.1:%d", port), Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientConfig = hxeClient.Config
+	t.Cleanup(func() { hxeClient, clientConfig, agents = nil, nil, nil })
+
+	// wait until the hub routes requests to every agent
+	for _, id := range ids {
+		for deadline := time.Now().Add(5 * time.Second); ; {
+			if _, err := hxeClient.Agent.Agent(id).Info(); err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("agent %s is not reachable through the hub", id)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+}
+
+// info returns the agent that answered as a response with a program
+// named after it
+func info(c *client.Client) (*prog.Response, error) {
+	info, err := c.Agent.Info()
+	if err != nil {
+		return nil, err
+	}
+	return &prog.Response{Programs: []*models.Program{{Name: info.ID}}}, nil
+}
+
+func TestFleetCommands(t *testing.T) {
+	fleet := []string{"list", "start", "stop", "restart", "kill", "reload", "enable", "disable",
+		"delete", "status", "ps", "crashes", "history", "runs"}
+	single := []string{"export", "logs", "events"}
+
+	for _, name := range fleet {
+		if cmd := programCmd.Command(name); cmd == nil || cmd.Metadata["fleet"] != true {
+			t.Errorf("program %s does not run on several agents", name)
+		}
+	}
+	for _, name := range single {
+		if cmd := programCmd.Command(name); cmd == nil || cmd.Metadata["fleet"] == true {
+			t.Errorf("program %s runs on several agents", name)
+		}
+	}
+}
+
+func TestFanout(t *testing.T) {
+	startFleet(t, "web-01", "web-02")
+
+	agents = []string{"web-02", "missing", "web-01"}
+	fleet, err := fanout(info)
+	if err == nil || !strings.Contains(err.Error(), "failed on 1 of 3 agents") {
+		t.Errorf("error = %v, want the missing agent to fail", err)
+	}
+	var got []string
+	for _, res := range fleet {
+		if res.Agent != res.Programs[0].Name {
+			t.Errorf("agent %s answered for %s", res.Programs[0].Name, res.Agent)
+		}
+		got = append(got, res.Agent)
+	}
+	if want := []string{"web-02", "web-01"}; !slices.Equal(got, want) {
+		t.Errorf("responses from %v, want %v", got, want)
+	}
+
+	// a single agent is reached through the client of the profile
+	agents = nil
+	hxeClient = hxeClient.For("web-01")
+	if fleet, err = fanout(info); err != nil || len(fleet) != 1 || fleet[0].Programs[0].Name != "web-01" {
+		t.Errorf("fanout to one agent = %v, %v, want web-01", fleet, err)
+	}
+}
+
+func TestSelectAgents(t *testing.T) {
+	startFleet(t, "web-01", "web-02")
+	noop := func(ctx context.Context, cmd *cli.Command) error { return nil }
+
+	tests := []struct {
+		args   []string
+		agents []string
+		err    string
+	}{
+		{args: []string{"--all-agents", "ps"}, agents: []string{"hub", "web-01", "web-02"}},
+		{args: []string{"--agent", "web-01", "--agent", "web-02", "history"}, agents: []string{"web-01", "web-02"}},
+		{args: []string{"--agent", "web-01", "export"}, agents: []string{"web-01"}},
+		{args: []string{"--all-agents", "export"}, err: "runs on a single agent"},
+		{args: []string{"--all-agents", "--agent", "web-01", "ps"}, err: "can not be combined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			agents = nil
+			cmd := &cli.Command{
+				Name:  "program",
+				Flags: agentFlags(),
+				Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+					return ctx, selectAgents(ctx, cmd)
+				},
+				Commands: []*cli.Command{
+					{Name: "ps", Metadata: fleetCommand, Action: noop},
+					{Name: "history", Metadata: fleetCommand, Action: noop},
+					{Name: "export", Action: noop},
+				},
+			}
+			err := cmd.Run(context.Background(), append([]string{"program"}, tt.args...))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(agents, tt.agents) {
+				t.Errorf("agents = %v, want %v", agents, tt.agents)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rangertaha/hxe/internal"
+	"github.com/rangertaha/hxe/internal/services/program/importer"
+	"github.com/rangertaha/hxe/internal/services/program/loader"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+	"github.com/urfave/cli/v3"
+)
+
+var importCmd *cli.Command = &cli.Command{
+	Name:      "import",
+	Usage:     "Import programs from supervisord, systemd, Procfile or compose files",
+	UsageText: "hxe import [options] <file>... > programs.hcl",
+	Description: `Translate supervisord [program:x] sections, systemd .service units,
+Procfiles and docker-compose services into programs, and write them as
+program blocks for the programs directory, or create them on the agent
+with --create.
+
+The format is detected from the file name: *.service is a systemd unit,
+Procfile a Procfile, *compose*.yml a compose file and *.conf or *.ini a
+supervisord config. A warning is printed for every directive that has
+no program equivalent.`,
+	Version:               internal.VERSION,
+	EnableShellCompletion: true,
+	Suggest:               true,
+	Flags: append(clientFlags(),
+		&cli.StringFlag{
+			Name:    "format",
+			Aliases: []string{"f"},
+			Usage:   "Format of the files, one of " + strings.Join(importer.Names(), ", "),
+		},
+		&cli.BoolFlag{
+			Name:  "create",
+			Usage: "Create the programs on the agent instead of writing HCL",
+		},
+	),
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() == 0 {
+			return fmt.Errorf("no files given")
+		}
+
+		programs := []*models.Program{}
+		for _, path := range cmd.Args().Slice() {
+			res, err := importer.Import(cmd.String("format"), path)
+			if err != nil {
+				return err
+			}
+			for _, warning := range res.Warnings {
+				fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+			}
+			programs = append(programs, res.Programs...)
+		}
+
+		if !cmd.Bool("create") {
+			data, err := loader.Encode(programs...)
+			if err != nil {
+				return fmt.Errorf("failed to encode programs: %w", err)
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+
+		if _, err := connect(ctx, cmd); err != nil {
+			return err
+		}
+		return createPrograms(programs)
+	},
+}
+
+// createPrograms creates the imported programs on the agent, refusing
+// names that are already taken
+func createPrograms(programs []*models.Program) error {
+	existing, err := hxeClient.Programs.List("")
+	if err != nil {
+		return fmt.Errorf("failed to list programs: %w", err)
+	}
+	taken := map[string]bool{}
+	for _, prog := range existing.Programs {
+		taken[prog.Name] = true
+	}
+	for _, prog := range programs {
+		if taken[prog.Name] {
+			return fmt.Errorf("program %s already exists", prog.Name)
+		}
+		taken[prog.Name] = true
+	}
+
+	for _, prog := range programs {
+		resp, err := hxeClient.Programs.Create(prog)
+		if err != nil {
+			return fmt.Errorf("failed to create program %s: %w", prog.Name, err)
+		}
+		fmt.Printf("created program %s (%d)\n", prog.Name, resp.Programs[0].ID)
+	}
+	return nil
+}
@@ -54,7 +54,7 @@ func main() {
 		Copyright:             "Rangertaha",
 		EnableShellCompletion: true,
 		Suggest:               true,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.BoolFlag{
 				Name:   "debug",
 				Value:  false,
@@ -62,7 +62,7 @@ func main() {
 				Usage:  "Log debug messages for development",
 				Action: nil,
 			},
-		},
+		}, agentFlags()...),
 		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
 			if cmd.Bool("debug") {
 				log.SetGlobalLevel(zerolog.DebugLevel)
@@ -77,6 +77,14 @@ func main() {
 		Commands: []*cli.Command{
 			serverCmd,
 			programCmd,
+			runCmd,
+			agentCmd,
+			configCmd,
+			profileCmd,
+			authCmd,
+			importCmd,
+			planCmd,
+			applyCmd,
 		},
 	}
 
@@ -0,0 +1,208 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rangertaha/hxe/internal"
+	prog "github.com/rangertaha/hxe/internal/services/program/client"
+	"github.com/rangertaha/hxe/internal/services/program/loader"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+	"github.com/urfave/cli/v3"
+)
+
+// planFlags select the desired programs of plan and apply
+func planFlags() []cli.Flag {
+	return append(clientFlags(),
+		&cli.StringSliceFlag{
+			Name:    "file",
+			Aliases: []string{"f"},
+			Usage:   "Program files or directories with the desired programs",
+		},
+		selectorFlag(),
+		&cli.BoolFlag{
+			Name:  "prune",
+			Usage: "Delete the programs created through the API that are not in the files",
+		},
+	)
+}
+
+var planCmd *cli.Command = &cli.Command{
+	Name:      "plan",
+	Usage:     "Preview the changes of program files on the agent",
+	UsageText: "hxe plan -f programs.hcl [--prune [-l selector]] [-o plan.json]",
+	Description: `Diff the programs of the given files against the programs and processes
+of the agent, showing the programs to create, to update in place, to
+update and restart, and to delete.
+
+Programs that are not in the files are left alone unless --prune is
+given, which deletes the programs created through the API that are not in
+the files; limit them with a label selector. Programs loaded from the
+programs directory of the agent can not be planned. Save the plan with --out and
+apply it with "hxe apply plan.json".`,
+	Version:               internal.VERSION,
+	EnableShellCompletion: true,
+	Suggest:               true,
+	Flags: append(planFlags(),
+		&cli.StringFlag{
+			Name:    "out",
+			Aliases: []string{"o"},
+			Usage:   "Save the plan to a file for apply",
+		},
+	),
+	Before: connect,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		plan, err := makePlan(cmd)
+		if err != nil {
+			return err
+		}
+		plan.Print()
+
+		if out := cmd.String("out"); out != "" {
+			data, err := json.MarshalIndent(plan, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err = os.WriteFile(out, data, 0o600); err != nil {
+				return fmt.Errorf("failed to save plan: %w", err)
+			}
+			fmt.Printf("\nSaved the plan to %s, apply it with: hxe apply %s\n", out, out)
+		}
+		return nil
+	},
+}
+
+var applyCmd *cli.Command = &cli.Command{
+	Name:      "apply",
+	Usage:     "Apply the changes of program files on the agent",
+	UsageText: "hxe apply -f programs.hcl [--prune [-l selector]] [--auto-approve] | hxe apply plan.json",
+	Description: `Plan the given program files and, once confirmed, apply the plan in a
+single transaction, or apply a plan saved by "hxe plan --out". The agent
+refuses a plan when its programs changed since the plan was made. Only
+--prune deletes programs, see "hxe plan --help".`,
+	Version:               internal.VERSION,
+	EnableShellCompletion: true,
+	Suggest:               true,
+	Flags: append(planFlags(),
+		&cli.BoolFlag{
+			Name:  "auto-approve",
+			Usage: "Apply without asking for confirmation",
+		},
+	),
+	Before: connect,
+	Action: func(ctx context.Context, cmd *cli.Command) (err error) {
+		plan := &prog.Plan{}
+		if saved := cmd.Args().First(); saved != "" {
+			data, err := os.ReadFile(saved)
+			if err != nil {
+				return err
+			}
+			if err = json.Unmarshal(data, plan); err != nil {
+				return fmt.Errorf("failed to read plan %s: %w", saved, err)
+			}
+		} else if plan, err = makePlan(cmd); err != nil {
+			return err
+		}
+
+		plan.Print()
+		if len(plan.Changes) == 0 {
+			return nil
+		}
+		if !cmd.Bool("auto-approve") && !confirm("\nApply these changes? Only 'yes' is accepted: ") {
+			return fmt.Errorf("apply cancelled")
+		}
+
+		resp, err := hxeClient.Programs.Apply(plan)
+		if err != nil {
+			return fmt.Errorf("failed to apply plan: %w", err)
+		}
+		plan = resp.Plan
+		fmt.Printf("\nApplied: %d created, %d updated, %d restarted, %d deleted.\n",
+			plan.Count(prog.PLAN_CREATE), plan.Count(prog.PLAN_UPDATE), plan.Count(prog.PLAN_RESTART), plan.Count(prog.PLAN_DELETE))
+		return nil
+	},
+}
+
+// makePlan plans the programs of the --file flags on the agent
+func makePlan(cmd *cli.Command) (*prog.Plan, error) {
+	progs, err := desiredPrograms(cmd.StringSlice("file"))
+	if err != nil {
+		return nil, err
+	}
+	if cmd.String("selector") != "" && !cmd.Bool("prune") {
+		return nil, fmt.Errorf("--selector limits the programs --prune deletes, use it with --prune")
+	}
+	resp, err := hxeClient.Programs.Plan(progs, cmd.String("selector"), cmd.Bool("prune"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan programs: %w", err)
+	}
+	return resp.Plan, nil
+}
+
+// desiredPrograms parses program files, and the *.hcl files of
+// directories, as one programs directory
+func desiredPrograms(paths []string) ([]*models.Program, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no program files given, use --file")
+	}
+
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.hcl"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no program files in %s", strings.Join(paths, ", "))
+	}
+
+	result := loader.ParseFiles(filepath.Dir(files[0]), files...)
+	if len(result.Diags) > 0 {
+		result.WriteDiagnostics(os.Stderr)
+	}
+	if result.Diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse program files")
+	}
+	return result.Programs, nil
+}
+
+// confirm asks a question on stdout and reports whether it was answered
+// with yes
+func confirm(question string) bool {
+	fmt.Print(question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(answer) == "yes"
+}
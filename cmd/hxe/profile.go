@@ -0,0 +1,249 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rangertaha/hxe/internal"
+	"github.com/rangertaha/hxe/internal/config"
+	"github.com/urfave/cli/v3"
+)
+
+var profileCmd *cli.Command = &cli.Command{
+	Name:                  "profile",
+	Usage:                 "Client profile management",
+	Description:           `List, add and remove the connection profiles of client.hcl and choose the default profile.`,
+	Version:               internal.VERSION,
+	EnableShellCompletion: true,
+	Suggest:               true,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config",
+			Aliases: []string{"c"},
+			Usage:   "Configuration from `FILE`",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		cli.ShowSubcommandHelpAndExit(cmd, 1)
+		return nil
+	},
+	Commands: []*cli.Command{
+		{
+			Name:      "list",
+			Aliases:   []string{"ls"},
+			Usage:     "List profiles",
+			UsageText: "hxe profile list",
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				conf, err := loadProfiles(ctx, cmd, true)
+				if err != nil {
+					return err
+				}
+
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "DEFAULT\tNAME\tURL\tAUTH")
+				for _, client := range conf.Clients {
+					mark := ""
+					if client.Name == conf.Selected() {
+						mark = "*"
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", mark, client.Name, client.URL(), authMethod(client))
+				}
+				return w.Flush()
+			},
+		},
+		{
+			Name:      "show",
+			Usage:     "Show the effective settings of a profile",
+			UsageText: "hxe profile show [name]",
+			Description: `Print the URL the profile connects to and every attribute after HXE_*
+environment variables are applied, with where each value came from.
+Without a name the default profile is shown.`,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				conf, err := loadProfiles(ctx, cmd, true)
+				if err != nil {
+					return err
+				}
+				name := cmd.Args().First()
+				if name == "" {
+					name = conf.Selected()
+				}
+				client, err := conf.Profile(name)
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf("# %s\n", conf.File())
+				fmt.Printf("Profile: %s\n", client.Name)
+				fmt.Printf("URL:     %s\n\n", client.URL())
+
+				prefix := "client." + client.Name + "."
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "ATTRIBUTE\tVALUE\tSOURCE")
+				for _, value := range conf.Effective() {
+					if strings.HasPrefix(value.Key, prefix) {
+						fmt.Fprintf(w, "%s\t%s\t%s\n", strings.TrimPrefix(value.Key, prefix), value.Value, value.Source)
+					}
+				}
+				return w.Flush()
+			},
+		},
+		{
+			Name:      "add",
+			Usage:     "Add a profile",
+			UsageText: "hxe profile add [options] <name>",
+			Description: `Add a client block to client.hcl. Give either --url, or --host and
+--port, of the agent. With --default the profile becomes the default.`,
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "url", Usage: "NATS URL of the agent"},
+				&cli.StringFlag{Name: "host", Usage: "Host of the agent"},
+				&cli.IntFlag{Name: "port", Usage: "Port of the agent"},
+				&cli.BoolFlag{Name: "ipc", Usage: "Connect to the agent on this host"},
+				&cli.StringFlag{Name: "username", Aliases: []string{"u"}, Usage: "Username for authentication"},
+				&cli.StringFlag{Name: "password", Usage: "Password for authentication"},
+				&cli.StringFlag{Name: "token", Usage: "Token for authentication"},
+				&cli.DurationFlag{Name: "timeout", Usage: "Timeout for API requests"},
+				&cli.StringFlag{Name: "creds", Usage: "Credentials `FILE` of a user, written by hxe auth user add"},
+				&cli.StringFlag{Name: "tls-ca", Usage: "CA `FILE` to verify the agent with"},
+				&cli.StringFlag{Name: "tls-cert", Usage: "Client certificate `FILE`"},
+				&cli.StringFlag{Name: "tls-key", Usage: "Client key `FILE`"},
+				&cli.BoolFlag{Name: "default", Usage: "Make the profile the default"},
+			},
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				name := cmd.Args().First()
+				if name == "" {
+					return fmt.Errorf("no profile name given")
+				}
+				if cmd.IsSet("url") && (cmd.IsSet("host") || cmd.IsSet("port") || cmd.Bool("ipc")) {
+					return fmt.Errorf("--url can't be combined with --host, --port or --ipc")
+				}
+
+				conf, err := loadProfiles(ctx, cmd, false)
+				if err != nil {
+					return err
+				}
+				err = conf.AddProfile(&config.Client{
+					Name:     name,
+					UseIPC:   cmd.Bool("ipc"),
+					Host:     cmd.String("host"),
+					Url:      cmd.String("url"),
+					Port:     int(cmd.Int("port")),
+					Token:    cmd.String("token"),
+					Password: cmd.String("password"),
+					Username: cmd.String("username"),
+					Timeout:  cmd.Duration("timeout"),
+					Creds:    cmd.String("creds"),
+					TLSCA:    cmd.String("tls-ca"),
+					TLSCert:  cmd.String("tls-cert"),
+					TLSKey:   cmd.String("tls-key"),
+				})
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Added profile %s to %s\n", name, conf.File())
+
+				if cmd.Bool("default") {
+					if err := conf.SetDefault(name); err != nil {
+						return err
+					}
+					fmt.Printf("Default profile is now %s\n", name)
+				}
+				return nil
+			},
+		},
+		{
+			Name:      "remove",
+			Aliases:   []string{"rm"},
+			Usage:     "Remove a profile",
+			UsageText: "hxe profile remove <name>",
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				name := cmd.Args().First()
+				if name == "" {
+					return fmt.Errorf("no profile name given")
+				}
+				conf, err := loadProfiles(ctx, cmd, false)
+				if err != nil {
+					return err
+				}
+				if err := conf.RemoveProfile(name); err != nil {
+					return err
+				}
+				fmt.Printf("Removed profile %s from %s\n", name, conf.File())
+				return nil
+			},
+		},
+		{
+			Name:      "use",
+			Usage:     "Set the default profile",
+			UsageText: "hxe profile use <name>",
+			Description: `Set the profile that commands use when --profile isn't given. The
+default is saved in client.hcl.`,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				name := cmd.Args().First()
+				if name == "" {
+					return fmt.Errorf("no profile name given")
+				}
+				conf, err := loadProfiles(ctx, cmd, false)
+				if err != nil {
+					return err
+				}
+				if err := conf.SetDefault(name); err != nil {
+					return err
+				}
+				fmt.Printf("Default profile is now %s\n", name)
+				return nil
+			},
+		},
+	},
+}
+
+// loadProfiles loads client.hcl, or the file given with --config, with
+// HXE_* environment variables applied when env is set. Profiles are
+// edited without them.
+func loadProfiles(ctx context.Context, cmd *cli.Command, env bool) (*config.ClientConfig, error) {
+	options := []func(*config.ClientConfig) error{}
+	if file := cmd.String("config"); file != "" {
+		options = append(options, config.ClientFileOption(file))
+	} else {
+		options = append(options, config.ClientDefaultOptions())
+	}
+	if env {
+		options = append(options, config.ClientCliOpts(ctx, cmd))
+	}
+	return config.LoadClientConfig(options...)
+}
+
+// authMethod describes how a profile authenticates
+func authMethod(client *config.Client) string {
+	switch {
+	case client.Creds != "":
+		return "creds " + client.Creds
+	case client.Token != "":
+		return "token"
+	case client.Username != "":
+		return "user " + client.Username
+	case client.TLSCert != "":
+		return "certificate"
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rangertaha/hxe/internal/config"
+)
+
+func TestProfileCommands(t *testing.T) {
+	file := filepath.Join(t.TempDir(), config.CLIENT_CONFIG_FILE)
+	if err := os.WriteFile(file, []byte("client \"local\" {\n  ipc = true\n}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// each step runs on the file the previous steps edited. Flags keep
+	// their values between runs of a command, so add runs with flags once.
+	tests := []struct {
+		args     []string
+		out      string
+		profiles string // the profiles of the file, the default marked
+		err      string
+	}{
+		{args: []string{"add", "--url", "nats://prod:4222", "--default", "prod"}, out: "Default profile is now prod", profiles: "local *prod"},
+		{args: []string{"add"}, err: "no profile name given"},
+		{args: []string{"use", "local"}, out: "Default profile is now local", profiles: "*local prod"},
+		{args: []string{"use", "dev"}, err: `profile "dev" not found`},
+		{args: []string{"remove", "local"}, out: "Removed profile local", profiles: "prod"},
+		{args: []string{"rm", "local"}, err: `profile "local" not found`},
+		{args: []string{"add", "--url", "nats://dev:4222", "--host", "dev", "dev"}, err: "can't be combined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			out, err := stdout(t, func() error {
+				return profileCmd.Run(context.Background(), append([]string{"profile", "--config", file}, tt.args...))
+			})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out, tt.out) {
+				t.Errorf("output = %q, want %q", out, tt.out)
+			}
+
+			conf, err := config.LoadClientConfig(config.ClientFileOption(file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, client := range conf.Clients {
+				if client.Name == conf.Selected() {
+					names = append(names, "*"+client.Name)
+				} else {
+					names = append(names, client.Name)
+				}
+			}
+			if got := strings.Join(names, " "); got != tt.profiles {
+				t.Errorf("profiles = %s, want %s", got, tt.profiles)
+			}
+		})
+	}
+}
@@ -21,11 +21,15 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/rangertaha/hxe/internal"
 	"github.com/rangertaha/hxe/internal/client"
 	"github.com/rangertaha/hxe/internal/config"
+	prog "github.com/rangertaha/hxe/internal/services/program/client"
+	"github.com/rangertaha/hxe/internal/services/program/loader"
+	"github.com/rangertaha/hxe/internal/services/program/models"
 	"github.com/urfave/cli/v3"
 )
 
@@ -48,96 +52,14 @@ var programCmd *cli.Command = &cli.Command{
 	Copyright:             "Rangertaha",
 	EnableShellCompletion: true,
 	Suggest:               true,
-	Flags: []cli.Flag{
-		&cli.StringFlag{
-			Name:    "config",
-			Aliases: []string{"c"},
-			Hidden:  false,
-			Usage:   "Configuration from `FILE`",
-		},
-		&cli.StringFlag{
-			Name:        "profile",
-			Aliases:     []string{"p"},
-			Hidden:      false,
-			Value:       "default",
-			Usage:       "Client profile to use",
-			Destination: &profile,
-		},
-		&cli.StringFlag{
-			Name:        "username",
-			Aliases:     []string{"u"},
-			Value:       "",
-			Hidden:      false,
-			Usage:       "Username for authentication",
-			Destination: &username,
-		},
-		&cli.StringFlag{
-			Name:        "password",
-			Aliases:     []string{"p"},
-			Value:       "",
-			Hidden:      false,
-			Usage:       "Password for authentication",
-			Destination: &password,
-		},
-		&cli.StringFlag{
-			Name:        "url",
-			Value:       "",
-			Hidden:      false,
-			Usage:       "Hxe API server URL",
-			Destination: &serverURL,
-		},
-		&cli.DurationFlag{
-			Name:   "timeout",
-			Value:  10 * time.Second,
-			Hidden: false,
-			Usage:  "Timeout for API requests",
-		},
-	},
-	Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
-		// Create new client config
-		if clientConfig, err = config.NewClientConfig(
-			config.ClientDefaultOptions(),
-			config.ClientCliOpts(ctx, cmd),
-			config.ClientProfileOpts(profile),
-		); err != nil {
-			return ctx, err
-		}
-
-		// Create new client
-		hxeClient, err = client.New(clientConfig)
-		if err != nil {
-			return ctx, err
-		}
-
-		return ctx, nil
-	},
+	Flags:                 clientFlags(),
+	Before:                connect,
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		cli.ShowSubcommandHelpAndExit(cmd, 1)
 		return nil
 	},
 	Commands: []*cli.Command{
 
-		// {
-		// 	Name:  "run",
-		// 	Usage: "Run a service",
-		// 	Description: `Run a service. If no service name is provided,
-		// 		executes the command directly.`,
-		// 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		// 		// hxeClient := client.NewClient(serverURL, username, password)
-		// 		// if hxeClient, err = hxeClient.Login(); err != nil {
-		// 		// 	return err
-		// 		// }
-
-		// 		command := strings.Join(cmd.Args().Slice(), " ")
-		// 		prog, err := hxeClient.Service.Run(command)
-		// 		if err != nil {
-		// 			return fmt.Errorf("failed to run command: %w", err)
-		// 		}
-
-		// 		hxeClient.Service.Print(prog)
-		// 		return nil
-		// 	},
-		// },
 		// {
 		// 	Name:        "reload",
 		// 	Usage:       "Reload service configurations",
@@ -159,71 +81,394 @@ var programCmd *cli.Command = &cli.Command{
 			Name:        "list",
 			Usage:       "List all programs",
 			Description: `List all programs and their status.`,
+			Flags: []cli.Flag{
+				selectorFlag(),
+			},
+			Metadata: fleetCommand,
 			Action: func(ctx context.Context, cmd *cli.Command) error {
-				programs, err := hxeClient.Programs.List()
+				programs, err := fanout(func(c *client.Client) (*prog.Response, error) {
+					return c.Programs.List(cmd.String("selector"))
+				})
+				if len(programs) > 0 {
+					programs.Print()
+				}
 				if err != nil {
 					return fmt.Errorf("failed to list programs: %w", err)
 				}
-
-				// hxeClient.Programs.Print(programs)
-				programs.Print()
 				return nil
 			},
 		},
-		// {
-		// 	Name:        "start",
-		// 	Usage:       "Start a service",
-		// 	Description: `Start a service by name.`,
-		// 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		// 		// hxeClient := client.NewClient(serverURL, username, password)
-		// 		// if hxeClient, err = hxeClient.Login(); err != nil {
-		// 		// 	return err
-		// 		// }
-		// 		res, err := hxeClient.Service.Start(cmd.Args().First())
-		// 		if err != nil {
-		// 			return fmt.Errorf("failed to run command: %w", err)
-		// 		}
-		// 		hxeClient.Service.Print(res)
-
-		// 		return nil
-		// 	},
-		// },
-		// {
-		// 	Name:        "stop",
-		// 	Usage:       "Stop a service",
-		// 	Description: `Stop a service by name.`,
-		// 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		// 		// hxeClient := client.NewClient(serverURL, username, password)
-		// 		// if hxeClient, err = hxeClient.Login(); err != nil {
-		// 		// 	return err
-		// 		// }
+		{
+			Name:      "export",
+			Usage:     "Export programs as HCL",
+			UsageText: "hxe program export [selector] > programs.hcl",
+			Description: `Write all programs, or the programs matching a label selector, as
+formatted program blocks that the programs directory loads back as the
+same programs.`,
+			Flags: []cli.Flag{
+				selectorFlag(),
+			},
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				selector := cmd.String("selector")
+				if selector == "" {
+					selector = cmd.Args().First()
+				}
+				programs, err := hxeClient.Programs.List(selector)
+				if err != nil {
+					return fmt.Errorf("failed to list programs: %w", err)
+				}
 
-		// 		progs, err := hxeClient.Service.Stop(cmd.Args().First())
-		// 		if err != nil {
-		// 			return fmt.Errorf("failed to run command: %w", err)
-		// 		}
-		// 		hxeClient.Service.Print(progs)
-		// 		return nil
-		// 	},
-		// },
-		// {
-		// 	Name:        "restart",
-		// 	Usage:       "Restart a service",
-		// 	Description: `Restart a service by name.`,
-		// 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		// 		// hxeClient := client.NewClient(serverURL, username, password)
-		// 		// if hxeClient, err = hxeClient.Login(); err != nil {
-		// 		// 	return err
-		// 		// }
+				data, err := loader.Encode(programs.Programs...)
+				if err != nil {
+					return fmt.Errorf("failed to export programs: %w", err)
+				}
+				_, err = os.Stdout.Write(data)
+				return err
+			},
+		},
+		{
+			Name:        "start",
+			Usage:       "Start programs",
+			Description: `Start a program by name or ID, or the programs matching a label selector.`,
+			Flags: []cli.Flag{
+				selectorFlag(),
+			},
+			Metadata: fleetCommand,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				return bulk(func(c *client.Client) (*prog.Response, error) {
+					return c.Programs.Start(target(cmd))
+				})
+			},
+		},
+		{
+			Name:        "stop",
+			Usage:       "Stop programs",
+			Description: `Stop a program by name or ID, or the programs matching a label selector.`,
+			Flags: []cli.Flag{
+				selectorFlag(),
+			},
+			Metadata: fleetCommand,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				return bulk(func(c *client.Client) (*prog.Response, error) {
+					return c.Programs.Stop(target(cmd))
+				})
+			},
+		},
+		{
+			Name:  "restart",
+			Usage: "Restart programs",
+			Description: `Restart a program by name or ID, or the programs matching a label
+selector. With --max-unavailable or --wait-ready the selected programs
+are restarted in batches.`,
+			Flags: []cli.Flag{
+				selectorFlag(),
+				&cli.IntFlag{
+					Name:  "max-unavailable",
+					Usage: "Number of programs restarted at once",
+					Value: 1,
+				},
+				&cli.BoolFlag{
+					Name:  "wait-ready",
+					Usage: "Wait for each batch to become ready before the next one",
+				},
+				&cli.DurationFlag{
+					Name:  "rollout-timeout",
+					Usage: "Timeout for the whole rolling restart",
+					Value: 10 * time.Minute,
+				},
+			},
+			Metadata: fleetCommand,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				selector := cmd.String("selector")
+				if selector != "" && (cmd.IsSet("max-unavailable") || cmd.IsSet("wait-ready")) {
+					// every agent rolls its own programs
+					res, err := fanout(func(c *client.Client) (*prog.Response, error) {
+						return c.Programs.RollingRestart(selector, &prog.Rollout{
+							MaxUnavailable: int(cmd.Int("max-unavailable")),
+							WaitReady:      cmd.Bool("wait-ready"),
+							Timeout:        cmd.Duration("rollout-timeout"),
+						})
+					})
+					if len(res) > 0 {
+						res.PrintStatus()
+					}
+					if err != nil {
+						return fmt.Errorf("failed to restart programs: %w", err)
+					}
+					return nil
+				}
 
-		// 		progs, err := hxeClient.Service.Restart(cmd.Args().First())
-		// 		if err != nil {
-		// 			return fmt.Errorf("failed to run command: %w", err)
-		// 		}
-		// 		hxeClient.Service.Print(progs)
-		// 		return nil
-		// 	},
-		// },
+				return bulk(func(c *client.Client) (*prog.Response, error) {
+					return c.Programs.Restart(target(cmd))
+				})
+			},
+		},
+		{
+			Name:  "kill",
+			Usage: "Send a signal to programs",
+			Description: `Send a signal to the main process of a program by name or ID, or of
+the programs matching a label selector. With --group the signal is sent
+to the whole process group.`,
+			Flags: []cli.Flag{
+				selectorFlag(),
+				&cli.StringFlag{
+					Name:    "signal",
+					Aliases: []string{"s"},
+					Usage:   "Signal to send, by name or number",
+					Value:   "TERM",
+				},
+				&cli.BoolFlag{
+					Name:    "group",
+					Aliases: []string{"g"},
+					Usage:   "Signal the whole process group",
+				},
+			},
+			Metadata: fleetCommand,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				return bulk(func(c *client.Client) (*prog.Response, error) {
+					req := target(cmd)
+					req.Signal = cmd.String("signal")
+					req.Group = cmd.Bool("group")
+					return c.Programs.Signal(req)
+				})
+			},
+		},
+		{
+			Name:        "reload",
+			Usage:       "Reload programs",
+			Description: `Send programs their reload signal, SIGHUP unless reload_signal is set.`,
+			Flags: []cli.Flag{
+				selectorFlag(),
+			},
+			Metadata: fleetCommand,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				return bulk(func(c *client.Client) (*prog.Response, error) {
+					return c.Programs.Reload(target(cmd))
+				})
+			},
+		},
+		{
+			Name:        "enable",
+			Usage:       "Enable programs",
+			Description: `Enable programs to start automatically.`,
+			Flags: []cli.Flag{
+				selectorFlag(),
+			},
+			Metadata: fleetCommand,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				return bulk(func(c *client.Client) (*prog.Response, error) {
+					return c.Programs.Enable(target(cmd))
+				})
+			},
+		},
+		{
+			Name:        "disable",
+			Usage:       "Disable programs",
+			Description: `Disable programs from starting automatically.`,
+			Flags: []cli.Flag{
+				selectorFlag(),
+			},
+			Metadata: fleetCommand,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				return bulk(func(c *client.Client) (*prog.Response, error) {
+					return c.Programs.Disable(target(cmd))
+				})
+			},
+		},
+		{
+			Name:        "delete",
+			Usage:       "Delete programs",
+			Description: `Stop and delete a program by name or ID, or the programs matching a label selector.`,
+			Flags: []cli.Flag{
+				selectorFlag(),
+			},
+			Metadata: fleetCommand,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				return bulk(func(c *client.Client) (*prog.Response, error) {
+					return c.Programs.Delete(target(cmd))
+				})
+			},
+		},
+		{
+			Name:        "status",
+			Usage:       "Show program status",
+			Description: `Show the current lifecycle state of a program.`,
+			Metadata:    fleetCommand,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				res, err := fanout(func(c *client.Client) (*prog.Response, error) {
+					return c.Programs.Status(cmd.Args().First())
+				})
+				if len(res) > 0 {
+					res.PrintStatus()
+				}
+				if err != nil {
+					return fmt.Errorf("failed to get program status: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "ps",
+			Usage:       "Show program process tree",
+			Description: `Show the processes running under a program, with their CPU and memory usage.`,
+			Metadata:    fleetCommand,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				res, err := fanout(func(c *client.Client) (*prog.Response, error) {
+					return c.Programs.Ps(cmd.Args().First())
+				})
+				if len(res) > 0 {
+					res.PrintProcesses()
+				}
+				if err != nil {
+					return fmt.Errorf("failed to get program processes: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "crashes",
+			Usage:       "Show program crash reports",
+			Description: `Show the crash reports of a program, newest first.`,
+			Flags: []cli.Flag{
+				&cli.IntFlag{
+					Name:    "lines",
+					Aliases: []string{"n"},
+					Usage:   "Number of crash reports to show",
+					Value:   10,
+				},
+				&cli.BoolFlag{
+					Name:    "details",
+					Aliases: []string{"d"},
+					Usage:   "Show full reports with the output before each crash",
+				},
+			},
+			Metadata: fleetCommand,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				res, err := fanout(func(c *client.Client) (*prog.Response, error) {
+					return c.Programs.Crashes(cmd.Args().First(), int(cmd.Int("lines")))
+				})
+				switch {
+				case len(res) == 0:
+				case cmd.Bool("details"):
+					res.PrintCrashReports()
+				default:
+					res.PrintCrashes()
+				}
+				if err != nil {
+					return fmt.Errorf("failed to get program crashes: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "history",
+			Usage:       "Show program state history",
+			Description: `Show the state transitions of a program, newest first.`,
+			Flags: []cli.Flag{
+				&cli.IntFlag{
+					Name:    "lines",
+					Aliases: []string{"n"},
+					Usage:   "Number of transitions to show",
+					Value:   50,
+				},
+			},
+			Metadata: fleetCommand,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				res, err := fanout(func(c *client.Client) (*prog.Response, error) {
+					return c.Programs.History(cmd.Args().First(), int(cmd.Int("lines")))
+				})
+				if len(res) > 0 {
+					res.PrintHistory()
+				}
+				if err != nil {
+					return fmt.Errorf("failed to get program history: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "runs",
+			Usage:       "Show program run history",
+			Description: `Show the executions of a program with their exit status and restart decision.`,
+			Flags: []cli.Flag{
+				&cli.IntFlag{
+					Name:    "lines",
+					Aliases: []string{"n"},
+					Usage:   "Number of runs to show",
+					Value:   20,
+				},
+			},
+			Metadata: fleetCommand,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				res, err := fanout(func(c *client.Client) (*prog.Response, error) {
+					return c.Programs.Runs(cmd.Args().First(), int(cmd.Int("lines")))
+				})
+				if len(res) > 0 {
+					res.PrintRuns()
+				}
+				if err != nil {
+					return fmt.Errorf("failed to get program runs: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name:      "logs",
+			Usage:     "Show program output",
+			ArgsUsage: "[NAME]",
+			Description: `Replay the output of a program, or of every program, from the stream of
+the agent. The agent keeps output while the program runs and after it
+exits, up to the limits of the jetstream logs block.`,
+			Flags: []cli.Flag{
+				sinceFlag(),
+				followFlag(),
+				&cli.StringFlag{
+					Name:  "stream",
+					Usage: "Only show stdout or stderr",
+				},
+			},
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				r, err := replay(cmd)
+				if err != nil {
+					return err
+				}
+				name := cmd.Args().First()
+				switch stream := cmd.String("stream"); stream {
+				case "", "stdout", "stderr":
+				default:
+					return fmt.Errorf("unknown stream %s, expected stdout or stderr", stream)
+				}
+				err = hxeClient.Programs.Logs(ctx, r, name, cmd.String("stream"), func(line *models.Line) {
+					prog.PrintLine(line, name == "")
+				})
+				if err != nil {
+					return fmt.Errorf("failed to get program output: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name:      "events",
+			Usage:     "Show program events",
+			ArgsUsage: "[NAME]",
+			Description: `Replay the state changes, triggers, timeouts and crashes of a program, or
+of every program, from the stream of the agent.`,
+			Flags: []cli.Flag{
+				sinceFlag(),
+				followFlag(),
+			},
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				r, err := replay(cmd)
+				if err != nil {
+					return err
+				}
+				err = hxeClient.Programs.Events(ctx, r, cmd.Args().First(), prog.PrintEvent)
+				if err != nil {
+					return fmt.Errorf("failed to get program events: %w", err)
+				}
+				return nil
+			},
+		},
 		// {
 		// 	Name:        "get",
 		// 	Usage:       "Get service details by ID",
@@ -243,133 +488,180 @@ var programCmd *cli.Command = &cli.Command{
 		// 	},
 		// },
 		// {
-		// 	Name:        "reload",
-		// 	Usage:       "Reload configuration",
-		// 	Description: `Reload the configuration without restarting services.`,
+		// 	Name:        "shell",
+		// 	Usage:       "Open shell for a service",
+		// 	Description: `Open an interactive shell in the context of a service.`,
 		// 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		// 		// hxeClient := client.NewClient(serverURL, username, password)
 		// 		// if hxeClient, err = hxeClient.Login(); err != nil {
 		// 		// 	return err
 		// 		// }
 
-		// 		prog, err := hxeClient.Service.Reload(cmd.Args().First())
-		// 		if err != nil {
-		// 			return fmt.Errorf("failed to run command: %w", err)
-		// 		}
+		// 		// prog, err := hxeClient.Service.Shell(cmd.Args().Slice())...)
+		// 		// if err != nil {
+		// 		// 	return fmt.Errorf("failed to run command: %w", err)
+		// 		// }
 
-		// 		hxeClient.Service.Print(prog)
+		// 		// hxeClient.Service.PrintDetail(prog)
 		// 		return nil
 		// 	},
 		// },
-		// {
-		// 	Name:        "enable",
-		// 	Usage:       "Enable a service",
-		// 	Description: `Enable a service to start automatically.`,
-		// 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		// 		hxeClient := client.NewClient(serverURL, username, password)
-		// 		if hxeClient, err = hxeClient.Login(); err != nil {
-		// 			return err
-		// 		}
+	},
+}
 
-		// 		prog, err := hxeClient.Service.Enable(cmd.Args().First())
-		// 		if err != nil {
-		// 			return fmt.Errorf("failed to run command: %w", err)
-		// 		}
+// selectorFlag selects programs by label instead of by name
+func selectorFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "selector",
+		Aliases: []string{"l"},
+		Usage:   "Label selector, e.g. env=prod,tier!=db",
+	}
+}
 
-		// 		hxeClient.Service.Print(prog)
+// target builds a request from the selector flag or the first argument
+func target(cmd *cli.Command) *prog.Request {
+	return prog.Target(cmd.Args().First(), cmd.String("selector"))
+}
 
-		// 		return nil
-		// 	},
-		// },
-		// {
-		// 	Name:        "disable",
-		// 	Usage:       "Disable a service",
-		// 	Description: `Disable a service from starting automatically.`,
-		// 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		// 		// hxeClient := client.NewClient(serverURL, username, password)
-		// 		// if hxeClient, err = hxeClient.Login(); err != nil {
-		// 		// 	return err
-		// 		// }
+// sinceFlag replays the messages of a stream published since a time ago
+func sinceFlag() cli.Flag {
+	return &cli.DurationFlag{
+		Name:  "since",
+		Usage: "Show what was published since this long ago, everything kept when 0",
+		Value: time.Hour,
+	}
+}
 
-		// 		prog, err := hxeClient.Service.Disable(cmd.Args().First())
-		// 		if err != nil {
-		// 			return fmt.Errorf("failed to run command: %w", err)
-		// 		}
+// followFlag keeps waiting for new messages of a stream
+func followFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:    "follow",
+		Aliases: []string{"f"},
+		Usage:   "Keep showing new messages until interrupted",
+	}
+}
 
-		// 		hxeClient.Service.Print(prog)
-		// 		return nil
-		// 	},
-		// },
-		// {
-		// 	Name:        "delete",
-		// 	Usage:       "Delete registered service by ID",
-		// 	Description: `Delete a registered service by ID.`,
-		// 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		// 		// hxeClient := client.NewClient(serverURL, username, password)
-		// 		// if hxeClient, err = hxeClient.Login(); err != nil {
-		// 		// 	return err
-		// 		// }
+// replay asks the agent for its streams and builds a replay from the
+// since and follow flags
+func replay(cmd *cli.Command) (*prog.Replay, error) {
+	info, err := hxeClient.Agent.Info()
+	if err != nil {
+		return nil, err
+	}
+	if info.Events == "" {
+		return nil, fmt.Errorf("jetstream is not enabled on agent %s", info.ID)
+	}
 
-		// 		progs, err := hxeClient.Service.Delete(cmd.Args().First())
-		// 		if err != nil {
-		// 			return fmt.Errorf("failed to run command: %w", err)
-		// 		}
+	r := &prog.Replay{
+		Agent:  info.ID,
+		Domain: info.Domain,
+		Events: info.Events,
+		Logs:   info.Logs,
+		Follow: cmd.Bool("follow"),
+	}
+	if since := cmd.Duration("since"); since > 0 {
+		r.Since = time.Now().Add(-since)
+	}
+	return r, nil
+}
 
-		// 		hxeClient.Service.Print(progs)
-		// 		return nil
-		// 	},
-		// },
-		// {
-		// 	Name:        "log",
-		// 	Usage:       "Show service logs",
-		// 	Description: `Show the logs of a service.`,
-		// 	Flags: []cli.Flag{
-		// 		&cli.IntFlag{
-		// 			Name:    "lines",
-		// 			Aliases: []string{"n"},
-		// 			Usage:   "Number of lines to show",
-		// 			Value:   50,
-		// 		},
-		// 		&cli.BoolFlag{
-		// 			Name:    "follow",
-		// 			Aliases: []string{"f"},
-		// 			Usage:   "Follow log output",
-		// 			Value:   true,
-		// 		},
-		// 	},
-		// 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		// 		// hxeClient := client.NewClient(serverURL, username, password)
-		// 		// if hxeClient, err = hxeClient.Login(); err != nil {
-		// 		// 	return err
-		// 		// }
+// bulk runs a bulk operation on the selected agents and prints the
+// per-program results
+func bulk(fn func(c *client.Client) (*prog.Response, error)) error {
+	fleet, err := fanout(fn)
+	for _, res := range fleet {
+		if len(res.Results) > 0 {
+			fleet.PrintResults()
+			break
+		}
+	}
+	return err
+}
 
-		// 		// prog, err := hxeClient.Service.Log(cmd.Args().Slice())...)
-		// 		// if err != nil {
-		// 		// 	return fmt.Errorf("failed to run command: %w", err)
-		// 		// }
+// clientFlags are the flags of commands that talk to an agent
+func clientFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config",
+			Aliases: []string{"c"},
+			Hidden:  false,
+			Usage:   "Configuration from `FILE`",
+		},
+		&cli.StringFlag{
+			Name:        "profile",
+			Aliases:     []string{"p"},
+			Hidden:      false,
+			Usage:       "Client profile to use instead of the default profile",
+			Destination: &profile,
+		},
+		&cli.StringFlag{
+			Name:        "username",
+			Aliases:     []string{"u"},
+			Value:       "",
+			Hidden:      false,
+			Usage:       "Username for authentication",
+			Destination: &username,
+		},
+		&cli.StringFlag{
+			Name:        "password",
+			Aliases:     []string{"p"},
+			Value:       "",
+			Hidden:      false,
+			Usage:       "Password for authentication",
+			Destination: &password,
+		},
+		&cli.StringFlag{
+			Name:        "url",
+			Value:       "",
+			Hidden:      false,
+			Usage:       "Hxe API server URL",
+			Destination: &serverURL,
+		},
+		&cli.StringFlag{
+			Name:  "host",
+			Usage: "Host of the agent, instead of the profile's URL",
+		},
+		&cli.IntFlag{
+			Name:  "port",
+			Usage: "Port of the agent, instead of the profile's URL",
+		},
+		&cli.BoolFlag{
+			Name:  "ipc",
+			Usage: "Connect to the agent on this host",
+		},
+		&cli.StringFlag{
+			Name:  "token",
+			Usage: "Token for authentication",
+		},
+		&cli.StringFlag{
+			Name:  "creds",
+			Usage: "User credentials `FILE` for authentication",
+		},
+		&cli.DurationFlag{
+			Name:   "timeout",
+			Value:  10 * time.Second,
+			Hidden: false,
+			Usage:  "Timeout for API requests",
+		},
+	}
+}
 
-		// 		// hxeClient.Service.PrintDetail(prog)
-		// 		return nil
-		// 	},
-		// },
-		// {
-		// 	Name:        "shell",
-		// 	Usage:       "Open shell for a service",
-		// 	Description: `Open an interactive shell in the context of a service.`,
-		// 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		// 		// hxeClient := client.NewClient(serverURL, username, password)
-		// 		// if hxeClient, err = hxeClient.Login(); err != nil {
-		// 		// 	return err
-		// 		// }
+// connect creates the client used by commands that talk to an agent
+func connect(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+	// Create new client config
+	if clientConfig, err = config.NewClientConfig(
+		config.ClientDefaultOptions(),
+		config.ClientCliOpts(ctx, cmd),
+		config.ClientProfileOpts(profile),
+	); err != nil {
+		return ctx, err
+	}
 
-		// 		// prog, err := hxeClient.Service.Shell(cmd.Args().Slice())...)
-		// 		// if err != nil {
-		// 		// 	return fmt.Errorf("failed to run command: %w", err)
-		// 		// }
+	// Create new client
+	hxeClient, err = client.New(clientConfig)
+	if err != nil {
+		return ctx, err
+	}
 
-		// 		// hxeClient.Service.PrintDetail(prog)
-		// 		return nil
-		// 	},
-		// },
-	},
+	return ctx, selectAgents(ctx, cmd)
 }
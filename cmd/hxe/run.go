@@ -0,0 +1,93 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rangertaha/hxe/internal"
+	prog "github.com/rangertaha/hxe/internal/services/program/client"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/sys/unix"
+)
+
+var runCmd *cli.Command = &cli.Command{
+	Name:      "run",
+	Usage:     "Run a command once on the agent",
+	UsageText: "hxe run [options] -- <command> [args...]",
+	Description: `Run a one-off command on the agent and stream its output back. The
+command runs with the user, directory, environment and time limits of
+--program when given, is recorded in the run history and hxe exits with
+its exit code.`,
+	Version:               internal.VERSION,
+	EnableShellCompletion: true,
+	Suggest:               true,
+	Flags: append(clientFlags(),
+		&cli.StringFlag{
+			Name:  "program",
+			Usage: "Run in the context of a program, by name or ID",
+		},
+		&cli.DurationFlag{
+			Name:  "max-runtime",
+			Usage: "Stop the command if it runs longer than this",
+		},
+	),
+	Before: connect,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() == 0 {
+			return fmt.Errorf("no command given")
+		}
+
+		req := &prog.Request{
+			Command:    cmd.Args().Slice(),
+			MaxRuntime: cmd.Duration("max-runtime"),
+		}
+		if ref := cmd.String("program"); ref != "" {
+			req.Program = prog.Ref(ref)
+		}
+
+		// Ctrl-C cancels the command on the agent instead of leaving it
+		// running without anyone reading its output
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		run, err := hxeClient.Programs.Run(ctx, req, func(out *prog.Output) {
+			if out.Stream == "stderr" {
+				fmt.Fprintln(os.Stderr, out.Line)
+				return
+			}
+			fmt.Fprintln(os.Stdout, out.Line)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to run command: %w", err)
+		}
+		switch {
+		case run.Signal != "":
+			// Like a shell, report a command killed by a signal as 128+n
+			return cli.Exit("", 128+int(unix.SignalNum(run.Signal)))
+		case run.ExitCode != 0:
+			return cli.Exit("", run.ExitCode)
+		}
+		return nil
+	},
+}
@@ -9,6 +9,14 @@ program "web-server" {
   autostart   = true
   enabled     = true
   retries     = 3
+
+  // nginx re-reads its configuration on HUP
+  reload_signal = "HUP"
+
+  labels = {
+    tier = "web"
+    env  = "prod"
+  }
 }
 
 program "api-server" {
@@ -20,6 +28,28 @@ program "api-server" {
   autostart   = true
   enabled     = true
   retries     = 5
+
+  // Let the kernel write core files for crash reports
+  rlimit_core = -1
+
+  labels = {
+    tier = "api"
+    env  = "prod"
+  }
+
+  // Mark the program ready once it starts listening
+  trigger "listening" {
+    pattern = "listening on"
+    action  = "ready"
+  }
+
+  // Bring the program back when it runs out of memory
+  trigger "oom" {
+    pattern    = "OutOfMemoryError"
+    stream     = "stderr"
+    action     = "restart"
+    rate_limit = minutes(5)
+  }
 }
 
 program "database" {
@@ -31,6 +61,15 @@ program "database" {
   autostart   = true
   enabled     = true
   retries     = 3
+
+  // Always bring the database back, unless an operator stopped it with TERM
+  restart               = "always"
+  no_restart_on_signals = ["TERM"]
+
+  labels = {
+    tier = "db"
+    env  = "prod"
+  }
 }
 
 program "monitoring" {
@@ -42,4 +81,24 @@ program "monitoring" {
   autostart   = false
   enabled     = true
   retries     = 2
-} 
\ No newline at end of file
+} 
+program "scraper" {
+  description = "Nightly price scraper"
+  exec        = "python3 scrape.py"
+  directory   = "/opt/scraper"
+  user        = "scraper"
+  group       = "scraper"
+  autostart   = false
+  enabled     = true
+  retries     = 3
+
+  // Stop a hung run after 30 minutes and give up after 2 hours
+  max_runtime = minutes(30)
+  deadline    = hours(2)
+
+  // Exit code 3 means there was nothing to scrape, and 78 a broken
+  // configuration that disables the program until it is enabled again
+  restart            = "on-failure"
+  success_exit_codes = [0, 3]
+  disable_exit_codes = [78]
+}
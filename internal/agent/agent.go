@@ -23,12 +23,16 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
 	"github.com/rangertaha/hxe/internal/config"
 	"github.com/rangertaha/hxe/internal/interfaces"
 	"github.com/rangertaha/hxe/internal/log"
@@ -42,30 +46,61 @@ import (
 type Agent struct {
 	Services []interfaces.Service
 
-	conf *config.AgentConfig
-	sig  chan os.Signal
-	done chan struct{}
-	log  zerolog.Logger
-	ns   *server.Server
-	nc   *nats.Conn
+	id       string
+	streams  bool
+	domain   string
+	conf     *config.AgentConfig
+	services map[string]interfaces.Service
+	sig      chan os.Signal
+	done     chan struct{}
+	log      zerolog.Logger
+	ns       *server.Server
+	nc       *nats.Conn
+	micro    micro.Service
+	watcher  *fsnotify.Watcher
+	reload   sync.Mutex
 }
 
 // New creates a new Agent instance
 func New(cfg *config.AgentConfig) (agent *Agent, err error) {
 	agent = &Agent{
-		conf: cfg,
-		sig:  make(chan os.Signal, 1),
-		done: make(chan struct{}),
-		log:  log.With().Logger(),
+		conf:     cfg,
+		services: make(map[string]interfaces.Service),
+		sig:      make(chan os.Signal, 1),
+		done:     make(chan struct{}),
+		log:      log.With().Logger(),
 		// Services: make([]interfaces.Service, len(cfg.Services)),
 	}
 
+	if agent.id, err = cfg.AgentID(); err != nil {
+		return nil, err
+	}
+
 	// Create messaging with server options
-	agent.ns, agent.nc, err = NewMessaging(&cfg.Server)
+	opts, connOpts, err := cfg.Server.Options(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Server.Cluster != nil || cfg.Server.Leafnode != nil {
+		// Servers of a cluster or hub need unique names
+		opts.ServerName = agent.id
+	}
+	agent.streams = cfg.Server.JetStream != nil
+	if agent.streams && cfg.Server.Cluster == nil {
+		// The agent's own domain keeps its streams apart from those of
+		// the hub and other leafnodes, and lets clients reach them
+		agent.domain = agent.id
+		opts.JetStreamDomain = agent.domain
+	}
+	if !cfg.Server.Authenticated() && !cfg.Server.Loopback() {
+		agent.log.Warn().Msgf("server listens on %s:%d without authentication", opts.Host, opts.Port)
+	}
+	agent.ns, agent.nc, err = NewMessaging(opts, connOpts...)
 	if err != nil {
 		agent.log.Error().Err(err).Msg("failed to create messaging service")
 		return nil, err
 	}
+	agent.initStreams()
 
 	if err = agent.Load(); err != nil {
 		return nil, err
@@ -86,7 +121,7 @@ func (a *Agent) Init() (err error) {
 		service.Init()
 	}
 
-	return nil
+	return a.initMicro()
 }
 
 func (a *Agent) Load() (err error) {
@@ -98,14 +133,20 @@ func (a *Agent) Load() (err error) {
 			return err
 		}
 
-		srv := creator(a.nc)
+		if svc.Directory != "" {
+			svc.Directory = a.conf.Path(svc.Directory)
+		}
+		svc.Agent = a.id
+		svc.Auth = a.conf.Server.AuthStore(a.conf.Path)
+		srv := creator(a.nc, svc)
 
-		diags := gohcl.DecodeBody(svc.Config, config.CtxFunctions, srv)
+		diags := gohcl.DecodeBody(svc.Config, config.FileContext(a.conf.File()), srv)
 		for _, diag := range diags {
 			return errors.New(diag.Error())
 		}
 
 		a.Services = append(a.Services, srv)
+		a.services[svc.ID] = srv
 	}
 
 	return nil
@@ -115,6 +156,10 @@ func (a *Agent) Load() (err error) {
 func (a *Agent) Stop() {
 	log.Info().Msg("stopping agent")
 
+	if a.watcher != nil {
+		a.watcher.Close()
+	}
+
 	for _, service := range a.Services {
 		service.Stop()
 	}
@@ -129,20 +174,34 @@ func (a *Agent) Start() error {
 		service.Start()
 	}
 
+	if err := a.watch(); err != nil {
+		a.log.Error().Err(err).Msg("failed to watch configuration, reload with SIGHUP instead")
+	}
+
 	// wg.Add(1)
 	// go func() {
 	// 	defer wg.Done()
 	// Setup signal handling
-	signal.Notify(a.sig, syscall.SIGINT, syscall.SIGTERM)
-
-	// Wait for shutdown signal
-	select {
-	case sig := <-a.sig:
-		log.Debug().Msgf("received system signal %v, initiating shutdown", sig)
-		a.Stop()
-	case <-a.done:
-		log.Debug().Msg("received done signal, initiating shutdown")
-		a.Stop()
+	signal.Notify(a.sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+	// Wait for shutdown signal, reloading on SIGHUP
+wait:
+	for {
+		select {
+		case sig := <-a.sig:
+			if sig == syscall.SIGHUP {
+				log.Debug().Msg("received SIGHUP, reloading configuration")
+				a.Reload()
+				continue
+			}
+			log.Debug().Msgf("received system signal %v, initiating shutdown", sig)
+			a.Stop()
+			break wait
+		case <-a.done:
+			log.Debug().Msg("received done signal, initiating shutdown")
+			a.Stop()
+			break wait
+		}
 	}
 	// }()
 
@@ -157,13 +216,17 @@ func (a *Agent) Start() error {
 	return nil
 }
 
-func NewMessaging(cfg *config.Server) (ns *server.Server, nc *nats.Conn, err error) {
+// NewMessaging starts the NATS server and connects the agent to it in
+// process
+func NewMessaging(opts *server.Options, connOpts ...nats.Option) (ns *server.Server, nc *nats.Conn, err error) {
 	log.Info().Msg("initializing messaging service")
-	opts := &server.Options{Port: cfg.Port, Host: cfg.Host}
 	ns, err = server.NewServer(opts)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create messaging server: %w", err)
 	}
+	if opts.Debug || opts.Trace {
+		ns.ConfigureLogger()
+	}
 	// Start server in background
 	go ns.Start()
 
@@ -172,12 +235,14 @@ func NewMessaging(cfg *config.Server) (ns *server.Server, nc *nats.Conn, err err
 		return nil, nil, fmt.Errorf("server not ready for connections")
 	}
 
-	clientOpts := []nats.Option{
+	clientOpts := append([]nats.Option{
 		nats.Name("hxe-server"),
 		nats.InProcessServer(ns),
 		nats.FlusherTimeout(5 * time.Second),
-	}
-	nc, err = nats.Connect(ns.ClientURL(), clientOpts...)
+	}, connOpts...)
+	// In-process connections need no TLS, the server accepts them without
+	url := "nats://" + strings.TrimPrefix(ns.ClientURL(), "tls://")
+	nc, err = nats.Connect(url, clientOpts...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to connect to messaging server: %w", err)
 	}
@@ -0,0 +1,221 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 rangertaha@gmail.com
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+	"github.com/rangertaha/hxe/internal/log"
+	"github.com/rangertaha/hxe/internal/services"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// SERVICE is the name of the micro service of agents
+	SERVICE = "agent"
+
+	// ReloadTimeout bounds a reload, which may wait on programs restarting
+	ReloadTimeout = 60 * time.Second
+
+	// DiscoverTimeout is how long Discover waits for agents to answer
+	DiscoverTimeout = time.Second
+
+	// InfoTimeout bounds a request for the info of an agent
+	InfoTimeout = 5 * time.Second
+)
+
+// Client is a client of the agent's own endpoints
+type Client struct {
+	nc    *nats.Conn
+	agent string
+	log   zerolog.Logger
+}
+
+// Info describes an agent. Domain and the streams of events and logs
+// are only returned by Info, and are empty without JetStream.
+type Info struct {
+	ID       string `json:"id"`
+	Hostname string `json:"hostname"`
+	Version  string `json:"version"`
+	Domain   string `json:"domain,omitempty"`
+	Events   string `json:"events,omitempty"`
+	Logs     string `json:"logs,omitempty"`
+}
+
+// EventStream returns the name of the stream of an agent's events
+func EventStream(id string) string {
+	return "HXE_EVENTS_" + id
+}
+
+// LogStream returns the name of the stream of an agent's program output
+func LogStream(id string) string {
+	return "HXE_LOGS_" + id
+}
+
+type Request struct{}
+
+type Response struct {
+	Error    string            `json:"error,omitempty"`
+	Changes  []services.Change `json:"changes,omitempty"`
+	Warnings []string          `json:"warnings,omitempty"`
+}
+
+func New(nc *nats.Conn) *Client {
+	return &Client{nc: nc, log: log.With().Logger()}
+}
+
+// Agent returns a client of the agent with the given ID
+func (c *Client) Agent(id string) *Client {
+	return &Client{nc: c.nc, agent: id, log: c.log}
+}
+
+// Reload asks the agent to re-read its configuration and program definitions
+func (c *Client) Reload() (resp *Response, err error) {
+	return c.request(services.Subject(c.agent, "agent.reload"), &Request{}, ReloadTimeout)
+}
+
+// Info returns the info of the agent, with its JetStream domain and
+// streams
+func (c *Client) Info() (*Info, error) {
+	subject := services.Subject(c.agent, "agent.info")
+	msg, err := c.nc.Request(subject, nil, InfoTimeout)
+	if err != nil {
+		errMsg := fmt.Sprintf("%s: failed to request %s", c.nc.ConnectedUrl(), subject)
+		c.log.Error().Err(err).Str("url", c.nc.ConnectedUrl()).Msg(errMsg)
+		return nil, errors.New(errMsg)
+	}
+
+	info := &Info{}
+	if err = json.Unmarshal(msg.Data, info); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s response: %w", subject, err)
+	}
+	return info, nil
+}
+
+// Discover returns the agents reachable through the server, the hub of
+// a fleet, sorted by ID. Agents that don't answer within timeout are
+// left out.
+func (c *Client) Discover(timeout time.Duration) ([]*Info, error) {
+	inbox := c.nc.NewRespInbox()
+	sub, err := c.nc.SubscribeSync(inbox)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover agents: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	subject, err := micro.ControlSubject(micro.InfoVerb, SERVICE, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := c.nc.PublishRequest(subject, inbox, nil); err != nil {
+		return nil, fmt.Errorf("failed to discover agents: %w", err)
+	}
+
+	agents := map[string]*Info{}
+	deadline := time.Now().Add(timeout)
+	for {
+		msg, err := sub.NextMsg(time.Until(deadline))
+		if errors.Is(err, nats.ErrTimeout) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover agents: %w", err)
+		}
+
+		info := &micro.Info{}
+		if err := json.Unmarshal(msg.Data, info); err != nil || info.Metadata["id"] == "" {
+			c.log.Debug().Msgf("ignoring agent info: %s", string(msg.Data))
+			continue
+		}
+		agents[info.Metadata["id"]] = &Info{
+			ID:       info.Metadata["id"],
+			Hostname: info.Metadata["hostname"],
+			Version:  info.Version,
+		}
+	}
+
+	list := []*Info{}
+	for _, agent := range agents {
+		list = append(list, agent)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (c *Client) request(subject string, req *Request, timeout time.Duration) (resp *Response, err error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s request: %w", subject, err)
+	}
+
+	msg, err := c.nc.Request(subject, data, timeout)
+	if err != nil {
+		errMsg := fmt.Sprintf("%s: failed to request %s", c.nc.ConnectedUrl(), subject)
+		c.log.Error().Err(err).Str("url", c.nc.ConnectedUrl()).Msg(errMsg)
+		return nil, errors.New(errMsg)
+	}
+	c.log.Debug().Msgf("%s response: %s", subject, string(msg.Data))
+
+	resp = &Response{}
+	if err = json.Unmarshal(msg.Data, resp); err != nil {
+		errMsg := fmt.Sprintf("failed to unmarshal %s response", subject)
+		c.log.Error().Err(err).Msg(errMsg)
+		return nil, errors.New(errMsg)
+	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+// PrintChanges prints what a reload changed, followed by its warnings
+func PrintChanges(resp *Response) {
+	if len(resp.Changes) == 0 {
+		fmt.Println("No changes")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SERVICE\tCHANGE\tNAME\tFIELDS")
+		for _, change := range resp.Changes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", change.Service, change.Kind, change.Name, strings.Join(change.Fields, ","))
+		}
+		w.Flush()
+	}
+	for _, warning := range resp.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+}
+
+// PrintAgents prints discovered agents in table format
+func PrintAgents(agents []*Info) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tHOSTNAME\tVERSION")
+	for _, agent := range agents {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", agent.ID, agent.Hostname, agent.Version)
+	}
+	w.Flush()
+}
@@ -0,0 +1,171 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package agent
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	ac "github.com/rangertaha/hxe/internal/agent/client"
+	"github.com/rangertaha/hxe/internal/config"
+	"github.com/rangertaha/hxe/internal/services"
+)
+
+// freePort returns a port that nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// startAgent starts an agent without services on a random client port
+func startAgent(t *testing.T, id string, server config.Server) *Agent {
+	t.Helper()
+	server.Name, server.Host, server.Port = id, "127.0.0.1", -1
+	a, err := New(&config.AgentConfig{ID: id, Server: server})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		a.nc.Close()
+		a.ns.Shutdown()
+	})
+	return a
+}
+
+// connect connects a client to the server of an agent
+func connect(t *testing.T, a *Agent) *nats.Conn {
+	t.Helper()
+	nc, err := nats.Connect(a.ns.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+// eventually fails the test when ok doesn't become true within 5s
+func eventually(t *testing.T, what string, ok func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !ok(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// checkFleet discovers the agents through nc and checks every agent
+// answers on its own namespaced subjects
+func checkFleet(t *testing.T, nc *nats.Conn, ids []string) {
+	t.Helper()
+	client := ac.New(nc)
+	eventually(t, "every agent to be discovered", func() bool {
+		infos, err := client.Discover(200 * time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found []string
+		for _, info := range infos {
+			found = append(found, info.ID)
+		}
+		return slices.Equal(found, ids)
+	})
+
+	for _, id := range ids {
+		// Interest in the subjects of remote agents propagates after the
+		// connection is up, so the first requests may find no responders
+		var info *ac.Info
+		eventually(t, "agent "+id+" to answer", func() bool {
+			var err error
+			info, err = client.Agent(id).Info()
+			return err == nil
+		})
+		if info.ID != id {
+			t.Errorf("%s answered for agent %s", info.ID, id)
+		}
+	}
+
+	if _, err := client.Agent("missing").Info(); err == nil {
+		t.Error("an agent answered for a missing agent")
+	}
+}
+
+func TestSubjects(t *testing.T) {
+	a := startAgent(t, "web-01", config.Server{})
+	nc := connect(t, a)
+
+	for _, subject := range []string{"agent.info", services.Subject("web-01", "agent.info")} {
+		if _, err := nc.Request(subject, nil, time.Second); err != nil {
+			t.Errorf("%s: %v", subject, err)
+		}
+	}
+	if _, err := nc.Request(services.Subject("web-02", "agent.info"), nil, 100*time.Millisecond); err == nil {
+		t.Error("agent web-01 answered on the subjects of web-02")
+	}
+}
+
+func TestLeafnodeFleet(t *testing.T) {
+	port := freePort(t)
+	hub := startAgent(t, "hub", config.Server{
+		Leafnode: &config.ServerLeafnode{Host: "127.0.0.1", Port: port},
+	})
+	ids := []string{"hub"}
+	for i := 1; i <= 3; i++ {
+		id := fmt.Sprintf("web-%02d", i)
+		startAgent(t, id, config.Server{
+			Leafnode: &config.ServerLeafnode{Remotes: []string{fmt.Sprintf("nats-leaf://127.0.0.1:%d", port)}},
+		})
+		ids = append(ids, id)
+	}
+	eventually(t, "the leafnodes to connect", func() bool { return hub.ns.NumLeafNodes() == 3 })
+
+	checkFleet(t, connect(t, hub), ids)
+}
+
+func TestClusterFleet(t *testing.T) {
+	ports := []int{freePort(t), freePort(t), freePort(t)}
+	routes := []string{}
+	for _, port := range ports {
+		routes = append(routes, fmt.Sprintf("nats-route://127.0.0.1:%d", port))
+	}
+
+	var agents []*Agent
+	ids := []string{"db-01", "db-02", "db-03"}
+	for i, id := range ids {
+		agents = append(agents, startAgent(t, id, config.Server{
+			Cluster: &config.ServerCluster{Name: "hxe", Host: "127.0.0.1", Port: ports[i], Routes: routes},
+		}))
+	}
+	for _, a := range agents {
+		eventually(t, "the routes to connect", func() bool { return a.ns.NumRoutes() >= 2 })
+	}
+
+	// every server of the cluster reaches every agent
+	for _, a := range agents {
+		checkFleet(t, connect(t, a), ids)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * HXE - Host-based Process Execution Agent
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	ac "github.com/rangertaha/hxe/internal/agent/client"
+	"github.com/rangertaha/hxe/internal/config"
+	"github.com/rangertaha/hxe/internal/services"
+)
+
+const (
+	// StreamTimeout bounds creating or updating a stream
+	StreamTimeout = 10 * time.Second
+
+	// StreamRetry is how long the agent waits before trying again to
+	// create streams, e.g. while a cluster elects a JetStream leader
+	StreamRetry = 5 * time.Second
+)
+
+// initStreams creates the streams of the agent's events and program
+// output, or updates their limits. When JetStream isn't available yet it
+// keeps trying in the background.
+func (a *Agent) initStreams() {
+	js := a.conf.Server.JetStream
+	if js == nil {
+		return
+	}
+	err := a.createStreams(js)
+	if err == nil {
+		return
+	}
+	a.log.Warn().Err(err).Msgf("failed to create streams, retrying every %s", StreamRetry)
+
+	go func() {
+		ticker := time.NewTicker(StreamRetry)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-a.done:
+				return
+			case <-ticker.C:
+			}
+			if err := a.createStreams(js); err != nil {
+				a.log.Debug().Err(err).Msg("failed to create streams")
+				continue
+			}
+			a.log.Info().Msg("created streams")
+			return
+		}
+	}()
+}
+
+// createStreams creates or updates the streams of events and program output
+func (a *Agent) createStreams(conf *config.ServerJetStream) error {
+	js, err := jetstream.New(a.nc)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), StreamTimeout)
+	defer cancel()
+
+	streams := []jetstream.StreamConfig{
+		stream(ac.EventStream(a.id), "Program events of agent "+a.id, services.Subject(a.id, "events.>"), conf.EventLimits()),
+		stream(ac.LogStream(a.id), "Program output of agent "+a.id, services.Subject(a.id, "logs.>"), conf.LogLimits()),
+	}
+	for _, cfg := range streams {
+		if _, err := js.CreateOrUpdateStream(ctx, cfg); err != nil {
+			return err
+		}
+		a.log.Debug().Str("stream", cfg.Name).Dur("max_age", cfg.MaxAge).Int64("max_bytes", cfg.MaxBytes).
+			Int64("max_msgs", cfg.MaxMsgs).Msg("stream ready")
+	}
+	return nil
+}
+
+// stream returns the config of a file stream of subject, dropping the
+// oldest messages at the limits
+func stream(name, description, subject string, limits config.StreamLimits) jetstream.StreamConfig {
+	return jetstream.StreamConfig{
+		Name:        name,
+		Description: description,
+		Subjects:    []string{subject},
+		Storage:     jetstream.FileStorage,
+		Retention:   jetstream.LimitsPolicy,
+		Discard:     jetstream.DiscardOld,
+		MaxAge:      limits.MaxAge,
+		MaxBytes:    limits.MaxBytes,
+		MaxMsgs:     limits.MaxMsgs,
+	}
+}
@@ -0,0 +1,203 @@
+/*
+ * HXE - Host-based Process Execution Agent
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/nats-io/nats.go/micro"
+	"github.com/rangertaha/hxe/internal"
+	ac "github.com/rangertaha/hxe/internal/agent/client"
+	"github.com/rangertaha/hxe/internal/services"
+)
+
+// ReloadDelay is how long the watcher waits for file changes to settle
+// before reloading
+const ReloadDelay = 500 * time.Millisecond
+
+// Reload re-reads the config file and hands each service its new block.
+// Changes to the server, and services added or removed, are reported as
+// warnings since they need a restart of the agent.
+func (a *Agent) Reload() (changes []services.Change, warnings []string, err error) {
+	a.reload.Lock()
+	defer a.reload.Unlock()
+
+	conf, err := a.conf.Reload()
+	if err != nil {
+		a.log.Error().Err(err).Msg("failed to reload configuration")
+		return nil, nil, err
+	}
+	if !reflect.DeepEqual(conf.Server, a.conf.Server) {
+		warnings = append(warnings, "server changes take effect after a restart")
+	}
+
+	defined := map[string]bool{}
+	for _, svc := range conf.Services {
+		defined[svc.ID] = true
+		if svc.Directory != "" {
+			svc.Directory = conf.Path(svc.Directory)
+		}
+
+		srv, ok := a.services[svc.ID]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("service %s was added and starts after a restart", svc.ID))
+			continue
+		}
+		if reloader, ok := srv.(services.Reloader); ok {
+			changed, err := reloader.ReloadConfig(svc)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("service %s: %s", svc.ID, err))
+				continue
+			}
+			changes = append(changes, changed...)
+		} else if err := srv.Reload(); err != nil {
+			warnings = append(warnings, fmt.Sprintf("service %s: %s", svc.ID, err))
+		}
+	}
+	for id := range a.services {
+		if !defined[id] {
+			warnings = append(warnings, fmt.Sprintf("service %s was removed and stops after a restart", id))
+		}
+	}
+
+	a.conf = conf
+	for _, warning := range warnings {
+		a.log.Warn().Msg(warning)
+	}
+	a.log.Info().Int("changes", len(changes)).Msg("reloaded configuration")
+
+	if a.watcher != nil {
+		a.watchDirs()
+	}
+	return changes, warnings, nil
+}
+
+// watch reloads when an *.hcl or *.hcl.json file changes next to the
+// config file or in a service directory
+func (a *Agent) watch() (err error) {
+	if a.conf.File() == "" {
+		return nil
+	}
+	if a.watcher, err = fsnotify.NewWatcher(); err != nil {
+		return err
+	}
+	a.watchDirs()
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-a.watcher.Events:
+				if !ok {
+					return
+				}
+				hcl := strings.HasSuffix(event.Name, ".hcl") || strings.HasSuffix(event.Name, ".hcl.json")
+				if !hcl || event.Op == fsnotify.Chmod {
+					continue
+				}
+				a.log.Debug().Str("file", event.Name).Str("op", event.Op.String()).Msg("configuration changed")
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(ReloadDelay, func() { a.Reload() })
+			case err, ok := <-a.watcher.Errors:
+				if !ok {
+					return
+				}
+				a.log.Error().Err(err).Msg("configuration watcher failed")
+			}
+		}
+	}()
+	return nil
+}
+
+// watchDirs adds the config and service directories to the watcher.
+// Directories that don't exist yet are skipped.
+func (a *Agent) watchDirs() {
+	dirs := []string{filepath.Dir(a.conf.File())}
+	for _, svc := range a.conf.Services {
+		if svc.Directory != "" {
+			dirs = append(dirs, svc.Directory)
+		}
+	}
+	for _, dir := range dirs {
+		if err := a.watcher.Add(dir); err != nil {
+			a.log.Debug().Err(err).Str("directory", dir).Msg("not watching directory")
+		}
+	}
+}
+
+// initMicro registers the agent's own endpoints. The ID in the metadata
+// of the service lets clients discover the agents of a fleet.
+func (a *Agent) initMicro() (err error) {
+	hostname, _ := os.Hostname()
+	a.micro, err = micro.AddService(a.nc, micro.Config{
+		Name:        ac.SERVICE,
+		Version:     internal.VERSION,
+		Description: "Agent management",
+		Metadata:    map[string]string{"id": a.id, "hostname": hostname},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add agent service: %w", err)
+	}
+
+	for _, group := range services.Groups(a.id, "agent") {
+		g := a.micro.AddGroup(group)
+		if err := g.AddEndpoint("reload", micro.HandlerFunc(a.handleReload)); err != nil {
+			return err
+		}
+		if err := g.AddEndpoint("info", micro.HandlerFunc(a.handleInfo)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Agent) handleInfo(msg micro.Request) {
+	hostname, _ := os.Hostname()
+	info := &ac.Info{ID: a.id, Hostname: hostname, Version: internal.VERSION}
+	if a.streams {
+		info.Domain = a.domain
+		info.Events, info.Logs = ac.EventStream(a.id), ac.LogStream(a.id)
+	}
+	msg.RespondJSON(info)
+}
+
+func (a *Agent) handleReload(msg micro.Request) {
+	res := &ac.Response{}
+	changes, warnings, err := a.Reload()
+	if err != nil {
+		res.Error = err.Error()
+	}
+	res.Changes, res.Warnings = changes, warnings
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		msg.Error("500", "Marshal error", nil)
+		return
+	}
+	msg.Respond(data)
+}
@@ -0,0 +1,263 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package auth manages the operator, accounts and users of NATS
+// decentralized authentication. Everything lives in a directory written
+// by hxe auth init:
+//
+//	operator.jwt, operator.nk    the operator, trusted by the server
+//	accounts/SYS.jwt, SYS.nk     the system account
+//	accounts/HXE.jwt, HXE.nk     the account agents and clients use
+//	users/<name>.creds           user JWTs with their nkey seeds
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+)
+
+const (
+	// DIR is the default auth directory, relative to agent.hcl
+	DIR = "auth"
+
+	OPERATOR       = "hxe"
+	SYSTEM_ACCOUNT = "SYS"
+	ACCOUNT        = "HXE"
+
+	// ADMIN is the user with every permission created by Init
+	ADMIN = "admin"
+)
+
+// jetStream are the JetStream limits of the HXE account, leaving the
+// limits of streams to the server
+var jetStream = jwt.JetStreamLimits{
+	MemoryStorage: jwt.NoLimit,
+	DiskStorage:   jwt.NoLimit,
+	Streams:       jwt.NoLimit,
+	Consumer:      jwt.NoLimit,
+}
+
+// Store is an auth directory
+type Store struct {
+	Dir string
+
+	// nonces are those of the requests verified recently, by when they
+	// can be forgotten
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+// New returns the store of an auth directory
+func New(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// Exists reports whether the directory holds an operator
+func (s *Store) Exists() bool {
+	_, err := os.Stat(s.path("operator.jwt"))
+	return err == nil
+}
+
+// Init creates the operator, the system account, the account of agents
+// and clients, and the admin user. An existing operator is only replaced
+// with force, which invalidates every user of the old one.
+func (s *Store) Init(force bool) error {
+	if s.Exists() && !force {
+		return fmt.Errorf("%s is already initialized", s.Dir)
+	}
+	for _, dir := range []string{s.Dir, s.path("accounts"), s.path("users")} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+
+	operator, err := nkeys.CreateOperator()
+	if err != nil {
+		return err
+	}
+	sys, err := s.createAccount(operator, SYSTEM_ACCOUNT, jwt.JetStreamLimits{})
+	if err != nil {
+		return err
+	}
+	if _, err := s.createAccount(operator, ACCOUNT, jetStream); err != nil {
+		return err
+	}
+
+	pub, err := operator.PublicKey()
+	if err != nil {
+		return err
+	}
+	claims := jwt.NewOperatorClaims(pub)
+	claims.Name = OPERATOR
+	claims.SystemAccount = sys
+	token, err := claims.Encode(operator)
+	if err != nil {
+		return err
+	}
+	if err := s.writeKey("operator", token, operator); err != nil {
+		return err
+	}
+
+	_, err = s.AddUser(ADMIN, Permissions{}, 0)
+	return err
+}
+
+// createAccount creates an account signed by the operator and returns
+// its public key. JetStream is disabled when js has no storage.
+func (s *Store) createAccount(operator nkeys.KeyPair, name string, js jwt.JetStreamLimits) (string, error) {
+	kp, err := nkeys.CreateAccount()
+	if err != nil {
+		return "", err
+	}
+	pub, err := kp.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	claims := jwt.NewAccountClaims(pub)
+	claims.Name = name
+	claims.Limits.JetStreamLimits = js
+	token, err := claims.Encode(operator)
+	if err != nil {
+		return "", err
+	}
+	return pub, s.writeKey(filepath.Join("accounts", name), token, kp)
+}
+
+// writeKey writes the JWT and seed of an entity as name.jwt and name.nk
+func (s *Store) writeKey(name, token string, kp nkeys.KeyPair) error {
+	seed, err := kp.Seed()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path(name+".jwt"), []byte(token+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(name+".nk"), append(seed, '\n'), 0o600)
+}
+
+// Options configures the server to trust the operator and resolve its
+// accounts, and returns the connection option of the agent. The agent
+// connects as a user of the HXE account created on every start.
+func (s *Store) Options(opts *server.Options) (nats.Option, error) {
+	data, err := os.ReadFile(s.path("operator.jwt"))
+	if err != nil {
+		return nil, fmt.Errorf("no operator, run hxe auth init: %w", err)
+	}
+	operator, err := jwt.DecodeOperatorClaims(string(bytes.TrimSpace(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid operator: %w", err)
+	}
+
+	resolver := &server.MemAccResolver{}
+	accounts, err := filepath.Glob(s.path("accounts", "*.jwt"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range accounts {
+		claims, token, err := s.readAccount(strings.TrimSuffix(filepath.Base(file), ".jwt"))
+		if err != nil {
+			return nil, err
+		}
+		if err := resolver.Store(claims.Subject, token); err != nil {
+			return nil, err
+		}
+	}
+
+	opts.TrustedOperators = []*jwt.OperatorClaims{operator}
+	opts.SystemAccount = operator.SystemAccount
+	opts.AccountResolver = resolver
+
+	account, err := s.readSeed(filepath.Join("accounts", ACCOUNT))
+	if err != nil {
+		return nil, err
+	}
+	token, seed, err := issueUser(account, "agent", Permissions{}, 0)
+	if err != nil {
+		return nil, err
+	}
+	return nats.UserJWTAndSeed(token, string(seed)), nil
+}
+
+// EnableJetStream enables JetStream for the HXE account of directories
+// initialized before agents used it, signing the account again
+func (s *Store) EnableJetStream() error {
+	account, _, err := s.readAccount(ACCOUNT)
+	if err != nil {
+		return err
+	}
+	if account.Limits.IsJSEnabled() {
+		return nil
+	}
+
+	operator, err := s.readSeed("operator")
+	if err != nil {
+		return err
+	}
+	account.Limits.JetStreamLimits = jetStream
+	token, err := account.Encode(operator)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path("accounts", ACCOUNT+".jwt"), []byte(token+"\n"), 0o644)
+}
+
+// Account returns the public key of the HXE account
+func (s *Store) Account() (string, error) {
+	claims, _, err := s.readAccount(ACCOUNT)
+	if err != nil {
+		return "", err
+	}
+	return claims.Subject, nil
+}
+
+// readAccount reads the claims and JWT of an account
+func (s *Store) readAccount(name string) (*jwt.AccountClaims, string, error) {
+	data, err := os.ReadFile(s.path("accounts", name+".jwt"))
+	if err != nil {
+		return nil, "", err
+	}
+	token := string(bytes.TrimSpace(data))
+	claims, err := jwt.DecodeAccountClaims(token)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid account %s: %w", name, err)
+	}
+	return claims, token, nil
+}
+
+// readSeed reads the key pair of name.nk
+func (s *Store) readSeed(name string) (nkeys.KeyPair, error) {
+	data, err := os.ReadFile(s.path(name + ".nk"))
+	if err != nil {
+		return nil, err
+	}
+	return nkeys.FromSeed(bytes.TrimSpace(data))
+}
+
+func (s *Store) path(elem ...string) string {
+	return filepath.Join(append([]string{s.Dir}, elem...)...)
+}
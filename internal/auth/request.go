@@ -0,0 +1,164 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+)
+
+const (
+	// USER_HEADER carries the JWT of the user that sent a request and
+	// SIGNATURE_HEADER the user's signature of the request subject, time,
+	// nonce and data
+	USER_HEADER      = "Hxe-User"
+	SIGNATURE_HEADER = "Hxe-Signature"
+	TIME_HEADER      = "Hxe-Time"
+	NONCE_HEADER     = "Hxe-Nonce"
+
+	// MaxRequestAge is how far the time a request was signed may be from
+	// the clock of the agent. Nonces are remembered for twice as long, so
+	// a request can not be replayed while it would still be accepted.
+	MaxRequestAge = time.Minute
+)
+
+// Sign returns the headers identifying the user a connection
+// authenticates as to agents, with a signature of the subject and data
+// of a request, the time and a random nonce. Connections without a user
+// JWT send no headers.
+func Sign(opts *nats.Options, subject string, data []byte) (nats.Header, error) {
+	return sign(opts, subject, data, time.Now())
+}
+
+func sign(opts *nats.Options, subject string, data []byte, now time.Time) (nats.Header, error) {
+	if opts.UserJWT == nil || opts.SignatureCB == nil {
+		return nil, nil
+	}
+	token, err := opts.UserJWT()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user JWT: %w", err)
+	}
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("failed to create nonce: %w", err)
+	}
+	ts, nonce := strconv.FormatInt(now.UnixMilli(), 10), base64.RawURLEncoding.EncodeToString(random)
+	sig, err := opts.SignatureCB(signed(subject, ts, nonce, data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	header := nats.Header{}
+	header.Set(USER_HEADER, token)
+	header.Set(SIGNATURE_HEADER, base64.RawURLEncoding.EncodeToString(sig))
+	header.Set(TIME_HEADER, ts)
+	header.Set(NONCE_HEADER, nonce)
+	return header, nil
+}
+
+// signed is what the signature of a request covers
+func signed(subject, ts, nonce string, data []byte) []byte {
+	return append([]byte(subject+"\n"+ts+"\n"+nonce+"\n"), data...)
+}
+
+// Verify returns the name of the user of the HXE account that signed a
+// request to subject, failing for requests that are unsigned, were
+// signed for another subject, are stale or were seen before, or that are
+// signed by users that expired, were revoked or belong to another
+// account
+func (s *Store) Verify(header nats.Header, subject string, data []byte) (string, error) {
+	token := header.Get(USER_HEADER)
+	if token == "" {
+		return "", errors.New("request is not signed by a user")
+	}
+	claims, err := jwt.DecodeUserClaims(token)
+	if err != nil {
+		return "", fmt.Errorf("invalid user: %w", err)
+	}
+
+	account, _, err := s.readAccount(ACCOUNT)
+	if err != nil {
+		return "", err
+	}
+	if claims.Issuer != account.Subject {
+		return "", fmt.Errorf("user %s is not a user of this agent", claims.Name)
+	}
+	vr := jwt.CreateValidationResults()
+	claims.Validate(vr)
+	if vr.IsBlocking(true) {
+		return "", fmt.Errorf("user %s is not valid: %v", claims.Name, vr.Errors())
+	}
+	if account.IsClaimRevoked(claims) {
+		return "", fmt.Errorf("user %s is revoked", claims.Name)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(header.Get(SIGNATURE_HEADER))
+	if err != nil {
+		return "", fmt.Errorf("invalid request signature: %w", err)
+	}
+	user, err := nkeys.FromPublicKey(claims.Subject)
+	if err != nil {
+		return "", fmt.Errorf("invalid user %s: %w", claims.Name, err)
+	}
+	ts, nonce := header.Get(TIME_HEADER), header.Get(NONCE_HEADER)
+	if err := user.Verify(signed(subject, ts, nonce, data), sig); err != nil {
+		return "", fmt.Errorf("request signature of user %s does not match", claims.Name)
+	}
+
+	ms, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || nonce == "" {
+		return "", errors.New("request has no time or nonce")
+	}
+	if age := time.Since(time.UnixMilli(ms)); age > MaxRequestAge || age < -MaxRequestAge {
+		return "", fmt.Errorf("request of user %s was signed %s ago, check the clocks of the hosts", claims.Name, age.Round(time.Second))
+	}
+	if !s.claim(nonce) {
+		return "", fmt.Errorf("request of user %s was already received", claims.Name)
+	}
+	return claims.Name, nil
+}
+
+// claim records a request nonce, reporting false if it was seen before
+func (s *Store) claim(nonce string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.nonces == nil {
+		s.nonces = map[string]time.Time{}
+	}
+	for n, expires := range s.nonces {
+		if now.After(expires) {
+			delete(s.nonces, n)
+		}
+	}
+	if _, seen := s.nonces[nonce]; seen {
+		return false
+	}
+	s.nonces[nonce] = now.Add(2 * MaxRequestAge)
+	return true
+}
@@ -0,0 +1,128 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// signer returns the connection options of a user with credentials
+func signer(t *testing.T, creds string) *nats.Options {
+	t.Helper()
+	opts := nats.GetDefaultOptions()
+	if err := nats.UserCredentials(creds)(&opts); err != nil {
+		t.Fatal(err)
+	}
+	return &opts
+}
+
+func TestVerify(t *testing.T) {
+	store := New(t.TempDir())
+	if err := store.Init(false); err != nil {
+		t.Fatal(err)
+	}
+	alice, err := store.AddUser("alice", Permissions{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := store.AddUser("bob", Permissions{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := New(t.TempDir())
+	if err := other.Init(false); err != nil {
+		t.Fatal(err)
+	}
+	mallory, err := other.AddUser("mallory", Permissions{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, data := "hxe.web1.program.run", []byte(`{"command":["id"]}`)
+	signAt := func(creds string, now time.Time) nats.Header {
+		header, err := sign(signer(t, creds), subject, data, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return header
+	}
+	sign := func(creds string) nats.Header {
+		return signAt(creds, time.Now())
+	}
+
+	if caller, err := store.Verify(sign(alice), subject, data); err != nil || caller != "alice" {
+		t.Errorf("Verify = %q, %v, want alice", caller, err)
+	}
+	if _, err := store.Verify(sign(alice), subject, []byte(`{"command":["rm"]}`)); err == nil {
+		t.Error("verified a signature of other data")
+	}
+	if _, err := store.Verify(sign(alice), "hxe.web2.program.run", data); err == nil {
+		t.Error("verified a request signed for another agent")
+	}
+	if _, err := store.Verify(nats.Header{}, subject, data); err == nil {
+		t.Error("verified an unsigned request")
+	}
+	if _, err := store.Verify(sign(mallory), subject, data); err == nil {
+		t.Error("verified a user of another account")
+	}
+
+	replayed := sign(alice)
+	if _, err := store.Verify(replayed, subject, data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Verify(replayed, subject, data); err == nil {
+		t.Error("verified a replayed request")
+	}
+	if _, err := store.Verify(signAt(alice, time.Now().Add(-2*MaxRequestAge)), subject, data); err == nil {
+		t.Error("verified a stale request")
+	}
+	if _, err := store.Verify(signAt(alice, time.Now().Add(2*MaxRequestAge)), subject, data); err == nil {
+		t.Error("verified a request from the future")
+	}
+	retimed := sign(alice)
+	retimed.Set(TIME_HEADER, "0")
+	if _, err := store.Verify(retimed, subject, data); err == nil {
+		t.Error("verified a request with a changed time")
+	}
+
+	forged := sign(bob)
+	forged.Set(USER_HEADER, sign(alice).Get(USER_HEADER))
+	if _, err := store.Verify(forged, subject, data); err == nil {
+		t.Error("verified a user with the signature of another user")
+	}
+
+	revoked := sign(bob)
+	if err := store.RemoveUser("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Verify(revoked, subject, data); err == nil {
+		t.Error("verified a revoked user")
+	}
+}
+
+func TestSignWithoutUser(t *testing.T) {
+	opts := nats.GetDefaultOptions()
+	header, err := Sign(&opts, "program.run", []byte("data"))
+	if err != nil || header != nil {
+		t.Errorf("Sign = %v, %v, want no headers", header, err)
+	}
+}
@@ -0,0 +1,223 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package auth
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+)
+
+// READ_ONLY are the subjects of read-only users: listing programs,
+// reading their state, history, runs, processes and crashes, and
+// replaying their events and output, on the agent connected to or any
+// agent of a fleet
+var READ_ONLY = []string{
+	"program.list",
+	"program.get",
+	"program.status",
+	"program.history",
+	"program.runs",
+	"program.ps",
+	"program.crashes",
+	"hxe.*.program.list",
+	"hxe.*.program.get",
+	"hxe.*.program.status",
+	"hxe.*.program.history",
+	"hxe.*.program.runs",
+	"hxe.*.program.ps",
+	"hxe.*.program.crashes",
+	"agent.info",
+	"hxe.*.agent.info",
+	"$SRV.INFO.agent",
+	"$JS.API.STREAM.INFO.*",
+	"$JS.API.CONSUMER.CREATE.*.>",
+	"$JS.API.CONSUMER.MSG.NEXT.*.*",
+	"$JS.API.CONSUMER.DELETE.*.*",
+	"$JS.*.API.STREAM.INFO.*",
+	"$JS.*.API.CONSUMER.CREATE.*.>",
+	"$JS.*.API.CONSUMER.MSG.NEXT.*.*",
+	"$JS.*.API.CONSUMER.DELETE.*.*",
+}
+
+// Permissions limits the subjects a user may publish and subscribe to.
+// Empty lists allow every subject.
+type Permissions struct {
+	Publish   []string
+	Subscribe []string
+}
+
+// User is a user of the HXE account
+type User struct {
+	Name      string
+	PublicKey string
+	Publish   []string
+	Subscribe []string
+	Expires   time.Time
+	Revoked   bool
+	Creds     string
+}
+
+// AddUser issues a user of the HXE account and writes its credentials
+// to users/<name>.creds, returning the path. Users expire after expires
+// when it isn't zero.
+func (s *Store) AddUser(name string, perms Permissions, expires time.Duration) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid user name %q", name)
+	}
+	file := s.path("users", name+".creds")
+	if _, err := os.Stat(file); err == nil {
+		return "", fmt.Errorf("user %s already exists", name)
+	}
+
+	account, err := s.readSeed(filepath.Join("accounts", ACCOUNT))
+	if err != nil {
+		return "", fmt.Errorf("no account, run hxe auth init: %w", err)
+	}
+	token, seed, err := issueUser(account, name, perms, expires)
+	if err != nil {
+		return "", err
+	}
+	creds, err := jwt.FormatUserConfig(token, seed)
+	if err != nil {
+		return "", err
+	}
+	return file, os.WriteFile(file, creds, 0o600)
+}
+
+// issueUser creates a user signed by the account and returns its JWT
+// and seed. Users limited to subscribe subjects may still receive
+// replies to their requests.
+func issueUser(account nkeys.KeyPair, name string, perms Permissions, expires time.Duration) (string, []byte, error) {
+	kp, err := nkeys.CreateUser()
+	if err != nil {
+		return "", nil, err
+	}
+	pub, err := kp.PublicKey()
+	if err != nil {
+		return "", nil, err
+	}
+	seed, err := kp.Seed()
+	if err != nil {
+		return "", nil, err
+	}
+
+	claims := jwt.NewUserClaims(pub)
+	claims.Name = name
+	claims.Pub.Allow.Add(perms.Publish...)
+	if len(perms.Subscribe) > 0 {
+		claims.Sub.Allow.Add(nats.InboxPrefix + ">")
+		claims.Sub.Allow.Add(perms.Subscribe...)
+	}
+	if expires > 0 {
+		claims.Expires = time.Now().Add(expires).Unix()
+	}
+	token, err := claims.Encode(account)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, seed, nil
+}
+
+// Users returns the users with credentials in the users directory
+func (s *Store) Users() ([]*User, error) {
+	account, _, err := s.readAccount(ACCOUNT)
+	if err != nil {
+		return nil, fmt.Errorf("no account, run hxe auth init: %w", err)
+	}
+	files, err := filepath.Glob(s.path("users", "*.creds"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	users := []*User{}
+	for _, file := range files {
+		claims, err := readUser(file)
+		if err != nil {
+			return nil, err
+		}
+		user := &User{
+			Name:      strings.TrimSuffix(filepath.Base(file), ".creds"),
+			PublicKey: claims.Subject,
+			Publish:   claims.Pub.Allow,
+			Subscribe: claims.Sub.Allow,
+			Revoked:   account.IsClaimRevoked(claims),
+			Creds:     file,
+		}
+		if claims.Expires > 0 {
+			user.Expires = time.Unix(claims.Expires, 0)
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// RemoveUser revokes a user in the HXE account and deletes its
+// credentials. Running agents keep accepting the user until they are
+// restarted.
+func (s *Store) RemoveUser(name string) error {
+	file := s.path("users", name+".creds")
+	claims, err := readUser(file)
+	if err != nil {
+		return err
+	}
+
+	operator, err := s.readSeed("operator")
+	if err != nil {
+		return err
+	}
+	account, _, err := s.readAccount(ACCOUNT)
+	if err != nil {
+		return err
+	}
+	account.Revoke(claims.Subject)
+	token, err := account.Encode(operator)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path("accounts", ACCOUNT+".jwt"), []byte(token+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Remove(file)
+}
+
+// readUser reads the claims of a creds file
+func readUser(file string) (*jwt.UserClaims, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	token, err := jwt.ParseDecoratedJWT(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials %s: %w", file, err)
+	}
+	claims, err := jwt.DecodeUserClaims(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials %s: %w", file, err)
+	}
+	return claims, nil
+}
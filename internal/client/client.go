@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/nats-io/nats.go"
+	ac "github.com/rangertaha/hxe/internal/agent/client"
 	"github.com/rangertaha/hxe/internal/config"
 	prog "github.com/rangertaha/hxe/internal/services/program/client"
 )
@@ -25,6 +26,7 @@ type (
 	Client struct {
 		Config   *config.Client
 		Programs *prog.Client
+		Agent    *ac.Client
 		conn     *nats.Conn
 	}
 )
@@ -41,18 +43,30 @@ func New(c *config.Client) (clt *Client, err error) {
 		return nil, err
 	}
 
-	clt.Programs = prog.New(clt.conn)
+	clt.Programs = prog.New(clt.conn).WithTimeout(c.Timeout)
+	clt.Agent = ac.New(clt.conn)
 
 	return clt, nil
 }
 
+// For returns a client of the agent with the given ID, reached through
+// the same connection
+func (c *Client) For(agent string) *Client {
+	return &Client{
+		Config:   c.Config,
+		Programs: c.Programs.Agent(agent),
+		Agent:    c.Agent.Agent(agent),
+		conn:     c.conn,
+	}
+}
+
 func (c *Client) Init() (err error) {
 	// clientOpts := []nats.Option{
 	// 	nats.Name("hxe-client"),
 	// 	nats.FlusherTimeout(5 * time.Second),
 	// }
 
-	c.conn, err = nats.Connect(c.Config.Url, c.Config.Options())
+	c.conn, err = nats.Connect(c.Config.URL(), c.Config.Options())
 	if err != nil {
 		return fmt.Errorf("failed to connect to messaging server: %w", err)
 	}
@@ -23,11 +23,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2"
-	"github.com/hashicorp/hcl/v2/hclsimple"
 	"github.com/nats-io/nats.go"
 	"github.com/rangertaha/hxe/internal"
+	"github.com/rangertaha/hxe/internal/auth"
 	"github.com/rangertaha/hxe/internal/db"
 	"github.com/rangertaha/hxe/internal/log"
 	"gorm.io/driver/sqlite"
@@ -61,6 +62,7 @@ type (
 		// Config
 		configFile string
 		configDir  string
+		sources    Sources
 
 		Server   Server     `hcl:"server,block"`
 		Services []*Service `hcl:"service,block"`
@@ -70,6 +72,14 @@ type (
 		Directory string `hcl:"directory,optional"`
 		Conn      *nats.Conn
 		Config    hcl.Body `hcl:"config,remain"`
+
+		// Agent is the ID of the agent, namespacing the subjects of the
+		// service
+		Agent string
+
+		// Auth verifies the users that sign requests, nil without
+		// decentralized authentication
+		Auth *auth.Store
 	}
 )
 
@@ -84,6 +94,7 @@ func NewAgentConfig(options ...func(*AgentConfig) error) (*AgentConfig, error) {
 		Banner:  true,
 		Debug:   false,
 		Version: internal.VERSION,
+		sources: Sources{},
 	}
 
 	// Apply config options
@@ -105,6 +116,58 @@ func NewAgentConfig(options ...func(*AgentConfig) error) (*AgentConfig, error) {
 	return s, nil
 }
 
+// Path resolves a path relative to the directory of the config file
+func (c *AgentConfig) Path(path string) string {
+	if filepath.IsAbs(path) || c.configFile == "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(c.configFile), path)
+}
+
+// AgentID returns the ID of the agent, the short host name unless id is
+// set. It namespaces the subjects of the agent, so it may not contain
+// dots, spaces, * or >.
+func (c *AgentConfig) AgentID() (string, error) {
+	id := c.ID
+	if id == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return "", fmt.Errorf("no agent id and no host name: %w", err)
+		}
+		id, _, _ = strings.Cut(hostname, ".")
+	}
+	if id == "" || strings.ContainsAny(id, ".*> \t\r\n") {
+		return "", fmt.Errorf("invalid agent id %q, it may not be empty or contain dots, spaces, * or >", id)
+	}
+	return id, nil
+}
+
+// File returns the path of the config file, if the config was read from one
+func (c *AgentConfig) File() string {
+	return c.configFile
+}
+
+// Reload reads the config file again into a new config
+func (c *AgentConfig) Reload() (*AgentConfig, error) {
+	if c.configFile == "" {
+		return nil, fmt.Errorf("config was not read from a file")
+	}
+	conf := &AgentConfig{
+		Banner:     c.Banner,
+		Version:    c.Version,
+		configFile: c.configFile,
+		configDir:  c.configDir,
+		sources:    Sources{},
+	}
+	if err := decodeFile(c.configFile, conf, conf.sources); err != nil {
+		return nil, fmt.Errorf("error parsing config file: %w", err)
+	}
+	if err := applyEnv(conf, conf.sources); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
 func AgentCliOpts(ctx context.Context, cmd *cli.Command) func(c *AgentConfig) error {
 	return func(c *AgentConfig) error {
 		if cmd.String("config") != "" {
@@ -119,8 +182,45 @@ func AgentCliOpts(ctx context.Context, cmd *cli.Command) func(c *AgentConfig) er
 				return err
 			}
 		}
-		return nil
+		return AgentEnvOpts()(c)
+	}
+}
+
+// LoadAgentConfig reads an agent config file, or the default one when
+// path is empty, and applies the environment. Unlike the server it
+// doesn't open the database.
+func LoadAgentConfig(path string) (*AgentConfig, error) {
+	c := &AgentConfig{Banner: true, Version: internal.VERSION, sources: Sources{}, configFile: path}
+	if path == "" {
+		userConfigDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("error getting user config directory: %w", err)
+		}
+		c.configDir = filepath.Join(userConfigDir, CONFIG_DIR)
+		c.configFile = filepath.Join(c.configDir, AGENT_CONFIG_FILE)
+		if err := createFileIfNotExists(c.configFile, DefaultAgentConfig); err != nil {
+			return nil, fmt.Errorf("error creating config file: %w", err)
+		}
 	}
+
+	if err := decodeFile(c.configFile, c, c.sources); err != nil {
+		return nil, fmt.Errorf("error parsing config file: %w", err)
+	}
+	return c, applyEnv(c, c.sources)
+}
+
+// AgentEnvOpts overrides attributes with HXE_* environment variables,
+// which take precedence over the config file
+func AgentEnvOpts() func(*AgentConfig) error {
+	return func(c *AgentConfig) error {
+		return applyEnv(c, c.sources)
+	}
+}
+
+// Effective returns every attribute of the config with its value and
+// where it came from
+func (c *AgentConfig) Effective() []Value {
+	return Effective(c, c.sources)
 }
 
 func AgentFileOpts(path string) func(*AgentConfig) (err error) {
@@ -128,7 +228,7 @@ func AgentFileOpts(path string) func(*AgentConfig) (err error) {
 		if path == "" {
 			return fmt.Errorf("config file path is required")
 		}
-		if err = hclsimple.DecodeFile(path, CtxFunctions, c); err != nil {
+		if err = decodeFile(path, c, c.sources); err != nil {
 			return fmt.Errorf("error parsing config file: %w", err)
 		}
 		return nil
@@ -152,7 +252,7 @@ func AgentDefaultOpts() func(*AgentConfig) (err error) {
 		}
 
 		// Load config
-		if err = hclsimple.DecodeFile(c.configFile, CtxFunctions, c); err != nil {
+		if err = decodeFile(c.configFile, c, c.sources); err != nil {
 			return fmt.Errorf("error parsing config file: %w", err)
 		}
 
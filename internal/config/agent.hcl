@@ -3,11 +3,39 @@
 
 // Hxe API Server
 server "hxe" {
-  host = "0.0.0.0"
+  // Only local clients until authentication is configured below, then
+  // listen on every interface with host = "0.0.0.0"
+  host = "127.0.0.1"
   port = 3143
-} 
+
+  // Require clients to authenticate, and connect with TLS
+  // user "admin" {
+  //   password = "change-me"
+  // }
+  // tls {
+  //   cert = "tls/server.pem"
+  //   key  = "tls/server.key"
+  // }
+
+  // Or with user JWTs of the operator written by hxe auth init
+  // auth_dir = "auth"
+
+  // Keep program events and output, replayed with hxe program logs
+  // jetstream {
+  //   logs {
+  //     max_age = duration("24h")
+  //   }
+  // }
+}
 service "programs" {
   directory = "programs"
+
+  // Crash reports of programs killed by SIGSEGV, SIGABRT and the like
+  crash {
+    lines       = 50
+    retention   = days(30)
+    max_reports = 20
+  }
 }
 
 // Timeseries Database: (Optional) Timeseries database client connection
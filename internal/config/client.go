@@ -21,11 +21,12 @@ package config
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
-	"github.com/hashicorp/hcl/v2/hclsimple"
 	"github.com/nats-io/nats.go"
 
 	_ "embed"
@@ -35,8 +36,8 @@ import (
 
 const (
 	DefaultPort        = 3143
-	DefaultHost        = "0.0.0.0"
-	DefaultBaseURL     = "nats://0.0.0.0:3143"
+	DefaultHost        = "localhost"
+	DefaultProfile     = "default"
 	DefaultUsername    = ""
 	DefaultPassword    = ""
 	DefaultTimeout     = 30 * time.Second
@@ -48,13 +49,19 @@ var DefaultClientConfig []byte
 
 type (
 	ClientConfig struct {
+		// Default is the profile used when none is given with --profile
+		Default    string    `hcl:"default,optional"`
 		Clients    []*Client `hcl:"client,block"`
 		profile    string
 		configFile string
 		configDir  string
+		sources    Sources
 	}
 	Client struct {
-		Name     string        `hcl:"name,label"`
+		Name string `hcl:"name,label"`
+
+		// The URL of the agent, or its host and port. IPC connects to the
+		// agent on this host over the loopback interface.
 		UseIPC   bool          `hcl:"ipc,optional"`
 		Host     string        `hcl:"host,optional"`
 		Url      string        `hcl:"url,optional"`
@@ -64,19 +71,23 @@ type (
 		Password string        `hcl:"password,optional"`
 		Username string        `hcl:"username,optional"`
 		Timeout  time.Duration `hcl:"timeout,optional"`
+
+		// Credentials file of a user JWT, written by hxe auth user add
+		Creds string `hcl:"creds,optional"`
+
+		// TLS CA to verify the server with, and the certificate and key
+		// for servers that verify clients
+		TLSCA   string `hcl:"tls_ca,optional"`
+		TLSCert string `hcl:"tls_cert,optional"`
+		TLSKey  string `hcl:"tls_key,optional"`
 	}
 )
 
 // New creates a new configuration
 func NewClientConfig(options ...func(*ClientConfig) error) (client *Client, err error) {
-	s := &ClientConfig{}
-
-	// Apply config options
-	for _, opt := range options {
-		err := opt(s)
-		if err != nil {
-			return nil, err
-		}
+	s, err := LoadClientConfig(options...)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get the client configuration
@@ -87,6 +98,23 @@ func NewClientConfig(options ...func(*ClientConfig) error) (client *Client, err
 	return client, nil
 }
 
+// LoadClientConfig applies the options to a new client configuration
+func LoadClientConfig(options ...func(*ClientConfig) error) (*ClientConfig, error) {
+	s := &ClientConfig{sources: Sources{}}
+
+	// Apply config options
+	for _, opt := range options {
+		err := opt(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// ClientCliOpts loads the config file given with --config, then applies
+// HXE_* environment variables and finally the flags that were set to the
+// selected profile
 func ClientCliOpts(ctx context.Context, cmd *cli.Command) func(c *ClientConfig) error {
 	return func(c *ClientConfig) error {
 		if cmd.String("config") != "" {
@@ -102,54 +130,82 @@ func ClientCliOpts(ctx context.Context, cmd *cli.Command) func(c *ClientConfig)
 		}
 
 		for i := range c.Clients {
-
-			c.Clients[i].UseIPC = false
-			if cmd.Bool("ipc") {
-				c.Clients[i].UseIPC = true
-			}
-
-			c.Clients[i].Host = DefaultHost
-			if cmd.String("host") != "" {
-				c.Clients[i].Host = cmd.String("host")
-			}
-
-			c.Clients[i].Url = DefaultBaseURL
-			if cmd.Int("port") != 0 {
-				c.Clients[i].Port = cmd.Int("port")
-			}
-
-			c.Clients[i].Username = DefaultUsername
-			if cmd.String("username") != "" {
-				c.Clients[i].Username = cmd.String("username")
-			}
-
-			c.Clients[i].Password = DefaultPassword
-			if cmd.String("password") != "" {
-				c.Clients[i].Password = cmd.String("password")
+			if c.Clients[i].Timeout == 0 {
+				c.Clients[i].Timeout = DefaultTimeout
 			}
+		}
 
-			c.Clients[i].Token = ""
-			if cmd.String("token") != "" {
-				c.Clients[i].Token = cmd.String("token")
-			}
+		if err := applyEnv(c, c.sources); err != nil {
+			return err
+		}
 
-			if cmd.Bool("timeout") {
-				c.Clients[i].Timeout = cmd.Duration("timeout")
-			}
+		client, err := c.Profile()
+		if err != nil {
+			// NewClientConfig reports the missing profile
+			return nil
+		}
+		key := "client." + client.Name
 
+		if cmd.IsSet("ipc") {
+			client.UseIPC = cmd.Bool("ipc")
+			c.sources[key+".ipc"] = "flag --ipc"
+		}
+		if cmd.IsSet("host") {
+			client.Host = cmd.String("host")
+			c.sources[key+".host"] = "flag --host"
+		}
+		if cmd.IsSet("port") {
+			client.Port = int(cmd.Int("port"))
+			c.sources[key+".port"] = "flag --port"
+		}
+		if cmd.IsSet("host") || cmd.IsSet("port") {
+			// The host and port replace the URL of the profile
+			client.Url = ""
+			delete(c.sources, key+".url")
+		}
+		if cmd.IsSet("url") {
+			client.Url = cmd.String("url")
+			c.sources[key+".url"] = "flag --url"
+		}
+		if cmd.IsSet("username") {
+			client.Username = cmd.String("username")
+			c.sources[key+".username"] = "flag --username"
+		}
+		if cmd.IsSet("password") {
+			client.Password = cmd.String("password")
+			c.sources[key+".password"] = "flag --password"
+		}
+		if cmd.IsSet("token") {
+			client.Token = cmd.String("token")
+			c.sources[key+".token"] = "flag --token"
+		}
+		if cmd.IsSet("creds") {
+			client.Creds = cmd.String("creds")
+			c.sources[key+".creds"] = "flag --creds"
+		}
+		if cmd.IsSet("timeout") {
+			client.Timeout = cmd.Duration("timeout")
+			c.sources[key+".timeout"] = "flag --timeout"
 		}
 
 		return nil
 	}
 }
 
+// Effective returns every attribute of the config with its value and
+// where it came from
+func (c *ClientConfig) Effective() []Value {
+	return Effective(c, c.sources)
+}
+
 func ClientFileOption(path string) func(*ClientConfig) (err error) {
 	return func(c *ClientConfig) (err error) {
 		if path == "" {
 			return fmt.Errorf("config file path is required")
 		}
 
-		if err = hclsimple.DecodeFile(path, CtxFunctions, c); err != nil {
+		c.configFile = path
+		if err = decodeFile(path, c, c.sources); err != nil {
 			return fmt.Errorf("error parsing config file: %w", err)
 		}
 		return nil
@@ -172,7 +228,7 @@ func ClientDefaultOptions() func(*ClientConfig) (err error) {
 			}
 		}
 
-		if err = hclsimple.DecodeFile(c.configFile, CtxFunctions, c); err != nil {
+		if err = decodeFile(c.configFile, c, c.sources); err != nil {
 			return fmt.Errorf("error parsing config file: %w", err)
 		}
 
@@ -180,6 +236,11 @@ func ClientDefaultOptions() func(*ClientConfig) (err error) {
 	}
 }
 
+// File returns the path of the config file the profiles were read from
+func (c *ClientConfig) File() string {
+	return c.configFile
+}
+
 func ClientProfileOpts(profile string) func(*ClientConfig) (err error) {
 	return func(c *ClientConfig) (err error) {
 		if profile != "" {
@@ -214,10 +275,23 @@ func ClientConfigOpts(config *ClientConfig) func(*ClientConfig) (err error) {
 // 	return
 // }
 
-// Profile returns the client configuration for the specified profile name
+// Selected returns the name of the profile given with --profile, the
+// default profile of the file, or "default"
+func (c *ClientConfig) Selected() string {
+	if c.profile != "" {
+		return c.profile
+	}
+	if c.Default != "" {
+		return c.Default
+	}
+	return DefaultProfile
+}
+
+// Profile returns the client configuration for the specified profile
+// name, or the selected profile
 func (c *ClientConfig) Profile(names ...string) (*Client, error) {
 	if len(names) == 0 {
-		names = append(names, c.profile)
+		names = append(names, c.Selected())
 	}
 
 	// Return the first client that matches the profile name
@@ -228,7 +302,27 @@ func (c *ClientConfig) Profile(names ...string) (*Client, error) {
 			}
 		}
 	}
-	return nil, fmt.Errorf("client not found")
+	return nil, fmt.Errorf("profile %q not found in %s", names[0], c.configFile)
+}
+
+// URL returns the URL of the agent, the url attribute when set or one
+// composed of the host and port
+func (c *Client) URL() string {
+	if c.Url != "" {
+		return c.Url
+	}
+
+	host, port := c.Host, c.Port
+	if c.UseIPC {
+		host = "127.0.0.1"
+	}
+	if host == "" {
+		host = DefaultHost
+	}
+	if port == 0 {
+		port = DefaultPort
+	}
+	return "nats://" + net.JoinHostPort(host, strconv.Itoa(port))
 }
 
 func (c *Client) Options() (opts nats.Option) {
@@ -239,14 +333,24 @@ func (c *Client) Options() (opts nats.Option) {
 		nats.FlusherTimeout(c.Timeout)(o)
 
 		o.Name = c.Name
-		o.Url = c.Url
+		o.Url = c.URL()
 		o.User = c.Username
 		o.Password = c.Password
 		o.Token = c.Token
 		o.Timeout = c.Timeout
-		o.Servers = []string{c.Url}
-		if c.UseIPC {
-			o.Servers = []string{"nats://localhost:4222"}
+		o.Servers = []string{o.Url}
+		if c.Creds != "" {
+			if err := nats.UserCredentials(c.Creds)(o); err != nil {
+				return err
+			}
+		}
+		if c.TLSCA != "" {
+			if err := nats.RootCAs(c.TLSCA)(o); err != nil {
+				return err
+			}
+		}
+		if c.TLSCert != "" || c.TLSKey != "" {
+			return nats.ClientCert(c.TLSCert, c.TLSKey)(o)
 		}
 		return nil
 	}
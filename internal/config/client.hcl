@@ -1,6 +1,9 @@
 // Hxe client configuration
 //
 
+// Profile used when --profile isn't given, changed with hxe profile use
+default = "default"
+
 client "default" {
   ipc = true
   host = "0.0.0.0"
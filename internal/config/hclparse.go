@@ -19,11 +19,18 @@
 package config
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 	"time"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
 	"github.com/zclconf/go-cty/cty/gocty"
 )
 
@@ -31,14 +38,30 @@ const YYYYMMDD = "2006/01/02"
 
 var CtxFunctions *hcl.EvalContext = &hcl.EvalContext{
 	Functions: map[string]function.Function{
-		"seconds": SecondsFunc,
-		"minutes": MinutesFunc,
-		"hours":   HoursFunc,
-		"days":    DaysFunc,
-		"date":    DateFunc,
+		"seconds":  SecondsFunc,
+		"minutes":  MinutesFunc,
+		"hours":    HoursFunc,
+		"days":     DaysFunc,
+		"date":     DateFunc,
+		"duration": DurationFunc,
+		"env":      EnvFunc,
+		"file":     FileFunc,
+		"hostname": HostnameFunc,
+		"cpus":     CpusFunc,
+		"join":     stdlib.JoinFunc,
+		"split":    stdlib.SplitFunc,
+		"upper":    stdlib.UpperFunc,
+		"lower":    stdlib.LowerFunc,
+		"coalesce": CoalesceFunc,
 	},
 }
 
+// templatefile renders templates with the other functions, so it is
+// added once CtxFunctions exists
+func init() {
+	CtxFunctions.Functions["templatefile"] = TemplateFileFunc
+}
+
 var SecondsFunc = function.New(&function.Spec{
 	Description: "Returns the given seconds",
 	Params: []function.Parameter{
@@ -100,7 +123,7 @@ var HoursFunc = function.New(&function.Spec{
 })
 
 var DaysFunc = function.New(&function.Spec{
-	Description: "Returns the given hours",
+	Description: "Returns the given days",
 	Params: []function.Parameter{
 		{
 			Name:             "num",
@@ -143,3 +166,216 @@ var DateFunc = function.New(&function.Spec{
 		return cty.NumberIntVal(t.Unix()), nil
 	},
 })
+
+var DurationFunc = function.New(&function.Spec{
+	Description: "Returns a duration string such as 1h30m",
+	Params: []function.Parameter{
+		{
+			Name: "duration",
+			Type: cty.String,
+		},
+	},
+	Type: function.StaticReturnType(cty.Number),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		d, err := time.ParseDuration(args[0].AsString())
+		if err != nil {
+			return cty.UnknownVal(cty.Number), err
+		}
+
+		return cty.NumberIntVal(int64(d)), nil
+	},
+})
+
+var EnvFunc = function.New(&function.Spec{
+	Description: "Returns an environment variable, or the default when it is not set",
+	Params: []function.Parameter{
+		{
+			Name: "name",
+			Type: cty.String,
+		},
+	},
+	VarParam: &function.Parameter{
+		Name: "default",
+		Type: cty.String,
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		name := args[0].AsString()
+		if value, ok := os.LookupEnv(name); ok {
+			return cty.StringVal(value), nil
+		}
+		switch len(args) {
+		case 1:
+			return cty.UnknownVal(cty.String), fmt.Errorf("environment variable %s is not set", name)
+		case 2:
+			return args[1], nil
+		}
+		return cty.UnknownVal(cty.String), fmt.Errorf("env takes a name and at most one default")
+	},
+})
+
+// FileFunc returns the contents of a file, relative to the working
+// directory
+var FileFunc = MakeFileFunc("")
+
+// MakeFileFunc returns a file function that reads relative paths from dir
+func MakeFileFunc(dir string) function.Function {
+	return function.New(&function.Spec{
+		Description: "Returns the contents of a file, relative to the directory of the config file",
+		Params: []function.Parameter{
+			{
+				Name: "path",
+				Type: cty.String,
+			},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			data, err := os.ReadFile(resolvePath(dir, args[0].AsString()))
+			if err != nil {
+				return cty.UnknownVal(cty.String), err
+			}
+
+			return cty.StringVal(string(data)), nil
+		},
+	})
+}
+
+// TemplateFileFunc renders a file as a template, relative to the working
+// directory
+var TemplateFileFunc = MakeTemplateFileFunc("")
+
+// MakeTemplateFileFunc returns a templatefile function that reads relative
+// paths from dir
+func MakeTemplateFileFunc(dir string) function.Function {
+	return function.New(&function.Spec{
+		Description: "Renders a file, relative to the directory of the config file, as a template with the given variables",
+		Params: []function.Parameter{
+			{
+				Name: "path",
+				Type: cty.String,
+			},
+			{
+				Name: "vars",
+				Type: cty.DynamicPseudoType,
+			},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			path, vars := args[0].AsString(), args[1]
+			if !vars.Type().IsObjectType() && !vars.Type().IsMapType() {
+				return cty.UnknownVal(cty.String), fmt.Errorf("template variables must be an object or a map")
+			}
+
+			path = resolvePath(dir, path)
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return cty.UnknownVal(cty.String), err
+			}
+			expr, diags := hclsyntax.ParseTemplate(src, path, hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				return cty.UnknownVal(cty.String), diags
+			}
+
+			// Templates can use every function but templatefile itself,
+			// with paths relative to the same directory
+			funcs := Functions(dir)
+			delete(funcs, "templatefile")
+			ctx := &hcl.EvalContext{Variables: map[string]cty.Value{}, Functions: funcs}
+			if !vars.IsNull() && vars.LengthInt() > 0 {
+				ctx.Variables = vars.AsValueMap()
+			}
+
+			value, diags := expr.Value(ctx)
+			if diags.HasErrors() {
+				return cty.UnknownVal(cty.String), diags
+			}
+			if value, err = convert.Convert(value, cty.String); err != nil {
+				return cty.UnknownVal(cty.String), fmt.Errorf("template %s: %w", path, err)
+			}
+			return value, nil
+		},
+	})
+}
+
+// Functions returns the functions of CtxFunctions with file and
+// templatefile reading relative paths from dir
+func Functions(dir string) map[string]function.Function {
+	funcs := map[string]function.Function{}
+	for name, fn := range CtxFunctions.Functions {
+		funcs[name] = fn
+	}
+	funcs["file"] = MakeFileFunc(dir)
+	funcs["templatefile"] = MakeTemplateFileFunc(dir)
+	return funcs
+}
+
+// FileContext returns the context to evaluate the config file at path in,
+// where file and templatefile read paths relative to its directory
+func FileContext(path string) *hcl.EvalContext {
+	if path == "" {
+		return CtxFunctions
+	}
+	return &hcl.EvalContext{Functions: Functions(filepath.Dir(path))}
+}
+
+// resolvePath resolves a relative path against dir
+func resolvePath(dir, path string) string {
+	if dir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+var CoalesceFunc = function.New(&function.Spec{
+	Description: "Returns the first argument that is neither null nor an empty string",
+	Params:      []function.Parameter{},
+	VarParam: &function.Parameter{
+		Name:             "vals",
+		Type:             cty.DynamicPseudoType,
+		AllowDynamicType: true,
+		AllowNull:        true,
+	},
+	Type: func(args []cty.Value) (cty.Type, error) {
+		types := make([]cty.Type, len(args))
+		for i, arg := range args {
+			types[i] = arg.Type()
+		}
+		retType, _ := convert.UnifyUnsafe(types)
+		if retType == cty.NilType {
+			return cty.NilType, fmt.Errorf("all arguments must have the same type")
+		}
+		return retType, nil
+	},
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		for _, arg := range args {
+			if arg.IsNull() || (arg.Type() == cty.String && arg.AsString() == "") {
+				continue
+			}
+			return convert.Convert(arg, retType)
+		}
+		return cty.NilVal, fmt.Errorf("no non-empty arguments")
+	},
+})
+
+var HostnameFunc = function.New(&function.Spec{
+	Description: "Returns the hostname of the host",
+	Params:      []function.Parameter{},
+	Type:        function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return cty.UnknownVal(cty.String), err
+		}
+
+		return cty.StringVal(hostname), nil
+	},
+})
+
+var CpusFunc = function.New(&function.Spec{
+	Description: "Returns the number of CPUs of the host",
+	Params:      []function.Parameter{},
+	Type:        function.StaticReturnType(cty.Number),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		return cty.NumberIntVal(int64(runtime.NumCPU())), nil
+	},
+})
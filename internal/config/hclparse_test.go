@@ -0,0 +1,157 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// eval evaluates an expression in the context of a config file in dir
+func eval(t *testing.T, dir, src string) (cty.Value, hcl.Diagnostics) {
+	t.Helper()
+	expr, diags := hclsyntax.ParseExpression([]byte(src), "test.hcl", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatal(diags.Error())
+	}
+	return expr.Value(FileContext(filepath.Join(dir, "agent.hcl")))
+}
+
+// chdir changes the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestFunctions(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"motd.txt":          "hello",
+		"conf/app.tpl":      `port=${port} name=${upper(name)} motd=${file("motd.txt")}`,
+		"conf/self.tpl":     `${templatefile("conf/app.tpl", {})}`,
+		"conf/invalid.tpl":  `${`,
+		"conf/variable.tpl": `${missing}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// relative paths must not depend on the working directory
+	chdir(t, t.TempDir())
+	t.Setenv("HXE_TEST_VALUE", "set")
+	hostname, _ := os.Hostname()
+
+	tests := []struct {
+		name string
+		expr string
+		want cty.Value
+		err  string
+	}{
+		{name: "seconds", expr: `seconds(30)`, want: cty.NumberIntVal(int64(30 * time.Second))},
+		{name: "minutes", expr: `minutes(2)`, want: cty.NumberIntVal(int64(2 * time.Minute))},
+		{name: "hours", expr: `hours(1)`, want: cty.NumberIntVal(int64(time.Hour))},
+		{name: "days", expr: `days(1)`, want: cty.NumberIntVal(int64(24 * time.Hour))},
+		{name: "date", expr: `date("2025/01/02")`, want: cty.NumberIntVal(time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC).Unix())},
+		{name: "invalid date", expr: `date("2025-01-02")`, err: "cannot parse"},
+		{name: "duration", expr: `duration("1h30m")`, want: cty.NumberIntVal(int64(90 * time.Minute))},
+		{name: "invalid duration", expr: `duration("soon")`, err: "invalid duration"},
+		{name: "env", expr: `env("HXE_TEST_VALUE")`, want: cty.StringVal("set")},
+		{name: "env default", expr: `env("HXE_TEST_UNSET", "default")`, want: cty.StringVal("default")},
+		{name: "env unset", expr: `env("HXE_TEST_UNSET")`, err: "HXE_TEST_UNSET is not set"},
+		{name: "env too many defaults", expr: `env("HXE_TEST_UNSET", "a", "b")`, err: "at most one default"},
+		{name: "file", expr: `file("motd.txt")`, want: cty.StringVal("hello")},
+		{name: "file absolute", expr: `file("` + filepath.Join(dir, "motd.txt") + `")`, want: cty.StringVal("hello")},
+		{name: "file missing", expr: `file("missing.txt")`, err: "no such file"},
+		{name: "hostname", expr: `hostname()`, want: cty.StringVal(hostname)},
+		{name: "cpus", expr: `cpus()`, want: cty.NumberIntVal(int64(runtime.NumCPU()))},
+		{name: "join", expr: `join(",", ["a", "b"])`, want: cty.StringVal("a,b")},
+		{name: "split", expr: `split(",", "a,b")`, want: cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")})},
+		{name: "upper", expr: `upper("a")`, want: cty.StringVal("A")},
+		{name: "lower", expr: `lower("A")`, want: cty.StringVal("a")},
+		{name: "coalesce", expr: `coalesce("", "a", "b")`, want: cty.StringVal("a")},
+		{name: "coalesce mixed types", expr: `coalesce("a", ["b"])`, err: "same type"},
+		{name: "coalesce empty", expr: `coalesce("", null)`, err: "no non-empty arguments"},
+		{
+			name: "templatefile",
+			expr: `templatefile("conf/app.tpl", { port = 80, name = "web" })`,
+			want: cty.StringVal("port=80 name=WEB motd=hello"),
+		},
+		{name: "templatefile recursion", expr: `templatefile("conf/self.tpl", {})`, err: "no function named \"templatefile\""},
+		{name: "templatefile invalid", expr: `templatefile("conf/invalid.tpl", {})`, err: "conf/invalid.tpl"},
+		{name: "templatefile unknown variable", expr: `templatefile("conf/variable.tpl", {})`, err: "Unknown variable"},
+		{name: "templatefile variables", expr: `templatefile("conf/app.tpl", "vars")`, err: "object or a map"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diags := eval(t, dir, tt.expr)
+			if tt.err != "" {
+				if !diags.HasErrors() || !strings.Contains(diags.Error(), tt.err) {
+					t.Fatalf("errors = %v, want %q", diags, tt.err)
+				}
+				return
+			}
+			if diags.HasErrors() {
+				t.Fatal(diags.Error())
+			}
+			if !got.RawEquals(tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeFileRelative(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "agent.hcl")
+	if err := os.WriteFile(path, []byte(`server "hxe" { token = file("token") }`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, t.TempDir())
+
+	conf := &AgentConfig{}
+	if err := decodeFile(path, conf, Sources{}); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Server.Token != "secret" {
+		t.Errorf("token = %q, want secret", conf.Server.Token)
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+// AddProfile appends a client block for the profile to the config file
+func (c *ClientConfig) AddProfile(client *Client) error {
+	if _, err := c.Profile(client.Name); err == nil {
+		return fmt.Errorf("profile %q already exists", client.Name)
+	}
+
+	err := c.edit(func(body *hclwrite.Body) error {
+		if len(body.Attributes()) > 0 || len(body.Blocks()) > 0 {
+			body.AppendNewline()
+		}
+		return encodeClient(body.AppendNewBlock("client", []string{client.Name}).Body(), client)
+	})
+	if err != nil {
+		return err
+	}
+	c.Clients = append(c.Clients, client)
+	return nil
+}
+
+// RemoveProfile removes the client block of a profile from the config
+// file, and unsets the default profile when it was the default
+func (c *ClientConfig) RemoveProfile(name string) error {
+	if _, err := c.Profile(name); err != nil {
+		return err
+	}
+
+	err := c.edit(func(body *hclwrite.Body) error {
+		for _, block := range body.Blocks() {
+			if block.Type() == "client" && len(block.Labels()) == 1 && block.Labels()[0] == name {
+				body.RemoveBlock(block)
+			}
+		}
+		if c.Default == name {
+			body.RemoveAttribute("default")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, client := range c.Clients {
+		if client.Name == name {
+			c.Clients = append(c.Clients[:i], c.Clients[i+1:]...)
+			break
+		}
+	}
+	if c.Default == name {
+		c.Default = ""
+	}
+	return nil
+}
+
+// SetDefault sets the profile used when none is given with --profile
+func (c *ClientConfig) SetDefault(name string) error {
+	if _, err := c.Profile(name); err != nil {
+		return err
+	}
+
+	err := c.edit(func(body *hclwrite.Body) error {
+		if body.GetAttribute("default") != nil {
+			body.SetAttributeValue("default", cty.StringVal(name))
+			return nil
+		}
+
+		// Keep the default above the profiles
+		rest := body.BuildTokens(nil)
+		body.Clear()
+		body.SetAttributeValue("default", cty.StringVal(name))
+		body.AppendNewline()
+		body.AppendUnstructuredTokens(rest)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.Default = name
+	return nil
+}
+
+// edit rewrites the config file with the changes fn makes to its body,
+// keeping comments and the layout of everything else
+func (c *ClientConfig) edit(fn func(body *hclwrite.Body) error) error {
+	if strings.HasSuffix(c.configFile, ".json") {
+		return fmt.Errorf("%s is HCL JSON, edit it by hand", c.configFile)
+	}
+
+	info, err := os.Stat(c.configFile)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(c.configFile)
+	if err != nil {
+		return err
+	}
+	file, diags := hclwrite.ParseConfig(data, c.configFile, hcl.InitialPos)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	if err := fn(file.Body()); err != nil {
+		return err
+	}
+	return os.WriteFile(c.configFile, hclwrite.Format(file.Bytes()), info.Mode().Perm())
+}
+
+// encodeClient writes the attributes of a profile that are set into body
+func encodeClient(body *hclwrite.Body, client *Client) error {
+	v := reflect.ValueOf(client).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name, kind, ok := hclTag(v.Type().Field(i))
+		field := v.Field(i)
+		if !ok || kind == "label" || field.IsZero() {
+			continue
+		}
+
+		if field.Type() == durationType {
+			body.SetAttributeRaw(name, hclwrite.TokensForFunctionCall("duration",
+				hclwrite.TokensForValue(cty.StringVal(time.Duration(field.Int()).String()))))
+			continue
+		}
+
+		ty, err := gocty.ImpliedType(field.Interface())
+		if err != nil {
+			return err
+		}
+		value, err := gocty.ToCtyValue(field.Interface(), ty)
+		if err != nil {
+			return err
+		}
+		body.SetAttributeValue(name, value)
+	}
+	return nil
+}
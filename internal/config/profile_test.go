@@ -0,0 +1,206 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+const profiles = `# Profiles of the fleet
+client "local" {
+  ipc = true
+}
+
+client "prod" {
+  url = "nats://prod:4222" # the hub
+}
+`
+
+const defaultProfiles = `default = "prod"
+
+` + profiles
+
+func TestEditProfiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		edit     func(c *ClientConfig) error
+		want     string
+		profiles []string
+		selected string
+		err      bool
+	}{
+		{
+			name: "add",
+			file: profiles,
+			edit: func(c *ClientConfig) error {
+				return c.AddProfile(&Client{Name: "staging", Url: "nats://staging:4222", Username: "ops", Timeout: 30 * time.Second})
+			},
+			want: profiles + `
+client "staging" {
+  url      = "nats://staging:4222"
+  username = "ops"
+  timeout  = duration("30s")
+}
+`,
+			profiles: []string{"local", "prod", "staging"},
+			selected: DefaultProfile,
+		},
+		{
+			name:     "add existing",
+			file:     profiles,
+			edit:     func(c *ClientConfig) error { return c.AddProfile(&Client{Name: "prod", Host: "prod"}) },
+			want:     profiles,
+			profiles: []string{"local", "prod"},
+			selected: DefaultProfile,
+			err:      true,
+		},
+		{
+			name:     "add to an empty file",
+			file:     "",
+			edit:     func(c *ClientConfig) error { return c.AddProfile(&Client{Name: "local", UseIPC: true}) },
+			want:     "client \"local\" {\n  ipc = true\n}\n",
+			profiles: []string{"local"},
+			selected: DefaultProfile,
+		},
+		{
+			name:     "set default",
+			file:     profiles,
+			edit:     func(c *ClientConfig) error { return c.SetDefault("prod") },
+			want:     defaultProfiles,
+			profiles: []string{"local", "prod"},
+			selected: "prod",
+		},
+		{
+			name:     "change default",
+			file:     defaultProfiles,
+			edit:     func(c *ClientConfig) error { return c.SetDefault("local") },
+			want:     "default = \"local\"\n\n" + profiles,
+			profiles: []string{"local", "prod"},
+			selected: "local",
+		},
+		{
+			name:     "default to an unknown profile",
+			file:     profiles,
+			edit:     func(c *ClientConfig) error { return c.SetDefault("staging") },
+			want:     profiles,
+			profiles: []string{"local", "prod"},
+			selected: DefaultProfile,
+			err:      true,
+		},
+		{
+			name: "remove",
+			file: defaultProfiles,
+			edit: func(c *ClientConfig) error { return c.RemoveProfile("local") },
+			// the comment above a block goes with it
+			want: `default = "prod"
+
+
+client "prod" {
+  url = "nats://prod:4222" # the hub
+}
+`,
+			profiles: []string{"prod"},
+			selected: "prod",
+		},
+		{
+			name: "remove the default",
+			file: defaultProfiles,
+			edit: func(c *ClientConfig) error { return c.RemoveProfile("prod") },
+			want: `
+# Profiles of the fleet
+client "local" {
+  ipc = true
+}
+
+`,
+			profiles: []string{"local"},
+			selected: DefaultProfile,
+		},
+		{
+			name:     "remove an unknown profile",
+			file:     profiles,
+			edit:     func(c *ClientConfig) error { return c.RemoveProfile("staging") },
+			want:     profiles,
+			profiles: []string{"local", "prod"},
+			selected: DefaultProfile,
+			err:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), CLIENT_CONFIG_FILE)
+			if err := os.WriteFile(file, []byte(tt.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			conf, err := LoadClientConfig(ClientFileOption(file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.edit(conf); (err != nil) != tt.err {
+				t.Fatalf("edit error = %v, want error %v", err, tt.err)
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("file =\n%s\nwant\n%s", data, tt.want)
+			}
+			if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0o600 {
+				t.Errorf("file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+			}
+
+			// the edited file and the edited config agree
+			reloaded, err := LoadClientConfig(ClientFileOption(file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range []*ClientConfig{conf, reloaded} {
+				var names []string
+				for _, client := range c.Clients {
+					names = append(names, client.Name)
+				}
+				if !slices.Equal(names, tt.profiles) || c.Selected() != tt.selected {
+					t.Errorf("profiles = %v, default %s, want %v, default %s", names, c.Selected(), tt.profiles, tt.selected)
+				}
+			}
+		})
+	}
+}
+
+func TestEditProfilesJSON(t *testing.T) {
+	file := filepath.Join(t.TempDir(), CLIENT_CONFIG_FILE+".json")
+	if err := os.WriteFile(file, []byte(`{"client": {"local": {"ipc": true}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := LoadClientConfig(ClientFileOption(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.AddProfile(&Client{Name: "prod", Host: "prod"}); err == nil {
+		t.Error("editing an HCL JSON file succeeded")
+	}
+}
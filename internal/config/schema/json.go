@@ -0,0 +1,147 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package schema
+
+import (
+	"strings"
+)
+
+// JSON_SCHEMA_DIALECT is the JSON Schema version of the generated schemas
+const JSON_SCHEMA_DIALECT = "https://json-schema.org/draft/2020-12/schema"
+
+// expression is a string template, which HCL JSON accepts for values of
+// any type, e.g. "${var.port}" or "${duration(\"90s\")}"
+var expression = map[string]any{
+	"type":        "string",
+	"pattern":     `\$\{`,
+	"description": `An HCL expression, e.g. "${var.port}" or "${duration(\"90s\")}".`,
+}
+
+// JSONSchema returns a JSON Schema of the file in the HCL JSON syntax,
+// as read from *.hcl.json files. Block bodies are defined once under
+// $defs by their path.
+func (f *File) JSONSchema() map[string]any {
+	defs := map[string]any{"expression": expression}
+	schema := f.Body.body(defs, "")
+	schema["$schema"] = JSON_SCHEMA_DIALECT
+	schema["title"] = f.Title + " (" + f.Path + ")"
+	schema["$defs"] = defs
+	return schema
+}
+
+// body returns the schema of a block body, an object of attributes and
+// nested blocks. The "//" property is the comment of HCL JSON.
+func (b *Block) body(defs map[string]any, path string) map[string]any {
+	props := map[string]any{"//": map[string]any{"description": "Comment."}}
+	required := []string{}
+	for _, attr := range b.Attributes {
+		props[attr.Name] = attr.schema()
+		if attr.Required {
+			required = append(required, attr.Name)
+		}
+	}
+	for _, block := range b.Blocks {
+		props[block.Name] = block.schema(defs, join(path, block.Name))
+	}
+
+	schema := map[string]any{"type": "object", "properties": props, "additionalProperties": b.Open}
+	if b.Doc != "" {
+		schema["description"] = b.Doc
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// schema returns the schema of the value of a block type: its body,
+// nested in an object by each of its labels. Repeated blocks and labels
+// may also be given as arrays of those.
+func (b *Block) schema(defs map[string]any, path string) map[string]any {
+	value := b.define(defs, path)
+	for i := len(b.Labels) - 1; i >= 0; i-- {
+		byLabel := map[string]any{
+			"type":                 "object",
+			"description":          "Blocks by " + b.Labels[i] + ".",
+			"additionalProperties": value,
+		}
+		if i == 0 && len(b.Variants) > 0 {
+			props := map[string]any{}
+			for _, name := range b.variants() {
+				props[name] = b.Variants[name].define(defs, path+"."+name)
+			}
+			byLabel["properties"] = props
+		}
+		value = oneOrMany(byLabel)
+	}
+	return value
+}
+
+// define adds the body of a block to defs, returning a reference to it
+// or to an array of it for repeated blocks
+func (b *Block) define(defs map[string]any, path string) map[string]any {
+	defs[path] = b.body(defs, path)
+	ref := map[string]any{"$ref": "#/$defs/" + path}
+	if b.Repeated {
+		return oneOrMany(ref)
+	}
+	return ref
+}
+
+// oneOrMany accepts an object or an array of objects
+func oneOrMany(object map[string]any) map[string]any {
+	return map[string]any{
+		"anyOf": []any{object, map[string]any{"type": "array", "items": object}},
+	}
+}
+
+// schema returns the schema of an attribute value, or an expression
+func (a *Attribute) schema() map[string]any {
+	value := typeSchema(a.Type)
+	if len(a.Enum) > 0 {
+		value["enum"] = a.Enum
+	}
+	if a.Type != "any" && (a.Type != "string" || len(a.Enum) > 0) {
+		value = map[string]any{"anyOf": []any{value, map[string]any{"$ref": "#/$defs/expression"}}}
+	}
+	if a.Doc != "" {
+		value["description"] = a.Doc
+	}
+	return value
+}
+
+// typeSchema returns the schema of an HCL type. Durations are numbers of
+// nanoseconds, usually written with duration().
+func typeSchema(typ string) map[string]any {
+	switch {
+	case typ == "string":
+		return map[string]any{"type": "string"}
+	case typ == "bool":
+		return map[string]any{"type": "boolean"}
+	case typ == "number":
+		return map[string]any{"type": "number"}
+	case typ == "duration":
+		return map[string]any{"type": "integer"}
+	case strings.HasPrefix(typ, "list("):
+		return map[string]any{"type": "array", "items": typeSchema(typ[5 : len(typ)-1])}
+	case strings.HasPrefix(typ, "map("):
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(typ[4 : len(typ)-1])}
+	}
+	return map[string]any{}
+}
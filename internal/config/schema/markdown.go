@@ -0,0 +1,115 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package schema
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteMarkdown writes the attribute reference of the file
+func (f *File) WriteMarkdown(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "# %s\n\n`%s`\n", f.Title, f.Path); err != nil {
+		return err
+	}
+	if err := f.Body.writeBody(w); err != nil {
+		return err
+	}
+	for _, block := range f.Body.Blocks {
+		if err := block.writeMarkdown(w, 2); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMarkdown writes a block under a heading of the level, followed by
+// its variants and nested blocks one level down
+func (b *Block) writeMarkdown(w io.Writer, level int) error {
+	if _, err := fmt.Fprintf(w, "\n%s `%s`\n", strings.Repeat("#", level), b.header()); err != nil {
+		return err
+	}
+	if b.Doc != "" {
+		if _, err := fmt.Fprintf(w, "\n%s\n", b.Doc); err != nil {
+			return err
+		}
+	}
+	if b.Repeated {
+		if _, err := fmt.Fprintln(w, "\nMay be given more than once."); err != nil {
+			return err
+		}
+	}
+	if err := b.writeBody(w); err != nil {
+		return err
+	}
+
+	for _, name := range b.variants() {
+		variant := *b.Variants[name]
+		variant.Labels = []string{name}
+		variant.Attributes = nil
+		variant.Repeated = false
+		if err := variant.writeMarkdown(w, level+1); err != nil {
+			return err
+		}
+	}
+	for _, block := range b.Blocks {
+		if err := block.writeMarkdown(w, level+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// header is the block type followed by its labels, e.g. program "name"
+func (b *Block) header() string {
+	header := b.Name
+	for _, label := range b.Labels {
+		header += fmt.Sprintf(" %q", label)
+	}
+	return header
+}
+
+// writeBody writes the attribute table of a block
+func (b *Block) writeBody(w io.Writer) (err error) {
+	if b.Open {
+		_, err = fmt.Fprintln(w, "\nTakes attributes of any name and type.")
+	}
+	if len(b.Attributes) == 0 || err != nil {
+		return err
+	}
+
+	rows := []string{"", "| Attribute | Type | Required | Description |", "|---|---|---|---|"}
+	for _, attr := range b.Attributes {
+		required := "no"
+		if attr.Required {
+			required = "yes"
+		}
+		doc := attr.Doc
+		if len(attr.Enum) > 0 {
+			doc += " One of `" + strings.Join(attr.Enum, "`, `") + "`."
+		}
+		if attr.Type == "duration" {
+			doc += " Written with `duration(\"90s\")` or `days(30)`."
+		}
+		rows = append(rows, fmt.Sprintf("| `%s` | %s | %s | %s |", attr.Name, attr.Type, required, strings.TrimSpace(doc)))
+	}
+	_, err = fmt.Fprintln(w, strings.Join(rows, "\n"))
+	return err
+}
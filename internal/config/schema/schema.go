@@ -0,0 +1,242 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package schema describes the agent, client and program configuration
+// files, generated from the hcl tags of the config structs, as JSON
+// Schema for the HCL JSON syntax and as a markdown attribute reference
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rangertaha/hxe/internal/config"
+	"github.com/rangertaha/hxe/internal/services/program"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Attribute is an attribute of a block body. Type is the HCL type:
+// string, bool, number, duration, list(T), map(T) or any.
+type Attribute struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required,omitempty"`
+	Doc      string   `json:"doc,omitempty"`
+	Enum     []string `json:"enum,omitempty"`
+}
+
+// Block is a block type and the attributes and blocks of its body
+type Block struct {
+	Name       string       `json:"name"`
+	Labels     []string     `json:"labels,omitempty"`
+	Doc        string       `json:"doc,omitempty"`
+	Repeated   bool         `json:"repeated,omitempty"`
+	Attributes []*Attribute `json:"attributes,omitempty"`
+	Blocks     []*Block     `json:"blocks,omitempty"`
+
+	// Variants are the bodies of blocks whose label selects more
+	// attributes and blocks, such as the service blocks
+	Variants map[string]*Block `json:"variants,omitempty"`
+
+	// Open bodies take attributes of any name, such as locals
+	Open bool `json:"open,omitempty"`
+}
+
+// File is a kind of configuration file
+type File struct {
+	Kind  string
+	Title string
+	Path  string
+	Body  *Block
+}
+
+// Kinds are the kinds of configuration files
+var Kinds = []string{"agent", "client", "program"}
+
+// Get returns the schema of a kind of configuration file
+func Get(kind string) (*File, error) {
+	switch kind {
+	case "agent":
+		return Agent(), nil
+	case "client":
+		return Client(), nil
+	case "program":
+		return Program(), nil
+	}
+	return nil, fmt.Errorf("unknown configuration %q, expected %s", kind, strings.Join(Kinds, ", "))
+}
+
+// Agent describes agent.hcl
+func Agent() *File {
+	root := reflectBody("agent", "", reflect.TypeOf(config.AgentConfig{}))
+	service := root.Block("service")
+	programs := reflectBody("service", "service.programs", reflect.TypeOf(program.Service{}))
+	service.Variants = map[string]*Block{
+		"programs": {
+			Name:       service.Name,
+			Labels:     service.Labels,
+			Doc:        Docs["service.programs"],
+			Attributes: service.Attributes,
+			Blocks:     programs.Blocks,
+		},
+	}
+	return &File{Kind: "agent", Title: "Agent configuration", Path: config.AGENT_CONFIG_FILE, Body: root}
+}
+
+// Client describes client.hcl
+func Client() *File {
+	root := reflectBody("client", "", reflect.TypeOf(config.ClientConfig{}))
+	return &File{Kind: "client", Title: "Client configuration", Path: config.CLIENT_CONFIG_FILE, Body: root}
+}
+
+// Program describes the files of the programs directory
+func Program() *File {
+	extends := attribute("program.extends", "string", false)
+	prog := reflectBody("program", "program", reflect.TypeOf(models.Program{}))
+	prog.Repeated = true
+	prog.Attributes = append([]*Attribute{extends}, prog.Attributes...)
+
+	template := reflectBody("template", "program", reflect.TypeOf(models.Program{}))
+	template.Doc = Docs["template"]
+	template.Repeated = true
+	template.Attributes = append([]*Attribute{extends}, template.Attributes...)
+
+	root := &Block{
+		Name: "program",
+		Blocks: []*Block{
+			prog,
+			template,
+			{
+				Name:     "variable",
+				Labels:   []string{"name"},
+				Doc:      Docs["variable"],
+				Repeated: true,
+				Attributes: []*Attribute{
+					attribute("variable.default", "any", false),
+					attribute("variable.description", "string", false),
+				},
+			},
+			{Name: "locals", Doc: Docs["locals"], Repeated: true, Open: true},
+		},
+	}
+	return &File{Kind: "program", Title: "Program files", Path: "programs/*.hcl", Body: root}
+}
+
+// Block returns the nested block of a type
+func (b *Block) Block(name string) *Block {
+	for _, block := range b.Blocks {
+		if block.Name == name {
+			return block
+		}
+	}
+	return nil
+}
+
+// reflectBody builds the block name from the hcl tags of a struct. Docs
+// and enums are looked up by path, the dotted names of the enclosing
+// blocks and the attribute.
+func reflectBody(name, path string, t reflect.Type) *Block {
+	block := &Block{Name: name, Doc: Docs[path]}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("hcl")
+		if !ok {
+			continue
+		}
+		// gohcl reads attributes without a name under the empty name,
+		// which no file can set
+		attr, kind, _ := strings.Cut(tag, ",")
+		if attr == "" {
+			continue
+		}
+
+		key := join(path, attr)
+		switch kind {
+		case "label":
+			block.Labels = append(block.Labels, attr)
+		case "remain":
+			block.Open = true
+		case "block":
+			typ, repeated := field.Type, false
+			if typ.Kind() == reflect.Slice {
+				typ, repeated = typ.Elem(), true
+			}
+			if typ.Kind() == reflect.Pointer {
+				typ = typ.Elem()
+			}
+			child := reflectBody(attr, key, typ)
+			child.Repeated = repeated
+			block.Blocks = append(block.Blocks, child)
+		default:
+			block.Attributes = append(block.Attributes, attribute(key, typeName(field.Type), kind != "optional"))
+		}
+	}
+	return block
+}
+
+// attribute returns the attribute at path with its doc and enum
+func attribute(path, typ string, required bool) *Attribute {
+	name := path[strings.LastIndex(path, ".")+1:]
+	return &Attribute{Name: name, Type: typ, Required: required, Doc: Docs[path], Enum: Enums[path]}
+}
+
+// typeName returns the HCL type of a Go type
+func typeName(t reflect.Type) string {
+	if t == durationType {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeName(t.Elem())
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice:
+		return "list(" + typeName(t.Elem()) + ")"
+	case reflect.Map:
+		return "map(" + typeName(t.Elem()) + ")"
+	}
+	return "any"
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// variants returns the labels of the variants of a block, sorted
+func (b *Block) variants() []string {
+	names := make([]string, 0, len(b.Variants))
+	for name := range b.Variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,191 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// lookup returns the value at a JSON pointer of the schema as JSON
+func lookup(t *testing.T, schema map[string]any, pointer string) string {
+	t.Helper()
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range strings.Split(pointer, "/")[1:] {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[token]
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i >= len(v) {
+				return ""
+			}
+			value = v[i]
+		default:
+			return ""
+		}
+	}
+	if value == nil {
+		return ""
+	}
+	data, _ = json.Marshal(value)
+	return string(data)
+}
+
+func TestGet(t *testing.T) {
+	for _, kind := range Kinds {
+		if file, err := Get(kind); err != nil || file.Kind != kind {
+			t.Errorf("Get(%s) = %v, %v", kind, file, err)
+		}
+	}
+	if _, err := Get("server"); err == nil {
+		t.Error("Get of an unknown kind succeeded")
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	tests := []struct {
+		kind    string
+		pointer string
+		want    string
+	}{
+		{"program", "/$schema", `"https://json-schema.org/draft/2020-12/schema"`},
+		{"program", "/title", `"Program files (programs/*.hcl)"`},
+		{"program", "/properties/program/anyOf/0/additionalProperties/anyOf/0/$ref", `"#/$defs/program"`},
+		{"program", "/properties/program/anyOf/1/type", `"array"`},
+		{"program", "/$defs/program/additionalProperties", `false`},
+		{"program", "/$defs/program/properties/exec/type", `"string"`},
+		{"program", "/$defs/program/properties/restart/anyOf/0/enum", `["no","always","on-failure"]`},
+		{"program", "/$defs/program/properties/deadline/anyOf/0/type", `"integer"`},
+		{"program", "/$defs/program/properties/deadline/anyOf/1/$ref", `"#/$defs/expression"`},
+		{"program", "/$defs/program/properties/args/anyOf/0/items/type", `"string"`},
+		{"program", "/$defs/program/properties/labels/anyOf/0/additionalProperties/type", `"string"`},
+		{"program", "/$defs/program/properties/extends/type", `"string"`},
+		{"program", "/$defs/program/properties/~1~1/description", `"Comment."`},
+		{"program", "/$defs/program.trigger/type", `"object"`},
+		{"program", "/$defs/locals/additionalProperties", `true`},
+		{"program", "/$defs/expression/pattern", `"\\$\\{"`},
+		{"agent", "/title", `"Agent configuration (agent.hcl)"`},
+		{"agent", "/properties/service/anyOf/0/properties/programs/$ref", `"#/$defs/service.programs"`},
+		{"agent", "/$defs/service.programs.crash/properties/retention/anyOf/0/type", `"integer"`},
+		{"agent", "/$defs/server/properties/port/anyOf/0/type", `"number"`},
+		{"client", "/properties/default/type", `"string"`},
+		{"client", "/$defs/client/properties/url/type", `"string"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind+tt.pointer, func(t *testing.T) {
+			file, err := Get(tt.kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := lookup(t, file.JSONSchema(), tt.pointer); got != tt.want {
+				t.Errorf("%s = %s, want %s", tt.pointer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONSchemaBody(t *testing.T) {
+	file := &File{Title: "Test", Path: "test.hcl", Body: &Block{
+		Attributes: []*Attribute{
+			{Name: "name", Type: "string", Required: true},
+			{Name: "mode", Type: "string", Enum: []string{"a", "b"}},
+			{Name: "value", Type: "any"},
+		},
+		Blocks: []*Block{
+			{Name: "once", Attributes: []*Attribute{{Name: "port", Type: "number", Required: true}}},
+			{Name: "extra", Open: true},
+		},
+	}}
+	schema := file.JSONSchema()
+
+	tests := []struct {
+		pointer string
+		want    string
+	}{
+		{"/required", `["name"]`},
+		{"/properties/name", `{"type":"string"}`},
+		{"/properties/mode/anyOf/0", `{"enum":["a","b"],"type":"string"}`},
+		{"/properties/value", `{}`},
+		{"/properties/once", `{"$ref":"#/$defs/once"}`},
+		{"/$defs/once/required", `["port"]`},
+		{"/$defs/extra/additionalProperties", `true`},
+		{"/$defs/extra/required", ``},
+	}
+	for _, tt := range tests {
+		if got := lookup(t, schema, tt.pointer); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.pointer, got, tt.want)
+		}
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	tests := []struct {
+		kind string
+		want []string
+	}{
+		{"program", []string{
+			"# Program files\n\n`programs/*.hcl`\n",
+			"\n## `program \"name\"`\n",
+			"\nMay be given more than once.\n",
+			"\n### `trigger \"name\"`\n",
+			"| `restart` | string | no | Whether the program is restarted after it exits. One of `no`, `always`, `on-failure`. |",
+			"| `deadline` | duration | no |",
+			"Written with `duration(\"90s\")` or `days(30)`.",
+			"\n## `locals`\n",
+			"\nTakes attributes of any name and type.\n",
+		}},
+		{"agent", []string{
+			"# Agent configuration\n\n`agent.hcl`\n",
+			"\n## `service \"id\"`\n",
+			"\n### `service \"programs\"`\n",
+			"\n#### `crash`\n",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			file, err := Get(tt.kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := file.WriteMarkdown(&buf); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("reference is missing %q", want)
+				}
+			}
+		})
+	}
+}
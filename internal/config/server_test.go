@@ -0,0 +1,62 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package config
+
+import "testing"
+
+func TestServerListen(t *testing.T) {
+	tests := []struct {
+		name     string
+		server   Server
+		host     string
+		port     int
+		loopback bool
+	}{
+		{name: "defaults", host: "127.0.0.1", port: 3143, loopback: true},
+		{name: "user", server: Server{Users: []*ServerUser{{Name: "admin", Password: "secret"}}}, host: "0.0.0.0", port: 3143},
+		{name: "token", server: Server{Token: "secret"}, host: "0.0.0.0", port: 3143},
+		{name: "auth dir", server: Server{AuthDir: "auth"}, host: "0.0.0.0", port: 3143},
+		{name: "host and port", server: Server{Host: "0.0.0.0", Port: 4222}, host: "0.0.0.0", port: 4222},
+		{name: "localhost", server: Server{Host: "localhost"}, host: "localhost", port: 3143, loopback: true},
+		{name: "random port", server: Server{Port: -1}, host: "127.0.0.1", port: -1, loopback: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port := tt.server.Listen()
+			if host != tt.host || port != tt.port {
+				t.Errorf("listen = %s:%d, want %s:%d", host, port, tt.host, tt.port)
+			}
+			if loopback := tt.server.Loopback(); loopback != tt.loopback {
+				t.Errorf("loopback = %v, want %v", loopback, tt.loopback)
+			}
+
+			if tt.server.AuthDir != "" {
+				// options need an operator written by hxe auth init
+				return
+			}
+			opts, _, err := tt.server.Options(func(path string) string { return path })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if opts.Host != tt.host || opts.Port != tt.port {
+				t.Errorf("options listen on %s:%d, want %s:%d", opts.Host, opts.Port, tt.host, tt.port)
+			}
+		})
+	}
+}
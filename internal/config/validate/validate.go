@@ -0,0 +1,479 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package validate checks agent, client and program configuration files
+// before they are deployed
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/rangertaha/hxe/internal/config"
+	"github.com/rangertaha/hxe/internal/services"
+	"github.com/rangertaha/hxe/internal/services/program/loader"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+
+	_ "github.com/rangertaha/hxe/internal/services/all"
+)
+
+// kinds tells agent, client and program files apart by their blocks
+var kinds = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "program", LabelNames: []string{"name"}},
+		{Type: "template", LabelNames: []string{"name"}},
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "locals"},
+		{Type: "client", LabelNames: []string{"name"}},
+		{Type: "server", LabelNames: []string{"name"}},
+		{Type: "service", LabelNames: []string{"id"}},
+	},
+}
+
+// Report is the outcome of validating configuration files
+type Report struct {
+	Diags hcl.Diagnostics
+
+	files  map[string]*hcl.File
+	dirs   map[string]bool
+	parser *hclparse.Parser
+}
+
+// Validate parses and checks the given files and programs directories.
+// Without paths it validates the agent and client configuration in the
+// user config directory and the programs directory of the agent.
+func Validate(paths ...string) *Report {
+	r := &Report{files: map[string]*hcl.File{}, dirs: map[string]bool{}, parser: hclparse.NewParser()}
+
+	if len(paths) == 0 {
+		paths = DefaultPaths()
+	}
+
+	programs := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			r.Diags = r.Diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid path",
+				Detail:   err.Error(),
+			})
+			continue
+		}
+		if info.IsDir() {
+			r.programsDir(path)
+			continue
+		}
+
+		file, diags := r.parse(path)
+		r.Diags = r.Diags.Extend(diags)
+		if diags.HasErrors() {
+			continue
+		}
+		content, _, _ := file.Body.PartialContent(kinds)
+		switch kind(path, content) {
+		case "program":
+			programs = append(programs, path)
+		case "client":
+			r.client(path, file, content)
+		default:
+			r.agent(path, file, content)
+		}
+	}
+	if len(programs) > 0 {
+		r.programs(loader.ParseFiles(filepath.Dir(programs[0]), programs...))
+	}
+	return r
+}
+
+// DefaultPaths are the agent and client config files in the user config
+// directory that exist
+func DefaultPaths() (paths []string) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+	for _, name := range []string{config.AGENT_CONFIG_FILE, config.CLIENT_CONFIG_FILE} {
+		path := filepath.Join(dir, config.CONFIG_DIR, name)
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func kind(path string, content *hcl.BodyContent) string {
+	for _, block := range content.Blocks {
+		switch block.Type {
+		case "program", "template", "variable", "locals":
+			return "program"
+		case "client":
+			return block.Type
+		}
+	}
+	if filepath.Base(path) == config.CLIENT_CONFIG_FILE {
+		return "client"
+	}
+	return "agent"
+}
+
+func (r *Report) parse(path string) (*hcl.File, hcl.Diagnostics) {
+	if strings.HasSuffix(path, ".json") {
+		return r.parser.ParseJSONFile(path)
+	}
+	return r.parser.ParseHCLFile(path)
+}
+
+// agent decodes an agent config file, checks its services are known and
+// validates the programs directory of the programs service
+func (r *Report) agent(path string, file *hcl.File, content *hcl.BodyContent) {
+	conf := &config.AgentConfig{}
+	diags := gohcl.DecodeBody(file.Body, config.FileContext(path), conf)
+	r.Diags = r.Diags.Extend(diags)
+
+	if !diags.HasErrors() {
+		resolve := func(name string) string {
+			if filepath.IsAbs(name) {
+				return name
+			}
+			return filepath.Join(filepath.Dir(path), name)
+		}
+		if _, _, err := conf.Server.Options(resolve); err != nil {
+			diag := &hcl.Diagnostic{Severity: hcl.DiagError, Summary: "Invalid server", Detail: err.Error()}
+			for _, block := range content.Blocks {
+				if block.Type == "server" {
+					diag.Subject = block.DefRange.Ptr()
+				}
+			}
+			r.Diags = r.Diags.Append(diag)
+		}
+		if _, err := conf.AgentID(); err != nil {
+			diag := &hcl.Diagnostic{Severity: hcl.DiagError, Summary: "Invalid agent id", Detail: err.Error()}
+			if attrs, _ := file.Body.JustAttributes(); attrs["id"] != nil {
+				diag.Subject = attrs["id"].Range.Ptr()
+			}
+			r.Diags = r.Diags.Append(diag)
+		}
+	}
+
+	for _, block := range content.Blocks {
+		if block.Type != "service" {
+			continue
+		}
+		if _, err := services.Get(block.Labels[0]); err != nil {
+			r.Diags = r.Diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unknown service",
+				Detail:   fmt.Sprintf("There is no service named %q.", block.Labels[0]),
+				Subject:  block.LabelRanges[0].Ptr(),
+			})
+		}
+	}
+
+	for _, svc := range conf.Services {
+		if svc.ID != "programs" || svc.Directory == "" {
+			continue
+		}
+		dir := svc.Directory
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(path), dir)
+		}
+		if _, err := os.Stat(dir); err != nil {
+			r.Diags = r.Diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Missing programs directory",
+				Detail:   fmt.Sprintf("The programs directory %s does not exist, no programs will be loaded.", dir),
+			})
+			continue
+		}
+		r.programsDir(dir)
+	}
+}
+
+// client decodes a client config file and checks profile names are unique
+// and the default profile is defined
+func (r *Report) client(path string, file *hcl.File, content *hcl.BodyContent) {
+	conf := &config.ClientConfig{}
+	r.Diags = r.Diags.Extend(gohcl.DecodeBody(file.Body, config.FileContext(path), conf))
+
+	defined := map[string]hcl.Range{}
+	for _, block := range content.Blocks {
+		if block.Type != "client" {
+			continue
+		}
+		name := block.Labels[0]
+		if first, ok := defined[name]; ok {
+			r.Diags = r.Diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate profile",
+				Detail:   fmt.Sprintf("Profile %q was already defined at %s.", name, first),
+				Subject:  block.DefRange.Ptr(),
+			})
+			continue
+		}
+		defined[name] = block.DefRange
+	}
+
+	if _, ok := defined[conf.Default]; conf.Default != "" && !ok {
+		attrs, _ := file.Body.JustAttributes()
+		diag := &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unknown default profile",
+			Detail:   fmt.Sprintf("The default profile %q is not defined.", conf.Default),
+		}
+		if attr, ok := attrs["default"]; ok {
+			diag.Subject = attr.Range.Ptr()
+		}
+		r.Diags = r.Diags.Append(diag)
+	}
+}
+
+func (r *Report) programsDir(dir string) {
+	abs, _ := filepath.Abs(dir)
+	if r.dirs[abs] {
+		return
+	}
+	r.dirs[abs] = true
+	r.programs(loader.Parse(dir))
+}
+
+// programs checks the programs parsed by the loader can be executed as
+// configured on this host
+func (r *Report) programs(result *loader.Result) {
+	for name, file := range result.Files() {
+		r.files[name] = file
+	}
+	r.Diags = r.Diags.Extend(result.Diags)
+
+	for _, prog := range result.Programs {
+		r.executable(result, prog)
+
+		if prog.User != "" {
+			if _, err := lookupUser(prog.User); err != nil {
+				r.Diags = r.Diags.Append(&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Unknown user",
+					Detail:   fmt.Sprintf("Program %q runs as user %q, which does not exist.", prog.Name, prog.User),
+					Subject:  result.Range(prog.Name, "user"),
+				})
+			}
+		}
+		if prog.Group != "" {
+			if _, err := lookupGroup(prog.Group); err != nil {
+				r.Diags = r.Diags.Append(&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Unknown group",
+					Detail:   fmt.Sprintf("Program %q runs as group %q, which does not exist.", prog.Name, prog.Group),
+					Subject:  result.Range(prog.Name, "group"),
+				})
+			}
+		}
+	}
+}
+
+// executable checks the binary a program runs exists. Exec lines the
+// shell has to interpret are left to the shell.
+func (r *Report) executable(result *loader.Result, prog *models.Program) {
+	attr, name := "exec", prog.Exec
+	switch {
+	case prog.Exec != "" && len(prog.Args) == 0:
+		if strings.ContainsAny(prog.Exec, ";&|<>()$`\n") {
+			return
+		}
+		name = strings.Fields(prog.Exec)[0]
+	case prog.Exec == "" && prog.Path != "":
+		attr, name = "path", prog.Path
+	case prog.Exec == "":
+		r.Diags = r.Diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Missing command",
+			Detail:   fmt.Sprintf("Program %q has neither exec nor path set.", prog.Name),
+			Subject:  result.Range(prog.Name, "exec"),
+		})
+		return
+	}
+
+	var err error
+	if strings.Contains(name, "/") {
+		if !filepath.IsAbs(name) && prog.Dir != "" {
+			name = filepath.Join(prog.Dir, name)
+		}
+		var info os.FileInfo
+		if info, err = os.Stat(name); err == nil && (info.IsDir() || info.Mode()&0111 == 0) {
+			err = fmt.Errorf("%s is not executable", name)
+		}
+	} else {
+		_, err = exec.LookPath(name)
+	}
+	if err != nil {
+		r.Diags = r.Diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Executable not found",
+			Detail:   fmt.Sprintf("Program %q runs %s: %s.", prog.Name, name, err),
+			Subject:  result.Range(prog.Name, attr),
+		})
+	}
+}
+
+func lookupUser(name string) (*user.User, error) {
+	if _, err := strconv.Atoi(name); err == nil {
+		return user.LookupId(name)
+	}
+	return user.Lookup(name)
+}
+
+func lookupGroup(name string) (*user.Group, error) {
+	if _, err := strconv.Atoi(name); err == nil {
+		return user.LookupGroupId(name)
+	}
+	return user.LookupGroup(name)
+}
+
+// Files returns the names of the files that were validated
+func (r *Report) Files() (names []string) {
+	for name := range r.sources() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sources are the parsed files by name
+func (r *Report) sources() map[string]*hcl.File {
+	files := map[string]*hcl.File{}
+	for name, file := range r.parser.Files() {
+		files[name] = file
+	}
+	for name, file := range r.files {
+		files[name] = file
+	}
+	return files
+}
+
+// Errors returns the number of errors and warnings
+func (r *Report) Errors() (errors, warnings int) {
+	for _, diag := range r.Diags {
+		if diag.Severity == hcl.DiagError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	return
+}
+
+// WriteText writes the diagnostics with the source lines they refer to,
+// followed by a summary
+func (r *Report) WriteText(w io.Writer, color bool) error {
+	if err := hcl.NewDiagnosticTextWriter(w, r.sources(), 78, color).WriteDiagnostics(r.Diags); err != nil {
+		return err
+	}
+
+	errors, warnings := r.Errors()
+	files := len(r.Files())
+	if errors == 0 && warnings == 0 {
+		_, err := fmt.Fprintf(w, "%d files are valid\n", files)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d errors, %d warnings in %d files\n", errors, warnings, files)
+	return err
+}
+
+// Diagnostic is a diagnostic as written by WriteJSON
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail,omitempty"`
+	Range    *Range `json:"range,omitempty"`
+	Snippet  string `json:"snippet,omitempty"`
+}
+
+// Range is a span of a source file, with 1-based lines and columns
+type Range struct {
+	Filename string `json:"filename"`
+	Start    Pos    `json:"start"`
+	End      Pos    `json:"end"`
+}
+
+type Pos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Byte   int `json:"byte"`
+}
+
+// WriteJSON writes the report as a JSON document for tooling
+func (r *Report) WriteJSON(w io.Writer) error {
+	errors, warnings := r.Errors()
+	out := struct {
+		Valid       bool          `json:"valid"`
+		Files       []string      `json:"files"`
+		Errors      int           `json:"errors"`
+		Warnings    int           `json:"warnings"`
+		Diagnostics []*Diagnostic `json:"diagnostics"`
+	}{
+		Valid:       errors == 0,
+		Files:       r.Files(),
+		Errors:      errors,
+		Warnings:    warnings,
+		Diagnostics: []*Diagnostic{},
+	}
+
+	files := r.sources()
+	for _, diag := range r.Diags {
+		d := &Diagnostic{Summary: diag.Summary, Detail: diag.Detail, Severity: "error"}
+		if diag.Severity == hcl.DiagWarning {
+			d.Severity = "warning"
+		}
+		if rng := diag.Subject; rng != nil {
+			d.Range = &Range{
+				Filename: rng.Filename,
+				Start:    Pos{rng.Start.Line, rng.Start.Column, rng.Start.Byte},
+				End:      Pos{rng.End.Line, rng.End.Column, rng.End.Byte},
+			}
+			if file, ok := files[rng.Filename]; ok {
+				d.Snippet = snippet(file.Bytes, rng)
+			}
+		}
+		out.Diagnostics = append(out.Diagnostics, d)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// snippet returns the source lines a range spans
+func snippet(src []byte, rng *hcl.Range) string {
+	lines := strings.Split(string(src), "\n")
+	if rng.Start.Line < 1 || rng.Start.Line > len(lines) {
+		return ""
+	}
+	end := min(max(rng.End.Line, rng.Start.Line), len(lines))
+	return strings.Join(lines[rng.Start.Line-1:end], "\n")
+}
@@ -0,0 +1,203 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidatePrograms(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		summary string
+		line    int
+	}{
+		{
+			name: "valid",
+			src: `program "web" {
+  exec                  = "true"
+  restart               = "always"
+  reload_signal         = "HUP"
+  no_restart_on_signals = ["TERM", "SIGKILL", "9"]
+
+  trigger "up" {
+    pattern = "listening on (\\d+)"
+    stream  = "stdout"
+    action  = "ready"
+  }
+}`,
+		},
+		{
+			name: "restart policy",
+			src: `program "web" {
+  exec    = "true"
+  restart = "unless-stopped"
+}`,
+			summary: "Invalid restart policy",
+			line:    3,
+		},
+		{
+			name: "reload signal",
+			src: `program "web" {
+  exec          = "true"
+  reload_signal = "RELOAD"
+}`,
+			summary: "Invalid signal",
+			line:    3,
+		},
+		{
+			name: "no restart signals",
+			src: `program "web" {
+  exec                  = "true"
+  no_restart_on_signals = ["TERM", "SIGNOPE"]
+}`,
+			summary: "Invalid signal",
+			line:    3,
+		},
+		{
+			name: "trigger pattern",
+			src: `program "web" {
+  exec = "true"
+
+  trigger "up" {
+    pattern = "listening on ("
+    action  = "ready"
+  }
+}`,
+			summary: "Invalid trigger pattern",
+			line:    5,
+		},
+		{
+			name: "trigger action",
+			src: `program "web" {
+  exec = "true"
+
+  trigger "up" {
+    pattern = "listening"
+    action  = "reboot"
+  }
+}`,
+			summary: "Invalid trigger action",
+			line:    6,
+		},
+		{
+			name: "trigger stream",
+			src: `program "web" {
+  exec = "true"
+
+  trigger "up" {
+    pattern = "listening"
+    stream  = "stdin"
+    action  = "ready"
+  }
+}`,
+			summary: "Invalid trigger stream",
+			line:    6,
+		},
+		{
+			name: "trigger signal",
+			src: `program "web" {
+  exec = "true"
+
+  trigger "oom" {
+    pattern = "out of memory"
+    action  = "signal"
+  }
+}`,
+			summary: "Invalid trigger signal",
+			line:    4,
+		},
+		{
+			name: "executable",
+			src: `program "web" {
+  exec = "/nonexistent/web --port 80"
+}`,
+			summary: "Executable not found",
+			line:    2,
+		},
+		{
+			name: "user",
+			src: `program "web" {
+  exec = "true"
+  user = "hxe-no-such-user"
+}`,
+			summary: "Unknown user",
+			line:    3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "web.hcl")
+			if err := os.WriteFile(path, []byte(tt.src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			r := Validate(path)
+			if tt.summary == "" {
+				if len(r.Diags) > 0 {
+					t.Fatalf("diagnostics = %s", r.Diags.Error())
+				}
+				return
+			}
+			if len(r.Diags) != 1 {
+				t.Fatalf("diagnostics = %v, want %s", r.Diags, tt.summary)
+			}
+			diag := r.Diags[0]
+			if diag.Summary != tt.summary {
+				t.Errorf("summary = %q, want %q", diag.Summary, tt.summary)
+			}
+			if diag.Subject == nil || diag.Subject.Filename != path || diag.Subject.Start.Line != tt.line {
+				t.Errorf("range = %v, want %s:%d", diag.Subject, path, tt.line)
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web.hcl")
+	src := "program \"web\" {\n  exec    = \"true\"\n  restart = \"never\"\n}\n"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Validate(path).WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Valid       bool
+		Errors      int
+		Diagnostics []*Diagnostic
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Valid || out.Errors != 1 || len(out.Diagnostics) != 1 {
+		t.Fatalf("report = %s", buf.String())
+	}
+	d := out.Diagnostics[0]
+	if d.Range == nil || d.Range.Start.Line != 3 || d.Snippet != `  restart = "never"` {
+		t.Errorf("diagnostic = %+v, want line 3 with its snippet", d)
+	}
+}
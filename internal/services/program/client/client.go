@@ -19,60 +19,544 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/rangertaha/hxe/internal/auth"
 	"github.com/rangertaha/hxe/internal/log"
+	"github.com/rangertaha/hxe/internal/services"
 	"github.com/rangertaha/hxe/internal/services/program/models"
+	"github.com/rangertaha/hxe/internal/services/program/supervisor"
 	"github.com/rs/zerolog"
 )
 
+const (
+	// RequestTimeout is how long a request waits for the agent when the
+	// client has no timeout of its own
+	RequestTimeout = 5 * time.Second
+
+	// StopRequestTimeout is the least a request that stops programs waits,
+	// as the agent gives each program supervisor.StopTimeout to exit
+	// before killing it
+	StopRequestTimeout = supervisor.StopTimeout + 5*time.Second
+)
+
 // Client is a client used internally to manage services
 type Client struct {
-	nc  *nats.Conn
-	log zerolog.Logger
+	nc      *nats.Conn
+	agent   string
+	timeout time.Duration
+	log     zerolog.Logger
 }
 
 type Request struct {
-	Program *models.Program `json:"service"`
+	Program    *models.Program `json:"service"`
+	Selector   string          `json:"selector,omitempty"`
+	Limit      int             `json:"limit,omitempty"`
+	Rollout    *Rollout        `json:"rollout,omitempty"`
+	Signal     string          `json:"signal,omitempty"`
+	Group      bool            `json:"group,omitempty"`
+	Command    []string        `json:"command,omitempty"`
+	Caller     string          `json:"-"`
+	Output     string          `json:"output,omitempty"`
+	RunID      uint            `json:"run,omitempty"`
+	MaxRuntime time.Duration   `json:"maxRuntime,omitempty"`
+	Plan       *Plan           `json:"plan,omitempty"`
+}
+
+// Output is a line written by an ad-hoc run, or its finished run
+type Output struct {
+	Stream string      `json:"stream,omitempty"`
+	Line   string      `json:"line,omitempty"`
+	Run    *models.Run `json:"run,omitempty"`
+}
+
+// Result is the outcome of a bulk operation for a single program
+type Result struct {
+	ProgramID uint   `json:"program"`
+	Name      string `json:"name"`
+	Ok        bool   `json:"ok"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Rollout configures a rolling restart of the selected programs
+type Rollout struct {
+	MaxUnavailable int           `json:"maxUnavailable"`
+	WaitReady      bool          `json:"waitReady"`
+	Timeout        time.Duration `json:"timeout"`
 }
 
 type Response struct {
-	Status   error             `json:"status"`
-	Programs []*models.Program `json:"programs"`
+	// Agent is the ID of the agent that answered, set by fleet commands
+	Agent string `json:"agent,omitempty"`
+
+	Status    error                `json:"status"`
+	Error     string               `json:"error,omitempty"`
+	Programs  []*models.Program    `json:"programs"`
+	Results   []*Result            `json:"results,omitempty"`
+	Statuses  []*models.Status     `json:"statuses,omitempty"`
+	History   []*models.Transition `json:"history,omitempty"`
+	Runs      []*models.Run        `json:"runs,omitempty"`
+	Processes []*models.ProcInfo   `json:"processes,omitempty"`
+	Crashes   []*models.Crash      `json:"crashes,omitempty"`
+	Plan      *Plan                `json:"plan,omitempty"`
 }
 
 func New(nc *nats.Conn) *Client {
 	return &Client{nc: nc, log: log.With().Logger()}
 }
 
-// List all services
-func (c *Client) List() (resp *Response, err error) {
+// Agent returns a client of the programs of the agent with the given ID
+func (c *Client) Agent(id string) *Client {
+	return &Client{nc: c.nc, agent: id, timeout: c.timeout, log: c.log}
+}
+
+// WithTimeout returns a client whose requests wait up to timeout for the
+// agent to answer, or longer when an operation takes longer by itself
+func (c *Client) WithTimeout(timeout time.Duration) *Client {
+	return &Client{nc: c.nc, agent: c.agent, timeout: timeout, log: c.log}
+}
+
+// List all services, or those matching a selector
+func (c *Client) List(selector string) (resp *Response, err error) {
+	return c.request("program.list", &Request{Selector: selector})
+}
+
+// Create a program
+func (c *Client) Create(prog *models.Program) (resp *Response, err error) {
+	return c.request("program.create", &Request{Program: prog})
+}
+
+// Start the targeted programs
+func (c *Client) Start(req *Request) (resp *Response, err error) {
+	return c.request("program.start", req)
+}
+
+// Stop the targeted programs
+func (c *Client) Stop(req *Request) (resp *Response, err error) {
+	return c.requestTimeout("program.stop", req, c.stopTimeout())
+}
+
+// Restart the targeted programs
+func (c *Client) Restart(req *Request) (resp *Response, err error) {
+	return c.requestTimeout("program.restart", req, c.stopTimeout())
+}
+
+// Signal sends a signal to the targeted programs, or to their whole
+// process groups when req.Group is set
+func (c *Client) Signal(req *Request) (resp *Response, err error) {
+	return c.request("program.signal", req)
+}
+
+// Reload sends the targeted programs their reload signal
+func (c *Client) Reload(req *Request) (resp *Response, err error) {
+	return c.request("program.reload", req)
+}
+
+// Enable the targeted programs to start automatically
+func (c *Client) Enable(req *Request) (resp *Response, err error) {
+	return c.request("program.enable", req)
+}
+
+// Disable the targeted programs from starting automatically
+func (c *Client) Disable(req *Request) (resp *Response, err error) {
+	return c.request("program.disable", req)
+}
+
+// Delete the targeted programs
+func (c *Client) Delete(req *Request) (resp *Response, err error) {
+	return c.requestTimeout("program.delete", req, c.stopTimeout())
+}
+
+// RollingRestart restarts the programs matching a label selector in
+// batches, waiting for the whole rollout to finish
+func (c *Client) RollingRestart(selector string, rollout *Rollout) (resp *Response, err error) {
+	req := &Request{Selector: selector, Rollout: rollout}
+	return c.requestTimeout("program.restart", req, rollout.Timeout+5*time.Second)
+}
+
+// Status of a program by name or ID
+func (c *Client) Status(ref string) (resp *Response, err error) {
+	return c.request("program.status", &Request{Program: Ref(ref)})
+}
+
+// History returns the state transitions of a program, newest first
+func (c *Client) History(ref string, limit int) (resp *Response, err error) {
+	return c.request("program.history", &Request{Program: Ref(ref), Limit: limit})
+}
+
+// Runs returns the recorded executions of a program, newest first, or the
+// ad-hoc runs made outside of any program when ref is empty
+func (c *Client) Runs(ref string, limit int) (resp *Response, err error) {
+	req := &Request{Limit: limit}
+	if ref != "" {
+		req.Program = Ref(ref)
+	}
+	return c.request("program.runs", req)
+}
+
+// Crashes returns the crash reports of a program, newest first
+func (c *Client) Crashes(ref string, limit int) (resp *Response, err error) {
+	return c.request("program.crashes", &Request{Program: Ref(ref), Limit: limit})
+}
+
+// Ps returns the process tree of a program by name or ID
+func (c *Client) Ps(ref string) (resp *Response, err error) {
+	return c.request("program.ps", &Request{Program: Ref(ref)})
+}
+
+// Run executes an ad-hoc command once on the agent, in the context of
+// req.Program when it is set. Every line of output is passed to output,
+// and the finished run is returned once the command has exited. When ctx
+// is cancelled first, the command is cancelled on the agent too.
+func (c *Client) Run(ctx context.Context, req *Request, output func(*Output)) (run *models.Run, err error) {
+	req.Output = c.nc.NewRespInbox()
+	sub, err := c.nc.SubscribeSync(req.Output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to run output: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	resp, err := c.request("program.run", req)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil && ctx.Err() != nil && len(resp.Runs) > 0 {
+			if cancelErr := c.Cancel(resp.Runs[0].ID); cancelErr != nil {
+				return nil, fmt.Errorf("failed to cancel run %d: %w", resp.Runs[0].ID, cancelErr)
+			}
+			return nil, fmt.Errorf("run %d cancelled: %w", resp.Runs[0].ID, ctx.Err())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read run output: %w", err)
+		}
+
+		out := &Output{}
+		if err = json.Unmarshal(msg.Data, out); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal run output: %w", err)
+		}
+		if out.Run != nil {
+			return out.Run, nil
+		}
+		output(out)
+	}
+}
+
+// Cancel stops an ad-hoc run started by Run that is still going
+func (c *Client) Cancel(runID uint) error {
+	_, err := c.request("program.cancel", &Request{RunID: runID})
+	return err
+}
+
+// request sends a request to a program endpoint and decodes the response
+func (c *Client) request(subject string, req *Request) (resp *Response, err error) {
+	if c.timeout > 0 {
+		return c.requestTimeout(subject, req, c.timeout)
+	}
+	return c.requestTimeout(subject, req, RequestTimeout)
+}
+
+// stopTimeout returns how long a request that stops programs waits
+func (c *Client) stopTimeout() time.Duration {
+	return max(c.timeout, StopRequestTimeout)
+}
+
+func (c *Client) requestTimeout(subject string, req *Request, timeout time.Duration) (resp *Response, err error) {
+	subject = services.Subject(c.agent, subject)
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s request: %w", subject, err)
+	}
+
+	header, err := auth.Sign(&c.nc.Opts, subject, data)
+	if err != nil {
+		return nil, err
+	}
 
-	msg, err := c.nc.Request("program.list", []byte("{}"), 5*time.Second)
+	msg, err := c.nc.RequestMsg(&nats.Msg{Subject: subject, Data: data, Header: header}, timeout)
 	if err != nil {
-		errMsg := fmt.Sprintf("%s: failed to request program.list", c.nc.ConnectedUrl())
+		errMsg := fmt.Sprintf("%s: failed to request %s", c.nc.ConnectedUrl(), subject)
 		c.log.Error().Err(err).Str("url", c.nc.ConnectedUrl()).Msg(errMsg)
 		return nil, errors.New(errMsg)
 	}
-	c.log.Debug().Msgf("programs.list response: %s", string(msg.Data))
+	c.log.Debug().Msgf("%s response: %s", subject, string(msg.Data))
 
 	resp = &Response{}
-	err = json.Unmarshal(msg.Data, resp)
-	if err != nil {
-		errMsg := "failed to unmarshal programs.program.list response"
+	if err = json.Unmarshal(msg.Data, resp); err != nil {
+		errMsg := fmt.Sprintf("failed to unmarshal %s response", subject)
 		c.log.Error().Err(err).Msg(errMsg)
 		return nil, errors.New(errMsg)
 	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
 	return resp, nil
 }
 
+// Target builds a request for the programs matching a selector, or for
+// the single program named by ref
+func Target(ref, selector string) *Request {
+	if selector != "" {
+		return &Request{Selector: selector}
+	}
+	return &Request{Program: Ref(ref)}
+}
+
+// Ref builds a program reference from a name or numeric ID
+func Ref(ref string) *models.Program {
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		return &models.Program{ID: uint(id)}
+	}
+	return &models.Program{Name: ref}
+}
+
+// Fleet is the responses of several agents, printed as single tables
+// with an AGENT column
+type Fleet []*Response
+
+// agents reports whether the responses came from named agents
+func (f Fleet) agents() bool {
+	for _, res := range f {
+		if res.Agent != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// row prefixes a table row with the agent when the responses came from
+// named agents
+func (f Fleet) row(res *Response, format string, args ...any) string {
+	if !f.agents() {
+		return fmt.Sprintf(format, args...)
+	}
+	return res.Agent + "\t" + fmt.Sprintf(format, args...)
+}
+
+// header returns the header of a table, with an AGENT column when the
+// responses came from named agents
+func (f Fleet) header(header string) string {
+	if !f.agents() {
+		return header
+	}
+	return "AGENT\t" + header
+}
+
 // Print formats and prints the list of programs in table format
 func (s *Response) Print() {
-	for _, program := range s.Programs {
-		fmt.Printf("Program: %s\n", program.Name)
+	Fleet{s}.Print()
+}
+
+// Print prints the programs of every agent in table format
+func (f Fleet) Print() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, f.header("ID\tNAME\tSTATE\tENABLED\tSOURCE"))
+	for _, res := range f {
+		for _, program := range res.Programs {
+			source := program.Source
+			if program.Orphaned {
+				source += " (orphaned)"
+			}
+			fmt.Fprintln(w, f.row(res, "%d\t%s\t%s\t%t\t%s", program.ID, program.Name, program.State, program.IsEnabled(), source))
+		}
+	}
+	w.Flush()
+}
+
+// PrintStatus prints the status of programs in table format
+func (s *Response) PrintStatus() {
+	Fleet{s}.PrintStatus()
+}
+
+// PrintStatus prints the status of programs of every agent in table format
+func (f Fleet) PrintStatus() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, f.header("ID\tNAME\tSTATE\tPID\tUPTIME\tPROGRESS\tMESSAGE"))
+	for _, res := range f {
+		for _, status := range res.Statuses {
+			fmt.Fprintln(w, f.row(res, "%d\t%s\t%s\t%d\t%s\t%d%%\t%s", status.ProgramID, status.Name,
+				status.State, status.Pid, status.Uptime.Round(time.Second), status.Progress, status.Message))
+		}
+	}
+	w.Flush()
+}
+
+// PrintResults prints the outcome of a bulk operation in table format
+func (s *Response) PrintResults() {
+	Fleet{s}.PrintResults()
+}
+
+// PrintResults prints the outcome of a bulk operation on every agent in
+// table format
+func (f Fleet) PrintResults() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, f.header("ID\tNAME\tSTATE\tRESULT"))
+	for _, res := range f {
+		states := map[uint]models.State{}
+		for _, status := range res.Statuses {
+			if status != nil {
+				states[status.ProgramID] = status.State
+			}
+		}
+		for _, r := range res.Results {
+			result := "ok"
+			if !r.Ok {
+				result = "failed: " + r.Error
+			}
+			fmt.Fprintln(w, f.row(res, "%d\t%s\t%s\t%s", r.ProgramID, r.Name, states[r.ProgramID], result))
+		}
+	}
+	w.Flush()
+}
+
+// PrintHistory prints the state transitions of a program in table format
+func (s *Response) PrintHistory() {
+	Fleet{s}.PrintHistory()
+}
+
+// PrintHistory prints the state transitions of a program on every agent
+// in table format
+func (f Fleet) PrintHistory() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, f.header("TIME\tFROM\tTO\tREASON"))
+	for _, res := range f {
+		for _, t := range res.History {
+			fmt.Fprintln(w, f.row(res, "%s\t%s\t%s\t%s", t.Time().Format(time.RFC3339), t.From, t.To, t.Reason))
+		}
+	}
+	w.Flush()
+}
+
+// PrintRuns prints the executions of a program in table format
+func (s *Response) PrintRuns() {
+	Fleet{s}.PrintRuns()
+}
+
+// PrintRuns prints the executions of a program on every agent in table
+// format
+func (f Fleet) PrintRuns() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, f.header("STARTED\tDURATION\tPID\tEXIT\tSIGNAL\tRESULT\tRESTART\tCALLER\tCOMMAND\tREASON"))
+	for _, res := range f {
+		for _, r := range res.Runs {
+			fmt.Fprintln(w, f.row(res, "%s\t%s\t%d\t%d\t%s\t%s\t%t\t%s\t%s\t%s", time.UnixMilli(r.Started).Format(time.RFC3339),
+				r.Duration().Round(time.Millisecond), r.Pid, r.ExitCode, r.Signal, r.Result, r.Restart, r.Caller, r.Command, r.Reason))
+		}
+	}
+	w.Flush()
+}
+
+// PrintProcesses prints the process tree of a program in table format
+func (s *Response) PrintProcesses() {
+	Fleet{s}.PrintProcesses()
+}
+
+// PrintProcesses prints the process tree of a program on every agent in
+// table format
+func (f Fleet) PrintProcesses() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, f.header("PID\tPPID\tUSER\tSTATE\tCPU\tRSS\tCOMMAND"))
+	for _, res := range f {
+		for _, p := range res.Processes {
+			command := p.Command
+			if p.Depth > 0 {
+				command = strings.Repeat("    ", p.Depth-1) + " \\_ " + command
+			}
+			fmt.Fprintln(w, f.row(res, "%d\t%d\t%s\t%s\t%.1f%%\t%s\t%s", p.Pid, p.PPid, p.User, p.State,
+				p.CPU, bytes(p.RSS), command))
+		}
+	}
+	w.Flush()
+}
+
+// PrintCrashes prints the crash reports of a program in table format
+func (s *Response) PrintCrashes() {
+	Fleet{s}.PrintCrashes()
+}
+
+// PrintCrashes prints the crash reports of a program on every agent in
+// table format
+func (f Fleet) PrintCrashes() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, f.header("ID\tTIME\tRUN\tPID\tSIGNAL\tBINARY\tSHA256\tCORE\tMESSAGE"))
+	for _, res := range f {
+		for _, c := range res.Crashes {
+			core := "-"
+			if c.CoreFile != "" {
+				core = fmt.Sprintf("%s (%s)", c.CoreFile, bytes(c.CoreSize))
+			}
+			fmt.Fprintln(w, f.row(res, "%d\t%s\t%d\t%d\t%s\t%s\t%.12s\t%s\t%s", c.ID, c.Time().Format(time.RFC3339),
+				c.RunID, c.Pid, c.Signal, c.Binary, c.BinarySHA256, core, c.Message))
+		}
+	}
+	w.Flush()
+}
+
+// PrintCrashReports prints the crash reports of a program in full,
+// with the output leading up to each crash
+func (s *Response) PrintCrashReports() {
+	Fleet{s}.PrintCrashReports()
+}
+
+// PrintCrashReports prints the crash reports of a program on every agent
+// in full, with the agent when the responses came from named agents
+func (f Fleet) PrintCrashReports() {
+	first := true
+	for _, res := range f {
+		for _, c := range res.Crashes {
+			if !first {
+				fmt.Println()
+			}
+			first = false
+			fmt.Printf("Crash %d at %s\n", c.ID, c.Time().Format(time.RFC3339))
+			if f.agents() {
+				fmt.Printf("  Agent:   %s\n", res.Agent)
+			}
+			fmt.Printf("  Run:     %d (pid %d)\n", c.RunID, c.Pid)
+			fmt.Printf("  Signal:  %s\n", c.Signal)
+			fmt.Printf("  Binary:  %s\n", c.Binary)
+			fmt.Printf("  SHA256:  %s\n", c.BinarySHA256)
+			fmt.Printf("  Host:    %s (%s, kernel %s, hxe %s)\n", c.Hostname, c.Arch, c.Kernel, c.Version)
+			fmt.Printf("  Env:     %s\n", c.EnvHash)
+			if c.CoreFile != "" {
+				fmt.Printf("  Core:    %s (%s)\n", c.CoreFile, bytes(c.CoreSize))
+			}
+			if c.Message != "" {
+				fmt.Printf("  Message: %s\n", c.Message)
+			}
+			fmt.Println("  Stdout:")
+			for _, line := range c.Stdout {
+				fmt.Printf("    %s\n", line)
+			}
+			fmt.Println("  Stderr:")
+			for _, line := range c.Stderr {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+}
+
+// bytes formats a size in bytes for humans
+func bytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
 	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
 }
@@ -0,0 +1,99 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package client
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+// PlanAction is what applying a plan does to a program
+type PlanAction string
+
+const (
+	PLAN_CREATE  PlanAction = "create"
+	PLAN_UPDATE  PlanAction = "update"
+	PLAN_RESTART PlanAction = "restart"
+	PLAN_DELETE  PlanAction = "delete"
+)
+
+// Plan is the difference between the desired programs and the programs
+// of the agent. Serial identifies the state the plan was made against;
+// applying it fails once that state has changed. Only pruning plans
+// delete programs.
+type Plan struct {
+	Programs []*models.Program `json:"programs"`
+	Selector string            `json:"selector,omitempty"`
+	Prune    bool              `json:"prune,omitempty"`
+	Changes  []*PlanChange     `json:"changes"`
+	Serial   string            `json:"serial"`
+}
+
+// PlanChange is the change of a single program. Update changes take
+// effect without a restart, restart changes restart the running process.
+type PlanChange struct {
+	Action PlanAction `json:"action"`
+	Name   string     `json:"name"`
+	Fields []string   `json:"fields,omitempty"`
+}
+
+// Plan diffs the desired programs against the programs of the agent.
+// With prune, programs created through the API that match the selector
+// and are not desired are deleted.
+func (c *Client) Plan(progs []*models.Program, selector string, prune bool) (resp *Response, err error) {
+	return c.request("program.plan", &Request{Plan: &Plan{Programs: progs, Selector: selector, Prune: prune}})
+}
+
+// Apply executes a plan, failing when the programs changed since the
+// plan was made
+func (c *Client) Apply(plan *Plan) (resp *Response, err error) {
+	return c.requestTimeout("program.apply", &Request{Plan: plan}, time.Minute)
+}
+
+// Count returns the number of changes of an action
+func (p *Plan) Count(action PlanAction) (n int) {
+	for _, change := range p.Changes {
+		if change.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Print writes the changes of a plan and a summary line
+func (p *Plan) Print() {
+	if len(p.Changes) == 0 {
+		fmt.Println("No changes, the programs match the configuration.")
+		return
+	}
+
+	symbols := map[PlanAction]string{PLAN_CREATE: "+", PLAN_UPDATE: "~", PLAN_RESTART: "~", PLAN_DELETE: "-"}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, change := range p.Changes {
+		fmt.Fprintf(w, "%s %s\t%s\t%s\n", symbols[change.Action], change.Action, change.Name, strings.Join(change.Fields, ", "))
+	}
+	w.Flush()
+	fmt.Printf("\nPlan: %d to create, %d to update, %d to restart, %d to delete.\n",
+		p.Count(PLAN_CREATE), p.Count(PLAN_UPDATE), p.Count(PLAN_RESTART), p.Count(PLAN_DELETE))
+}
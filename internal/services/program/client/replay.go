@@ -0,0 +1,179 @@
+/*
+ * HXE - Host-based Process Execution Agent
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rangertaha/hxe/internal/services"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+// Replay selects the streams of an agent to read, the messages
+// published since Since, and whether to wait for new ones
+type Replay struct {
+	Agent  string
+	Domain string
+	Events string
+	Logs   string
+	Since  time.Time
+	Follow bool
+}
+
+// Logs passes the output of a program, or of every program when name is
+// empty, to fn. Stream limits the output to stdout or stderr.
+func (c *Client) Logs(ctx context.Context, r *Replay, name, stream string, fn func(*models.Line)) error {
+	subject := services.Subject(r.Agent, fmt.Sprintf("%s.%s.%s", models.LOG_SUBJECT, token(name), token(stream)))
+	return c.replay(ctx, r, r.Logs, subject, func(data []byte) error {
+		line := &models.Line{}
+		if err := json.Unmarshal(data, line); err != nil {
+			return fmt.Errorf("failed to unmarshal program output: %w", err)
+		}
+		fn(line)
+		return nil
+	})
+}
+
+// Events passes the events of a program, or of every program when name
+// is empty, to fn
+func (c *Client) Events(ctx context.Context, r *Replay, name string, fn func(*models.Event)) error {
+	subject := services.Subject(r.Agent, fmt.Sprintf("%s.%s.*", models.EVENT_SUBJECT, token(name)))
+	return c.replay(ctx, r, r.Events, subject, func(data []byte) error {
+		event := &models.Event{}
+		if err := json.Unmarshal(data, event); err != nil {
+			return fmt.Errorf("failed to unmarshal program event: %w", err)
+		}
+		fn(event)
+		return nil
+	})
+}
+
+// replay reads the messages of subject in stream with an ordered
+// consumer. Without follow it returns once it has read every message
+// published so far, otherwise when ctx is done.
+func (c *Client) replay(ctx context.Context, r *Replay, stream, subject string, fn func([]byte) error) error {
+	if stream == "" {
+		return errors.New("jetstream is not enabled on the agent")
+	}
+
+	var js jetstream.JetStream
+	var err error
+	if r.Domain != "" {
+		js, err = jetstream.NewWithDomain(c.nc, r.Domain)
+	} else {
+		js, err = jetstream.New(c.nc)
+	}
+	if err != nil {
+		return err
+	}
+
+	cfg := jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{subject},
+		DeliverPolicy:  jetstream.DeliverAllPolicy,
+	}
+	if !r.Since.IsZero() {
+		cfg.DeliverPolicy = jetstream.DeliverByStartTimePolicy
+		cfg.OptStartTime = &r.Since
+	}
+	cons, err := js.OrderedConsumer(ctx, stream, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to read stream %s: %w", stream, err)
+	}
+	if !r.Follow && cons.CachedInfo().NumPending == 0 {
+		return nil
+	}
+
+	msgs, err := cons.Messages()
+	if err != nil {
+		return fmt.Errorf("failed to read stream %s: %w", stream, err)
+	}
+	defer msgs.Stop()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			msgs.Stop()
+		case <-done:
+		}
+	}()
+
+	for {
+		msg, err := msgs.Next()
+		if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read stream %s: %w", stream, err)
+		}
+		if err := fn(msg.Data()); err != nil {
+			return err
+		}
+
+		meta, err := msg.Metadata()
+		if err != nil {
+			return err
+		}
+		if !r.Follow && meta.NumPending == 0 {
+			return nil
+		}
+	}
+}
+
+// PrintLine prints a line of output to stdout or stderr like the
+// program wrote it, prefixed with the name of the program when prefix is
+// set
+func PrintLine(line *models.Line, prefix bool) {
+	out := os.Stdout
+	if line.Stream == "stderr" {
+		out = os.Stderr
+	}
+	if prefix {
+		fmt.Fprintf(out, "%s | %s\n", line.Name, line.Line)
+		return
+	}
+	fmt.Fprintln(out, line.Line)
+}
+
+// PrintEvent prints an event on a single line
+func PrintEvent(event *models.Event) {
+	detail := event.Reason
+	switch event.Type {
+	case models.EVENT_STATE:
+		if event.From != nil && event.To != nil {
+			detail = fmt.Sprintf("%s -> %s: %s", event.From, event.To, event.Reason)
+		}
+	case models.EVENT_TRIGGER:
+		detail = fmt.Sprintf("%s on %s: %s", event.Trigger, event.Stream, event.Line)
+	}
+	fmt.Printf("%s  %-20s %-8s %s\n", time.UnixMilli(event.Timestamp).Format(time.RFC3339), event.Name, event.Type, detail)
+}
+
+// token returns the subject token matching value, any when it is empty
+func token(value string) string {
+	if value == "" {
+		return "*"
+	}
+	return value
+}
@@ -0,0 +1,213 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Compose translates the services of a docker-compose file into
+// programs that run their entrypoint and command on the host. Services
+// without a command are skipped since the command of their image is
+// unknown.
+func Compose(name string, data []byte) (*Result, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping of compose keys")
+	}
+
+	res := &Result{}
+	var services *yaml.Node
+	pairs(doc.Content[0], func(key, value *yaml.Node) {
+		if key.Value == "services" {
+			services = value
+		} else if key.Value != "version" && key.Value != "name" {
+			res.warn("line %d: %s is not translated", key.Line, key.Value)
+		}
+	})
+	if services == nil || services.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("no services")
+	}
+
+	var err error
+	pairs(services, func(key, value *yaml.Node) {
+		if err != nil {
+			return
+		}
+		var prog *models.Program
+		if prog, err = composeService(key.Value, value, res); err != nil {
+			err = fmt.Errorf("line %d: service %s: %w", key.Line, key.Value, err)
+		} else if prog != nil {
+			res.Programs = append(res.Programs, prog)
+		} else {
+			res.warn("line %d: service %s has no command or entrypoint, skipped", key.Line, key.Value)
+		}
+	})
+	return res, err
+}
+
+// composeService translates a service, returning nil when it has no
+// command to run
+func composeService(name string, node *yaml.Node, res *Result) (prog *models.Program, err error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping")
+	}
+
+	// docker does not restart a service without a restart policy
+	prog = program(name)
+	prog.Autostart = true
+	prog.Restart = models.RESTART_NO
+	var entrypoint, command []string
+	pairs(node, func(key, value *yaml.Node) {
+		if err != nil {
+			return
+		}
+		switch key.Value {
+		case "command":
+			command, err = composeWords(value)
+		case "entrypoint":
+			entrypoint, err = composeWords(value)
+		case "environment":
+			prog.Env = composeList(value, func(item string, line int) {
+				res.warn("line %d: service %s: environment %s is taken from the compose environment, not translated", line, name, item)
+			})
+		case "labels":
+			labels := composeList(value, nil)
+			prog.Labels = map[string]string{}
+			for _, label := range labels {
+				k, v, _ := strings.Cut(label, "=")
+				prog.Labels[k] = v
+			}
+		case "depends_on":
+			for _, dep := range composeKeys(value) {
+				res.warn("line %d: service %s: depends_on %s is not translated, programs have no dependencies", value.Line, name, dep)
+			}
+		case "working_dir":
+			prog.Dir = value.Value
+		case "user":
+			prog.User, prog.Group, _ = strings.Cut(value.Value, ":")
+		case "restart":
+			err = composeRestart(prog, value.Value)
+		default:
+			res.warn("line %d: service %s: %s is not translated", key.Line, name, key.Value)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	words := append(entrypoint, command...)
+	if len(words) == 0 {
+		return nil, nil
+	}
+	prog.Exec = words[0]
+	if len(words) > 1 {
+		prog.Args = words[1:]
+	}
+	return prog, nil
+}
+
+// composeRestart sets the restart policy and retries of a restart value
+func composeRestart(prog *models.Program, value string) (err error) {
+	policy, retries, _ := strings.Cut(value, ":")
+	switch policy {
+	case "no":
+		prog.Restart = models.RESTART_NO
+	case "always", "unless-stopped":
+		prog.Restart = models.RESTART_ALWAYS
+	case "on-failure":
+		prog.Restart = models.RESTART_ON_FAILURE
+		if retries != "" {
+			prog.Retries, err = strconv.Atoi(retries)
+		}
+	default:
+		err = fmt.Errorf("unknown restart policy %q", value)
+	}
+	return err
+}
+
+// composeWords returns the words of a command given as a string or a list
+func composeWords(node *yaml.Node) ([]string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return nil, nil
+		}
+		return fields(node.Value)
+	case yaml.SequenceNode:
+		words := []string{}
+		for _, item := range node.Content {
+			words = append(words, item.Value)
+		}
+		return words, nil
+	}
+	return nil, fmt.Errorf("line %d: expected a string or a list", node.Line)
+}
+
+// composeList returns the KEY=value items of a mapping or a list, calling
+// unset with the keys that have no value
+func composeList(node *yaml.Node, unset func(key string, line int)) []string {
+	items := []string{}
+	add := func(key, value string, ok bool, line int) {
+		if !ok && unset != nil {
+			unset(key, line)
+			return
+		}
+		items = append(items, key+"="+value)
+	}
+	if node.Kind == yaml.MappingNode {
+		pairs(node, func(key, value *yaml.Node) {
+			add(key.Value, value.Value, value.Tag != "!!null", key.Line)
+		})
+		return items
+	}
+	for _, item := range node.Content {
+		key, value, ok := strings.Cut(item.Value, "=")
+		add(key, value, ok, item.Line)
+	}
+	return items
+}
+
+// composeKeys returns the items of a list, or the keys of a mapping
+func composeKeys(node *yaml.Node) []string {
+	keys := []string{}
+	if node.Kind == yaml.MappingNode {
+		pairs(node, func(key, _ *yaml.Node) { keys = append(keys, key.Value) })
+		return keys
+	}
+	for _, item := range node.Content {
+		keys = append(keys, item.Value)
+	}
+	return keys
+}
+
+// pairs calls fn with the keys and values of a mapping in file order
+func pairs(node *yaml.Node, fn func(key, value *yaml.Node)) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		fn(node.Content[i], node.Content[i+1])
+	}
+}
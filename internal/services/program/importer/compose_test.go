@@ -0,0 +1,103 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"testing"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+func TestCompose(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		want     []*models.Program
+		warnings []string
+		err      bool
+	}{
+		{
+			name: "services",
+			data: `version: "3"
+services:
+  web:
+    image: nginx
+    entrypoint: ["/bin/web"]
+    command: --port 80
+    environment:
+      A: "1"
+      B:
+    labels:
+      - tier=web
+    working_dir: /srv
+    user: www:www
+    depends_on: [db]
+  db:
+    command: postgres
+    restart: always
+  worker:
+    command: ["worker", "--queue", "emails"]
+    restart: on-failure:5
+  cache:
+    image: redis
+volumes:
+  data:
+`,
+			want: []*models.Program{
+				{
+					Name: "web", Enabled: true, Autostart: true, Restart: models.RESTART_NO,
+					Exec: "/bin/web", Args: []string{"--port", "80"}, Env: []string{"A=1"},
+					Labels: map[string]string{"tier": "web"}, Dir: "/srv", User: "www", Group: "www",
+				},
+				{Name: "db", Enabled: true, Autostart: true, Restart: models.RESTART_ALWAYS, Exec: "postgres"},
+				{Name: "worker", Enabled: true, Autostart: true, Restart: models.RESTART_ON_FAILURE, Retries: 5, Exec: "worker", Args: []string{"--queue", "emails"}},
+			},
+			warnings: []string{
+				"line 23: volumes is not translated",
+				"line 4: service web: image is not translated",
+				"line 9: service web: environment B is taken from the compose environment",
+				"line 14: service web: depends_on db is not translated",
+				"line 22: service cache: image is not translated",
+				"line 21: service cache has no command or entrypoint, skipped",
+			},
+		},
+		{
+			name: "unless stopped",
+			data: "services:\n  web:\n    command: web\n    restart: unless-stopped\n",
+			want: []*models.Program{{Name: "web", Enabled: true, Autostart: true, Restart: models.RESTART_ALWAYS, Exec: "web"}},
+		},
+		{
+			name: "unknown restart",
+			data: "services:\n  web:\n    command: web\n    restart: sometimes\n",
+			err:  true,
+		},
+		{
+			name: "no services",
+			data: "version: \"3\"\n",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Compose("docker-compose.yml", []byte(tt.data))
+			checkResult(t, res, err, tt.err, tt.want, tt.warnings)
+		})
+	}
+}
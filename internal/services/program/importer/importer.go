@@ -0,0 +1,179 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package importer translates supervisord, systemd, Procfile and
+// docker-compose definitions into programs
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+const (
+	FORMAT_SUPERVISORD = "supervisord"
+	FORMAT_SYSTEMD     = "systemd"
+	FORMAT_PROCFILE    = "procfile"
+	FORMAT_COMPOSE     = "compose"
+)
+
+// Result holds the programs translated from a file, and a warning for
+// every directive that has no program equivalent
+type Result struct {
+	Programs []*models.Program
+	Warnings []string
+}
+
+// warn records a directive that was not translated
+func (r *Result) warn(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Importer parses the contents of a file; name is the base name of the
+// file, used by formats that define a single program per file
+type Importer func(name string, data []byte) (*Result, error)
+
+// Formats are the importers by format name
+var Formats = map[string]Importer{
+	FORMAT_SUPERVISORD: Supervisord,
+	FORMAT_SYSTEMD:     Systemd,
+	FORMAT_PROCFILE:    Procfile,
+	FORMAT_COMPOSE:     Compose,
+}
+
+// Names returns the supported format names, sorted
+func Names() []string {
+	names := make([]string, 0, len(Formats))
+	for name := range Formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Detect guesses the format of a file from its name
+func Detect(path string) (string, error) {
+	base := strings.ToLower(filepath.Base(path))
+	ext := filepath.Ext(base)
+	switch {
+	case ext == ".service":
+		return FORMAT_SYSTEMD, nil
+	case base == "procfile" || strings.HasPrefix(base, "procfile."):
+		return FORMAT_PROCFILE, nil
+	case (ext == ".yml" || ext == ".yaml") && strings.Contains(base, "compose"):
+		return FORMAT_COMPOSE, nil
+	case ext == ".conf" || ext == ".ini":
+		return FORMAT_SUPERVISORD, nil
+	}
+	return "", fmt.Errorf("%s: unknown format, use one of %s", path, strings.Join(Names(), ", "))
+}
+
+// Import reads a file in the given format, detecting the format from the
+// file name when it is empty. Warnings are prefixed with the file name.
+func Import(format, path string) (*Result, error) {
+	var err error
+	if format == "" {
+		if format, err = Detect(path); err != nil {
+			return nil, err
+		}
+	}
+	importer, ok := Formats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q, use one of %s", format, strings.Join(Names(), ", "))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	res, err := importer(filepath.Base(path), data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, prog := range res.Programs {
+		if models.ValidateName(prog.Name) == nil {
+			continue
+		}
+		name := strings.Map(func(r rune) rune {
+			if strings.ContainsRune(".*> \t\r\n", r) {
+				return '-'
+			}
+			return r
+		}, prog.Name)
+		res.warn("program %q renamed %q, names may not contain dots, spaces, * or >", prog.Name, name)
+		prog.Name = name
+	}
+	for i, warning := range res.Warnings {
+		res.Warnings[i] = path + ": " + warning
+	}
+	return res, nil
+}
+
+// program returns a new enabled program
+func program(name string) *models.Program {
+	return &models.Program{Name: name, Enabled: true}
+}
+
+// fields splits s into words the way a shell does, honouring single and
+// double quotes and backslash escapes
+func fields(s string) ([]string, error) {
+	var (
+		words []string
+		word  strings.Builder
+		quote rune
+		in    bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := rune(s[i])
+		switch {
+		case quote == '\'' && c == '\'', quote == '"' && c == '"':
+			quote = 0
+		case quote == '\'':
+			word.WriteRune(c)
+		case c == '\\' && i+1 < len(s):
+			i++
+			word.WriteByte(s[i])
+			in = true
+		case quote == '"':
+			word.WriteRune(c)
+		case c == '\'' || c == '"':
+			quote, in = c, true
+		case c == ' ' || c == '\t' || c == '\n':
+			if in {
+				words = append(words, word.String())
+				word.Reset()
+				in = false
+			}
+		default:
+			word.WriteRune(c)
+			in = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if in {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
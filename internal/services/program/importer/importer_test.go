@@ -0,0 +1,97 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+// checkResult compares the programs of an import, and checks that the
+// warnings contain the given texts in order
+func checkResult(t *testing.T, res *Result, err error, fail bool, want []*models.Program, warnings []string) {
+	t.Helper()
+	if fail {
+		if err == nil {
+			t.Fatalf("import succeeded, want an error")
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Programs) != len(want) {
+		t.Fatalf("got %d programs, want %d", len(res.Programs), len(want))
+	}
+	for i, prog := range res.Programs {
+		if !reflect.DeepEqual(prog, want[i]) {
+			t.Errorf("program %d = %+v, want %+v", i, *prog, *want[i])
+		}
+	}
+	if len(res.Warnings) != len(warnings) {
+		t.Fatalf("warnings = %q, want %q", res.Warnings, warnings)
+	}
+	for i, warning := range res.Warnings {
+		if !strings.Contains(warning, warnings[i]) {
+			t.Errorf("warning %d = %q, want %q", i, warning, warnings[i])
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/etc/systemd/system/web.service", want: FORMAT_SYSTEMD},
+		{path: "Procfile", want: FORMAT_PROCFILE},
+		{path: "Procfile.dev", want: FORMAT_PROCFILE},
+		{path: "docker-compose.yml", want: FORMAT_COMPOSE},
+		{path: "compose.yaml", want: FORMAT_COMPOSE},
+		{path: "/etc/supervisor/conf.d/app.conf", want: FORMAT_SUPERVISORD},
+		{path: "supervisord.ini", want: FORMAT_SUPERVISORD},
+		{path: "app.yml"},
+		{path: "README"},
+	}
+
+	for _, tt := range tests {
+		got, err := Detect(tt.path)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("Detect(%q) = %q, want an error", tt.path, got)
+		case tt.want != "" && got != tt.want:
+			t.Errorf("Detect(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestImportRename(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web.v2.service")
+	if err := os.WriteFile(path, []byte("[Service]\nExecStart=/usr/bin/web\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := Import("", path)
+	want := []*models.Program{{Name: "web-v2", Enabled: true, Exec: "/usr/bin/web"}}
+	checkResult(t, res, err, false, want, []string{path + `: program "web.v2" renamed "web-v2"`})
+}
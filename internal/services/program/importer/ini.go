@@ -0,0 +1,100 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// entry is a key = value line of an INI file
+type entry struct {
+	key   string
+	value string
+	line  int
+}
+
+// section is a [name] section of an INI file with its entries in order
+type section struct {
+	name    string
+	line    int
+	entries []*entry
+}
+
+// parseINI reads the sections of a supervisord or systemd file. Values
+// are continued on indented lines in supervisord files and after a
+// trailing backslash in systemd units.
+func parseINI(data []byte, systemd bool) ([]*section, error) {
+	var (
+		sections []*section
+		current  *section
+		last     *entry
+		joining  bool
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+
+		if joining {
+			last.value += " " + strings.TrimSpace(strings.TrimSuffix(line, "\\"))
+			joining = strings.HasSuffix(line, "\\")
+			continue
+		}
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if !systemd {
+			if i := strings.Index(line, " ;"); i >= 0 {
+				line = strings.TrimSpace(line[:i])
+			}
+			if last != nil && (raw[0] == ' ' || raw[0] == '\t') {
+				last.value += " " + line
+				continue
+			}
+		}
+
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated section %s", n, line)
+			}
+			current = &section{name: strings.TrimSpace(line[1 : len(line)-1]), line: n}
+			sections = append(sections, current)
+			last = nil
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value, got %q", n, line)
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: %s is outside of a section", n, strings.TrimSpace(key))
+		}
+		last = &entry{key: strings.TrimSpace(key), value: strings.TrimSpace(value), line: n}
+		if systemd && strings.HasSuffix(last.value, "\\") {
+			last.value = strings.TrimSpace(strings.TrimSuffix(last.value, "\\"))
+			joining = true
+		}
+		current.entries = append(current.entries, last)
+	}
+	return sections, scanner.Err()
+}
@@ -0,0 +1,61 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+var (
+	procfileLine = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.+)$`)
+	procfilePort = regexp.MustCompile(`\$\{?PORT\b`)
+)
+
+// Procfile translates the name: command lines of a Procfile into
+// programs that start with the agent and are always restarted
+func Procfile(name string, data []byte) (*Result, error) {
+	res := &Result{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		match := procfileLine.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("line %d: expected name: command, got %q", n, line)
+		}
+
+		prog := program(match[1])
+		prog.Exec = match[2]
+		prog.Autostart = true
+		prog.Restart = models.RESTART_ALWAYS
+		if procfilePort.MatchString(prog.Exec) {
+			res.warn("line %d: %s uses $PORT, which is not set by the agent", n, prog.Name)
+		}
+		res.Programs = append(res.Programs, prog)
+	}
+	return res, scanner.Err()
+}
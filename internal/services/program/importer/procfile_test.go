@@ -0,0 +1,57 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"testing"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+func TestProcfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		want     []*models.Program
+		warnings []string
+		err      bool
+	}{
+		{
+			name: "processes",
+			data: "# processes\nweb: bundle exec puma -p $PORT\n\nworker:rake jobs:work\n",
+			want: []*models.Program{
+				{Name: "web", Enabled: true, Autostart: true, Restart: models.RESTART_ALWAYS, Exec: "bundle exec puma -p $PORT"},
+				{Name: "worker", Enabled: true, Autostart: true, Restart: models.RESTART_ALWAYS, Exec: "rake jobs:work"},
+			},
+			warnings: []string{"line 2: web uses $PORT"},
+		},
+		{
+			name: "invalid line",
+			data: "web bundle exec puma\n",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Procfile("Procfile", []byte(tt.data))
+			checkResult(t, res, err, tt.err, tt.want, tt.warnings)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+// Supervisord translates the [program:x] sections of a supervisord
+// config. Programs start with the supervisord defaults: autostart,
+// restart on unexpected exit codes, 3 start retries and exit code 0.
+func Supervisord(name string, data []byte) (*Result, error) {
+	sections, err := parseINI(data, false)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{}
+	for _, sec := range sections {
+		kind, progName, _ := strings.Cut(sec.name, ":")
+		if kind != "program" || progName == "" {
+			res.warn("line %d: section [%s] is not translated", sec.line, sec.name)
+			continue
+		}
+
+		prog := program(progName)
+		prog.Autostart = true
+		prog.Restart = models.RESTART_ON_FAILURE
+		prog.Retries = 3
+		prog.SuccessExitCodes = []int{0}
+		for _, e := range sec.entries {
+			if err := supervisordEntry(prog, e, res); err != nil {
+				return nil, fmt.Errorf("line %d: %s: %w", e.line, e.key, err)
+			}
+		}
+		if prog.Exec == "" {
+			res.warn("line %d: [%s] has no command, skipped", sec.line, sec.name)
+			continue
+		}
+		res.Programs = append(res.Programs, prog)
+	}
+	return res, nil
+}
+
+// supervisordEntry sets the program field of a [program:x] entry
+func supervisordEntry(prog *models.Program, e *entry, res *Result) (err error) {
+	value := strings.ReplaceAll(e.value, "%(program_name)s", prog.Name)
+	if strings.Contains(value, "%(") {
+		res.warn("line %d: %s: %%(...)s expansions other than program_name are not translated", e.line, e.key)
+	}
+
+	switch e.key {
+	case "command":
+		prog.Exec = value
+	case "directory":
+		prog.Dir = value
+	case "user":
+		prog.User = value
+	case "environment":
+		prog.Env, err = supervisordEnv(value)
+	case "autostart":
+		prog.Autostart, err = supervisordBool(value)
+	case "autorestart":
+		if strings.ToLower(value) == "unexpected" {
+			prog.Restart = models.RESTART_ON_FAILURE
+			break
+		}
+		var always bool
+		if always, err = supervisordBool(value); err != nil {
+			return fmt.Errorf("expected true, false or unexpected, got %q", value)
+		}
+		prog.Restart = models.RESTART_NO
+		if always {
+			prog.Restart = models.RESTART_ALWAYS
+		}
+	case "startretries":
+		prog.Retries, err = strconv.Atoi(value)
+	case "exitcodes":
+		prog.SuccessExitCodes, err = ints(value)
+	default:
+		res.warn("line %d: [program:%s] %s = %s is not translated", e.line, prog.Name, e.key, e.value)
+	}
+	return err
+}
+
+// supervisordBool parses the true and false values of supervisord
+func supervisordBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected true or false, got %q", value)
+}
+
+// supervisordEnv parses KEY="value",KEY2=value2 into KEY=value pairs
+func supervisordEnv(value string) (env []string, err error) {
+	var (
+		pair  strings.Builder
+		quote rune
+	)
+	add := func() error {
+		key, val, ok := strings.Cut(strings.TrimSpace(pair.String()), "=")
+		pair.Reset()
+		if !ok {
+			return fmt.Errorf("expected KEY=value, got %q", key)
+		}
+		env = append(env, strings.TrimSpace(key)+"="+val)
+		return nil
+	}
+	for _, c := range value {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == ',':
+			if err := add(); err != nil {
+				return nil, err
+			}
+		default:
+			pair.WriteRune(c)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", value)
+	}
+	if strings.TrimSpace(pair.String()) != "" {
+		if err := add(); err != nil {
+			return nil, err
+		}
+	}
+	return env, nil
+}
+
+// ints parses a comma separated list of integers
+func ints(value string) ([]int, error) {
+	list := []int{}
+	for _, s := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, n)
+	}
+	return list, nil
+}
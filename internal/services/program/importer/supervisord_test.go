@@ -0,0 +1,103 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"testing"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+func TestSupervisord(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		want     []*models.Program
+		warnings []string
+		err      bool
+	}{
+		{
+			name: "program",
+			data: `[supervisord]
+logfile = /var/log/supervisord.log
+
+[program:web]
+command = /usr/bin/web --name %(program_name)s
+directory = /srv/web
+user = www
+environment = A="1, 2",B=b
+autorestart = True
+startretries = 5
+exitcodes = 0,2
+stdout_logfile = /var/log/web.log ; comment
+`,
+			want: []*models.Program{{
+				Name: "web", Enabled: true, Autostart: true,
+				Exec: "/usr/bin/web --name web", Dir: "/srv/web", User: "www",
+				Env:     []string{"A=1, 2", "B=b"},
+				Restart: models.RESTART_ALWAYS, Retries: 5, SuccessExitCodes: []int{0, 2},
+			}},
+			warnings: []string{"line 1: section [supervisord] is not translated", "line 12: [program:web] stdout_logfile"},
+		},
+		{
+			name: "defaults",
+			data: "[program:worker]\ncommand = worker\n",
+			want: []*models.Program{{
+				Name: "worker", Enabled: true, Autostart: true, Exec: "worker",
+				Restart: models.RESTART_ON_FAILURE, Retries: 3, SuccessExitCodes: []int{0},
+			}},
+		},
+		{
+			name: "restart values",
+			data: "[program:a]\ncommand = a\nautorestart = unexpected\nautostart = no\n[program:b]\ncommand = b\nautorestart = FALSE\n",
+			want: []*models.Program{
+				{Name: "a", Enabled: true, Exec: "a", Restart: models.RESTART_ON_FAILURE, Retries: 3, SuccessExitCodes: []int{0}},
+				{Name: "b", Enabled: true, Autostart: true, Exec: "b", Restart: models.RESTART_NO, Retries: 3, SuccessExitCodes: []int{0}},
+			},
+		},
+		{
+			name:     "no command",
+			data:     "[program:empty]\ndirectory = /tmp\n",
+			warnings: []string{"line 1: [program:empty] has no command, skipped"},
+		},
+		{
+			name:     "unsupported expansion",
+			data:     "[program:web]\ncommand = web --host %(host_node_name)s\n",
+			want:     []*models.Program{{Name: "web", Enabled: true, Autostart: true, Exec: "web --host %(host_node_name)s", Restart: models.RESTART_ON_FAILURE, Retries: 3, SuccessExitCodes: []int{0}}},
+			warnings: []string{"line 2: command: %(...)s expansions"},
+		},
+		{
+			name: "invalid autorestart",
+			data: "[program:web]\ncommand = web\nautorestart = sometimes\n",
+			err:  true,
+		},
+		{
+			name: "invalid exit codes",
+			data: "[program:web]\ncommand = web\nexitcodes = 0,x\n",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Supervisord("app.conf", []byte(tt.data))
+			checkResult(t, res, err, tt.err, tt.want, tt.warnings)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+// Systemd translates a .service unit into a program named after the unit
+func Systemd(name string, data []byte) (*Result, error) {
+	sections, err := parseINI(data, true)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{}
+	prog := program(strings.TrimSuffix(name, ".service"))
+	if strings.HasSuffix(prog.Name, "@") {
+		prog.Name = strings.TrimSuffix(prog.Name, "@")
+		res.warn("template unit, %%i and other specifiers are not expanded")
+	}
+
+	for _, sec := range sections {
+		for _, e := range sec.entries {
+			if err := systemdEntry(prog, sec.name, e, res); err != nil {
+				return nil, fmt.Errorf("line %d: %s: %w", e.line, e.key, err)
+			}
+		}
+	}
+	if prog.Exec == "" {
+		return nil, fmt.Errorf("no ExecStart in [Service]")
+	}
+	res.Programs = append(res.Programs, prog)
+	return res, nil
+}
+
+// systemdEntry sets the program field of a unit directive
+func systemdEntry(prog *models.Program, section string, e *entry, res *Result) (err error) {
+	switch section + "." + e.key {
+	case "Unit.Description":
+		prog.Desc = e.value
+	case "Service.ExecStart":
+		prog.Exec = systemdJoin(prog.Exec, systemdCommand(e, res))
+	case "Service.ExecStartPre":
+		prog.PreExec = systemdJoin(prog.PreExec, systemdCommand(e, res))
+	case "Service.ExecStopPost":
+		prog.PostExec = systemdJoin(prog.PostExec, systemdCommand(e, res))
+	case "Service.User":
+		prog.User = e.value
+	case "Service.Group":
+		prog.Group = e.value
+	case "Service.WorkingDirectory":
+		prog.Dir = strings.TrimPrefix(e.value, "-")
+	case "Service.Environment":
+		var env []string
+		if env, err = fields(e.value); err == nil {
+			prog.Env = append(prog.Env, env...)
+		}
+	case "Service.Restart":
+		switch e.value {
+		case "no":
+			prog.Restart = models.RESTART_NO
+		case "always":
+			prog.Restart = models.RESTART_ALWAYS
+		case "on-failure":
+			prog.Restart = models.RESTART_ON_FAILURE
+		case "on-abnormal", "on-abort", "on-watchdog":
+			prog.Restart = models.RESTART_ON_FAILURE
+			res.warn("line %d: Restart=%s is translated as on-failure", e.line, e.value)
+		case "on-success":
+			err = fmt.Errorf("Restart=on-success has no program equivalent, use no or always")
+		default:
+			err = fmt.Errorf("unknown restart setting %q", e.value)
+		}
+	case "Service.SuccessExitStatus":
+		prog.SuccessExitCodes = append([]int{0}, systemdExitCodes(e, res)...)
+	case "Service.RuntimeMaxSec":
+		prog.MaxRuntime, err = systemdDuration(e.value)
+	case "Service.LimitCORE":
+		var limit int64 = -1
+		if e.value != "infinity" {
+			limit, err = strconv.ParseInt(e.value, 10, 64)
+		}
+		prog.RlimitCore = &limit
+	case "Service.Type":
+		if e.value != "simple" && e.value != "exec" {
+			res.warn("line %d: Type=%s is not translated, programs are supervised as Type=exec", e.line, e.value)
+		}
+	case "Install.WantedBy":
+		prog.Autostart = true
+	default:
+		res.warn("line %d: [%s] %s=%s is not translated", e.line, section, e.key, e.value)
+	}
+	return err
+}
+
+// systemdCommand strips the @, -, :, + and ! prefixes of an Exec line,
+// warning about the ones that change how the command is run
+func systemdCommand(e *entry, res *Result) string {
+	command := e.value
+	for len(command) > 0 && strings.ContainsRune("@-:+!", rune(command[0])) {
+		if command[0] != ':' {
+			res.warn("line %d: %s prefix %q is not translated", e.line, e.key, command[0])
+		}
+		command = command[1:]
+	}
+	return command
+}
+
+// systemdJoin chains repeated Exec lines so that each runs after the
+// previous one succeeded
+func systemdJoin(commands, command string) string {
+	if commands == "" {
+		return command
+	}
+	return commands + " && " + command
+}
+
+// systemdExitCodes parses the numeric codes of SuccessExitStatus, warning
+// about signal names
+func systemdExitCodes(e *entry, res *Result) []int {
+	codes := []int{}
+	for _, s := range strings.Fields(e.value) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			res.warn("line %d: %s %s is not translated", e.line, e.key, s)
+			continue
+		}
+		codes = append(codes, n)
+	}
+	return codes
+}
+
+// systemdDuration parses a time span such as 90, 5min or 1h 30min
+func systemdDuration(value string) (time.Duration, error) {
+	if value == "infinity" {
+		return 0, nil
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+	span := strings.NewReplacer(" ", "", "min", "m", "sec", "s", "hr", "h").Replace(value)
+	return time.ParseDuration(span)
+}
@@ -0,0 +1,109 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+func TestSystemd(t *testing.T) {
+	unlimited := int64(-1)
+	tests := []struct {
+		name     string
+		unit     string
+		data     string
+		want     []*models.Program
+		warnings []string
+		err      bool
+	}{
+		{
+			name: "service",
+			unit: "web.service",
+			data: `[Unit]
+Description=Web server
+After=network.target
+
+[Service]
+Type=exec
+User=www
+Group=www
+WorkingDirectory=-/srv/web
+Environment="A=1 2" B=b
+ExecStartPre=/bin/true
+ExecStart=/usr/bin/web \
+  --port 80
+ExecStopPost=-/bin/cleanup
+Restart=always
+SuccessExitStatus=3 SIGUSR1
+RuntimeMaxSec=5min
+LimitCORE=infinity
+
+[Install]
+WantedBy=multi-user.target
+`,
+			want: []*models.Program{{
+				Name: "web", Desc: "Web server", Enabled: true, Autostart: true,
+				User: "www", Group: "www", Dir: "/srv/web", Env: []string{"A=1 2", "B=b"},
+				PreExec: "/bin/true", Exec: "/usr/bin/web --port 80", PostExec: "/bin/cleanup",
+				Restart: models.RESTART_ALWAYS, SuccessExitCodes: []int{0, 3},
+				MaxRuntime: 5 * time.Minute, RlimitCore: &unlimited,
+			}},
+			warnings: []string{
+				"line 3: [Unit] After=network.target is not translated",
+				"line 14: ExecStopPost prefix '-' is not translated",
+				"line 16: SuccessExitStatus SIGUSR1 is not translated",
+			},
+		},
+		{
+			name:     "template unit",
+			unit:     "worker@.service",
+			data:     "[Service]\nExecStart=/usr/bin/worker %i\nExecStart=/usr/bin/report\nType=forking\nRestart=on-abnormal\n",
+			want:     []*models.Program{{Name: "worker", Enabled: true, Exec: "/usr/bin/worker %i && /usr/bin/report", Restart: models.RESTART_ON_FAILURE}},
+			warnings: []string{"template unit", "line 4: Type=forking is not translated", "line 5: Restart=on-abnormal is translated as on-failure"},
+		},
+		{
+			name: "restart on success",
+			unit: "web.service",
+			data: "[Service]\nExecStart=/usr/bin/web\nRestart=on-success\n",
+			err:  true,
+		},
+		{
+			name: "unknown restart",
+			unit: "web.service",
+			data: "[Service]\nExecStart=/usr/bin/web\nRestart=sometimes\n",
+			err:  true,
+		},
+		{
+			name: "no exec",
+			unit: "web.service",
+			data: "[Service]\nUser=www\n",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Systemd(tt.unit, []byte(tt.data))
+			checkResult(t, res, err, tt.err, tt.want, tt.warnings)
+		})
+	}
+}
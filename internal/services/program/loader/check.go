@@ -0,0 +1,129 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package loader
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+	"github.com/rangertaha/hxe/internal/services/program/supervisor"
+)
+
+// check reports the settings of a program that the supervisor could only
+// reject or ignore once the program runs
+func check(prog *models.Program, block *hcl.Block) (diags hcl.Diagnostics) {
+	invalid := func(summary, attr, format string, args ...any) {
+		diags = diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  summary,
+			Detail:   fmt.Sprintf("Program %q ", prog.Name) + fmt.Sprintf(format, args...),
+			Subject:  attrRange(block, attr),
+		})
+	}
+
+	switch prog.Restart {
+	case "", models.RESTART_NO, models.RESTART_ALWAYS, models.RESTART_ON_FAILURE:
+	default:
+		invalid("Invalid restart policy", "restart", "has restart policy %q, which is not one of no, always or on-failure.", prog.Restart)
+	}
+	if prog.ReloadSignal != "" {
+		if _, err := supervisor.ParseSignal(prog.ReloadSignal); err != nil {
+			invalid("Invalid signal", "reload_signal", "reloads with an invalid signal: %s.", err)
+		}
+	}
+	for _, name := range prog.NoRestartOnSignals {
+		if _, err := supervisor.ParseSignal(name); err != nil {
+			invalid("Invalid signal", "no_restart_on_signals", "is not restarted on an invalid signal: %s.", err)
+		}
+	}
+	for _, t := range prog.Triggers {
+		diags = diags.Extend(checkTrigger(prog, &t, block))
+	}
+	return diags
+}
+
+// checkTrigger reports a trigger whose pattern, stream or action can not
+// be used
+func checkTrigger(prog *models.Program, t *models.Trigger, block *hcl.Block) (diags hcl.Diagnostics) {
+	invalid := func(summary, attr, format string, args ...any) {
+		diags = diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  summary,
+			Detail:   fmt.Sprintf("Trigger %q of program %q ", t.Name, prog.Name) + fmt.Sprintf(format, args...),
+			Subject:  triggerRange(block, t.Name, attr),
+		})
+	}
+
+	if _, err := regexp.Compile(t.Pattern); err != nil {
+		invalid("Invalid trigger pattern", "pattern", "has an invalid pattern: %s.", err)
+	}
+	switch t.Stream {
+	case "", "stdout", "stderr":
+	default:
+		invalid("Invalid trigger stream", "stream", "matches stream %q, which is neither stdout nor stderr.", t.Stream)
+	}
+	switch t.Action {
+	case models.TRIGGER_RESTART, models.TRIGGER_STOP, models.TRIGGER_PUBLISH, models.TRIGGER_READY:
+	case models.TRIGGER_SIGNAL:
+		if _, err := supervisor.ParseSignal(t.Signal); err != nil {
+			invalid("Invalid trigger signal", "signal", "sends an invalid signal: %s.", err)
+		}
+	case models.TRIGGER_EXEC:
+		if t.Command == "" {
+			invalid("Missing trigger command", "command", "runs a command but has no command set.")
+		}
+	default:
+		invalid("Invalid trigger action", "action", "has action %q, which is not one of restart, stop, signal, publish, exec or ready.", t.Action)
+	}
+	return diags
+}
+
+// attrRange returns the source range of an attribute of a program block,
+// or of the block when the attribute isn't set there
+func attrRange(block *hcl.Block, attr string) *hcl.Range {
+	if body, ok := block.Body.(*hclsyntax.Body); ok {
+		if attribute, ok := body.Attributes[attr]; ok {
+			return attribute.SrcRange.Ptr()
+		}
+	}
+	return block.DefRange.Ptr()
+}
+
+// triggerRange returns the source range of an attribute of a trigger
+// block, of the trigger block when the attribute isn't set, or of the
+// program block when the trigger comes from a template
+func triggerRange(block *hcl.Block, name, attr string) *hcl.Range {
+	body, ok := block.Body.(*hclsyntax.Body)
+	if !ok {
+		return block.DefRange.Ptr()
+	}
+	for _, b := range body.Blocks {
+		if b.Type != "trigger" || len(b.Labels) == 0 || b.Labels[0] != name {
+			continue
+		}
+		if attribute, ok := b.Body.Attributes[attr]; ok {
+			return attribute.SrcRange.Ptr()
+		}
+		return b.DefRange().Ptr()
+	}
+	return block.DefRange.Ptr()
+}
@@ -0,0 +1,101 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package loader
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Encode writes programs as formatted program blocks that the loader
+// reads back as the same programs. Zero values and nil lists are left
+// out and durations are written with duration().
+func Encode(progs ...*models.Program) ([]byte, error) {
+	file := hclwrite.NewEmptyFile()
+	body := file.Body()
+	for i, prog := range progs {
+		if i > 0 {
+			body.AppendNewline()
+		}
+		block := body.AppendNewBlock("program", []string{prog.Name})
+		if err := encodeBody(block.Body(), reflect.ValueOf(prog).Elem()); err != nil {
+			return nil, err
+		}
+	}
+	return hclwrite.Format(file.Bytes()), nil
+}
+
+// encodeBody writes the hcl tagged fields of a struct into body
+func encodeBody(body *hclwrite.Body, v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		name, kind, _ := strings.Cut(v.Type().Field(i).Tag.Get("hcl"), ",")
+		field := v.Field(i)
+		if name == "" || kind == "label" || field.IsZero() {
+			continue
+		}
+
+		if kind == "block" {
+			for j := 0; j < field.Len(); j++ {
+				elem := reflect.Indirect(field.Index(j))
+				block := body.AppendNewBlock(name, labelsOf(elem))
+				if err := encodeBody(block.Body(), elem); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		field = reflect.Indirect(field)
+		if field.Type() == durationType {
+			d := time.Duration(field.Int())
+			body.SetAttributeRaw(name, hclwrite.TokensForFunctionCall("duration",
+				hclwrite.TokensForValue(cty.StringVal(d.String()))))
+			continue
+		}
+
+		ty, err := gocty.ImpliedType(field.Interface())
+		if err != nil {
+			return err
+		}
+		value, err := gocty.ToCtyValue(field.Interface(), ty)
+		if err != nil {
+			return err
+		}
+		body.SetAttributeValue(name, value)
+	}
+	return nil
+}
+
+// labelsOf returns the label fields of a block struct
+func labelsOf(v reflect.Value) (labels []string) {
+	for i := 0; i < v.NumField(); i++ {
+		if _, kind, _ := strings.Cut(v.Type().Field(i).Tag.Get("hcl"), ","); kind == "label" {
+			labels = append(labels, v.Field(i).String())
+		}
+	}
+	return labels
+}
@@ -0,0 +1,133 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package loader
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	t.Setenv(VAR_PREFIX+"queue", "emails")
+
+	dir := t.TempDir()
+	write(t, dir, "templates.hcl", `
+variable "queue" {
+  default = "default"
+}
+
+variable "retries" {
+  default = 3
+}
+
+locals {
+  python = "/usr/bin/python3"
+  worker = "${local.python} worker.py"
+}
+
+template "base" {
+  env         = ["PYTHONUNBUFFERED=1", "LOG_LEVEL=info"]
+  labels      = { team = "backend", tier = "worker" }
+  retries     = var.retries
+  max_runtime = duration("1h")
+  rlimit_core = 0
+
+  trigger "ready" {
+    pattern = "listening"
+    action  = "ready"
+  }
+
+  trigger "panic" {
+    pattern = "panic:"
+    action  = "restart"
+  }
+}
+
+template "python-worker" {
+  extends = "base"
+  exec    = "${local.worker} --queue ${var.queue}"
+  restart = "on-failure"
+}`)
+	write(t, dir, "programs.hcl", `
+program "emails" {
+  extends = "python-worker"
+  env     = ["LOG_LEVEL=debug", "QUEUE=${var.queue}"]
+  labels  = { tier = "mail" }
+  args    = ["--verbose"]
+
+  trigger "panic" {
+    pattern    = "fatal:"
+    action     = "stop"
+    rate_limit = duration("30s")
+  }
+}`)
+
+	first := Parse(dir)
+	if first.Diags.HasErrors() {
+		t.Fatal(first.Diags.Error())
+	}
+	if len(first.Programs) != 1 {
+		t.Fatalf("got %d programs, want 1", len(first.Programs))
+	}
+	prog := first.Programs[0]
+
+	// the merged program, before it goes through the encoder
+	if want := "/usr/bin/python3 worker.py --queue emails"; prog.Exec != want {
+		t.Errorf("exec = %q, want %q", prog.Exec, want)
+	}
+	if want := []string{"PYTHONUNBUFFERED=1", "LOG_LEVEL=debug", "QUEUE=emails"}; !slices.Equal(prog.Env, want) {
+		t.Errorf("env = %q, want %q", prog.Env, want)
+	}
+	if want := map[string]string{"team": "backend", "tier": "mail"}; !reflect.DeepEqual(prog.Labels, want) {
+		t.Errorf("labels = %v, want %v", prog.Labels, want)
+	}
+	want := []models.Trigger{
+		{Name: "ready", Pattern: "listening", Action: models.TRIGGER_READY},
+		{Name: "panic", Pattern: "fatal:", Action: models.TRIGGER_STOP, RateLimit: 30 * time.Second},
+	}
+	if !reflect.DeepEqual(prog.Triggers, want) {
+		t.Errorf("triggers = %+v, want %+v", prog.Triggers, want)
+	}
+	if prog.Retries != 3 || prog.MaxRuntime != time.Hour || prog.RlimitCore == nil || *prog.RlimitCore != 0 {
+		t.Errorf("retries, max_runtime and rlimit_core = %d, %s, %v, want 3, 1h and 0", prog.Retries, prog.MaxRuntime, prog.RlimitCore)
+	}
+
+	data, err := Encode(first.Programs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported := t.TempDir()
+	write(t, exported, "export.hcl", string(data))
+	second := Parse(exported)
+	if second.Diags.HasErrors() {
+		t.Fatalf("%s\n%s", second.Diags.Error(), data)
+	}
+	if len(second.Programs) != 1 {
+		t.Fatalf("got %d programs back, want 1", len(second.Programs))
+	}
+
+	loaded := *second.Programs[0]
+	loaded.Source = prog.Source
+	if !reflect.DeepEqual(&loaded, prog) {
+		t.Errorf("exported program loads as\n%+v\nwant\n%+v\n%s", &loaded, prog, data)
+	}
+}
@@ -0,0 +1,320 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package loader loads program definitions from the HCL files of a
+// programs directory into the database
+package loader
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rangertaha/hxe/internal/config"
+	"github.com/rangertaha/hxe/internal/db"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+var schema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "program", LabelNames: []string{"name"}},
+		{Type: "template", LabelNames: []string{"name"}},
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "locals"},
+	},
+}
+
+// Result is what was parsed from a programs directory
+type Result struct {
+	Dir      string
+	Programs []*models.Program
+	Diags    hcl.Diagnostics
+
+	// failed are the files that have errors, whose programs are left
+	// as they are in the database
+	failed    map[string]bool
+	blocks    map[string]*hcl.Block
+	templates map[string]*template
+	ctx       *hcl.EvalContext
+	parser    *hclparse.Parser
+}
+
+// Changes are what a sync did to the programs in the database
+type Changes struct {
+	Created   []string
+	Updated   []string
+	Unchanged []string
+	Orphaned  []string
+
+	// Fields are the attributes that changed, by program name
+	Fields map[string][]string
+}
+
+// Parse reads the program blocks of every *.hcl and *.hcl.json file in
+// dir. A missing directory defines no programs.
+func Parse(dir string) *Result {
+	files, err := filepath.Glob(filepath.Join(dir, "*.hcl"))
+	if err == nil {
+		var json []string
+		json, err = filepath.Glob(filepath.Join(dir, "*.hcl.json"))
+		files = append(files, json...)
+	}
+	if err != nil {
+		r := newResult(dir)
+		r.Diags = r.Diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid programs directory",
+			Detail:   err.Error(),
+		})
+		return r
+	}
+	sort.Strings(files)
+	return ParseFiles(dir, files...)
+}
+
+// ParseFiles reads the program blocks of the given files as if they were
+// the files of the programs directory dir. Variables, locals and
+// templates are shared by all the files.
+func ParseFiles(dir string, files ...string) *Result {
+	r := newResult(dir)
+
+	contents := map[string]*hcl.BodyContent{}
+	for _, filename := range files {
+		parse := r.parser.ParseHCLFile
+		if strings.HasSuffix(filename, ".json") {
+			parse = r.parser.ParseJSONFile
+		}
+		file, diags := parse(filename)
+		if !diags.HasErrors() {
+			content, moreDiags := file.Body.Content(schema)
+			diags = diags.Extend(moreDiags)
+			contents[filename] = content
+		}
+		r.fail(filename, diags)
+	}
+	r.scope(files, contents)
+
+	defined := map[string]hcl.Range{}
+	for _, filename := range files {
+		if r.failed[filename] {
+			continue
+		}
+		progs, diags := r.parseFile(filename, contents[filename], defined)
+		r.fail(filename, diags)
+		if diags.HasErrors() {
+			continue
+		}
+		r.Programs = append(r.Programs, progs...)
+	}
+	return r
+}
+
+func newResult(dir string) *Result {
+	return &Result{
+		Dir:       dir,
+		failed:    map[string]bool{},
+		blocks:    map[string]*hcl.Block{},
+		templates: map[string]*template{},
+		ctx:       config.CtxFunctions,
+		parser:    hclparse.NewParser(),
+	}
+}
+
+// fail records the diagnostics of a file, marking it failed when there
+// are errors
+func (r *Result) fail(filename string, diags hcl.Diagnostics) {
+	r.Diags = r.Diags.Extend(diags)
+	if diags.HasErrors() {
+		r.failed[filename] = true
+	}
+}
+
+// parseFile decodes the programs of a file. Names are only claimed in
+// defined when the whole file is valid, so that a broken file does not
+// make the programs of others duplicates.
+func (r *Result) parseFile(filename string, content *hcl.BodyContent, defined map[string]hcl.Range) (progs []*models.Program, diags hcl.Diagnostics) {
+	blocks := map[string]*hcl.Block{}
+	defer func() {
+		if !diags.HasErrors() {
+			for name, block := range blocks {
+				defined[name] = block.DefRange
+				r.blocks[name] = block
+			}
+		}
+	}()
+
+	for _, block := range content.Blocks {
+		if block.Type != "program" {
+			continue
+		}
+		prog, moreDiags := r.decode(block.Body)
+		diags = diags.Extend(moreDiags)
+		prog.Name = block.Labels[0]
+		prog.Source = filename
+
+		if prog.Name == "" {
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Missing program name",
+				Detail:   "A program block needs a non-empty name label.",
+				Subject:  block.LabelRanges[0].Ptr(),
+			})
+			continue
+		}
+		if err := models.ValidateName(prog.Name); err != nil {
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid program name",
+				Detail:   "Program names are used in subjects, so they may not contain dots, spaces, * or >.",
+				Subject:  block.LabelRanges[0].Ptr(),
+			})
+			continue
+		}
+		if moreDiags := check(prog, block); moreDiags.HasErrors() {
+			diags = diags.Extend(moreDiags)
+			continue
+		}
+		first, ok := defined[prog.Name]
+		if block, dup := blocks[prog.Name]; dup {
+			first, ok = block.DefRange, true
+		}
+		if ok {
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate program",
+				Detail:   fmt.Sprintf("Program %q was already defined at %s.", prog.Name, first),
+				Subject:  block.DefRange.Ptr(),
+			})
+			continue
+		}
+		blocks[prog.Name] = block
+		progs = append(progs, prog)
+	}
+	return progs, diags
+}
+
+// WriteDiagnostics writes the diagnostics with the source lines they
+// refer to
+func (r *Result) WriteDiagnostics(w io.Writer) error {
+	return hcl.NewDiagnosticTextWriter(w, r.parser.Files(), 78, false).WriteDiagnostics(r.Diags)
+}
+
+// Files returns the parsed files by name, for printing diagnostics
+func (r *Result) Files() map[string]*hcl.File {
+	return r.parser.Files()
+}
+
+// Range returns the source range of an attribute of a parsed program, or
+// of its block when the attribute isn't set
+func (r *Result) Range(name, attr string) *hcl.Range {
+	block, ok := r.blocks[name]
+	if !ok {
+		return nil
+	}
+	if body, ok := block.Body.(*hclsyntax.Body); ok {
+		if attribute, ok := body.Attributes[attr]; ok {
+			return attribute.SrcRange.Ptr()
+		}
+	}
+	return block.DefRange.Ptr()
+}
+
+// Sync stores the parsed programs, matching existing programs by name,
+// keeping whether they were disabled by an operator or an exit code.
+// Programs loaded from a file that no longer defines them are marked
+// orphaned rather than deleted, unless that file failed to parse.
+func (r *Result) Sync() (changes *Changes, err error) {
+	changes = &Changes{Fields: map[string][]string{}}
+	loaded := map[string]bool{}
+
+	for _, prog := range r.Programs {
+		loaded[prog.Name] = true
+
+		existing := &models.Program{}
+		err = db.DB.Where("name = ?", prog.Name).Limit(1).Find(existing).Error
+		if err != nil {
+			return changes, err
+		}
+		if existing.ID == 0 {
+			if err = db.DB.Create(prog).Error; err != nil {
+				return changes, fmt.Errorf("failed to create program %s: %w", prog.Name, err)
+			}
+			changes.Created = append(changes.Created, prog.Name)
+			continue
+		}
+
+		prog.ID = existing.ID
+		prog.Created = existing.Created
+		prog.Updated = existing.Updated
+		prog.State = existing.State
+		prog.Disabled = existing.Disabled
+		if reflect.DeepEqual(prog, existing) {
+			changes.Unchanged = append(changes.Unchanged, prog.Name)
+			continue
+		}
+		if err = db.DB.Save(prog).Error; err != nil {
+			return changes, fmt.Errorf("failed to update program %s: %w", prog.Name, err)
+		}
+		changes.Updated = append(changes.Updated, prog.Name)
+		changes.Fields[prog.Name] = Diff(existing, prog)
+	}
+
+	sourced := []*models.Program{}
+	if err = db.DB.Where("source <> '' AND orphaned = ?", false).Find(&sourced).Error; err != nil {
+		return changes, err
+	}
+	for _, prog := range sourced {
+		if loaded[prog.Name] || r.failed[prog.Source] {
+			continue
+		}
+		if err = db.DB.Model(prog).Update("orphaned", true).Error; err != nil {
+			return changes, fmt.Errorf("failed to orphan program %s: %w", prog.Name, err)
+		}
+		changes.Orphaned = append(changes.Orphaned, prog.Name)
+	}
+	return changes, nil
+}
+
+// Diff returns the HCL attributes and blocks that differ between two
+// definitions of a program
+func Diff(a, b *models.Program) (fields []string) {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for i := 0; i < va.NumField(); i++ {
+		name, _, _ := strings.Cut(va.Type().Field(i).Tag.Get("hcl"), ",")
+		if name == "" || name == "name" {
+			continue
+		}
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// Load parses a programs directory and syncs it into the database
+func Load(dir string) (*Result, *Changes, error) {
+	r := Parse(dir)
+	changes, err := r.Sync()
+	return r, changes, err
+}
@@ -0,0 +1,337 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package loader
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rangertaha/hxe/internal/db"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+func TestParseExample(t *testing.T) {
+	r := Parse(filepath.Join("..", "..", "..", "..", "configs"))
+	if r.Diags.HasErrors() {
+		t.Fatal(r.Diags.Error())
+	}
+	if len(r.Programs) == 0 {
+		t.Fatal("configs/programs.hcl defines no programs")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		summary string
+		loaded  []string
+	}{
+		{
+			name: "duplicate across files",
+			files: map[string]string{
+				"a.hcl": `program "web" { exec = "a" }`,
+				"b.hcl": `program "web" { exec = "b" }`,
+			},
+			summary: "Duplicate program",
+			loaded:  []string{"web"},
+		},
+		{
+			name: "broken file",
+			files: map[string]string{
+				"a.hcl": `program "web" { exec = "a" }`,
+				"b.hcl": `program "api" { exec = }`,
+			},
+			loaded: []string{"web"},
+		},
+		{
+			name:    "empty name",
+			files:   map[string]string{"a.hcl": `program "" { exec = "a" }`},
+			summary: "Missing program name",
+		},
+		{
+			name:    "invalid name",
+			files:   map[string]string{"a.hcl": `program "web.v2" { exec = "a" }`},
+			summary: "Invalid program name",
+		},
+		{
+			name: "invalid trigger pattern",
+			files: map[string]string{
+				"a.hcl": `program "web" {
+  exec = "a"
+  trigger "up" {
+    pattern = "listening on ("
+    action  = "ready"
+  }
+}`,
+				"b.hcl": `program "api" { exec = "b" }`,
+			},
+			summary: "Invalid trigger pattern",
+			loaded:  []string{"api"},
+		},
+		{
+			name:    "invalid restart policy",
+			files:   map[string]string{"a.hcl": `program "web" { restart = "sometimes" }`},
+			summary: "Invalid restart policy",
+		},
+		{
+			name:    "invalid reload signal",
+			files:   map[string]string{"a.hcl": `program "web" { reload_signal = "RELOAD" }`},
+			summary: "Invalid signal",
+		},
+		{
+			name:    "invalid no restart signal",
+			files:   map[string]string{"a.hcl": `program "web" { no_restart_on_signals = ["TERM", "NOPE"] }`},
+			summary: "Invalid signal",
+		},
+		{
+			name: "invalid trigger action",
+			files: map[string]string{
+				"a.hcl": `program "web" {
+  trigger "t" {
+    pattern = "x"
+    action  = "reboot"
+  }
+}`,
+			},
+			summary: "Invalid trigger action",
+		},
+		{
+			name: "invalid trigger stream",
+			files: map[string]string{
+				"a.hcl": `program "web" {
+  trigger "t" {
+    pattern = "x"
+    action  = "stop"
+    stream  = "stdin"
+  }
+}`,
+			},
+			summary: "Invalid trigger stream",
+		},
+		{
+			name: "invalid trigger signal",
+			files: map[string]string{
+				"a.hcl": `program "web" {
+  trigger "t" {
+    pattern = "x"
+    action  = "signal"
+    signal  = "SIGNOPE"
+  }
+}`,
+			},
+			summary: "Invalid trigger signal",
+		},
+		{
+			name: "trigger without command",
+			files: map[string]string{
+				"a.hcl": `program "web" {
+  trigger "t" {
+    pattern = "x"
+    action  = "exec"
+  }
+}`,
+			},
+			summary: "Missing trigger command",
+		},
+		{
+			name:    "unknown attribute",
+			files:   map[string]string{"a.hcl": `program "web" { command = "a" }`},
+			summary: "Unsupported argument",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				write(t, dir, name, content)
+			}
+			r := Parse(dir)
+			if !r.Diags.HasErrors() {
+				t.Fatal("no errors")
+			}
+			if tt.summary != "" && !strings.Contains(r.Diags.Error(), tt.summary) {
+				t.Errorf("errors = %s, want %s", r.Diags.Error(), tt.summary)
+			}
+			var loaded []string
+			for _, prog := range r.Programs {
+				loaded = append(loaded, prog.Name)
+			}
+			if !slices.Equal(loaded, tt.loaded) {
+				t.Errorf("loaded = %v, want %v", loaded, tt.loaded)
+			}
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	reset(t)
+	dir := t.TempDir()
+	write(t, dir, "web.hcl", `
+program "web" {
+  exec    = "nginx"
+  enabled = true
+}
+
+program "api" {
+  exec    = "api"
+  enabled = true
+}
+`)
+
+	_, changes, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changes.Created, []string{"web", "api"}) {
+		t.Errorf("created = %v, want [web api]", changes.Created)
+	}
+	if prog := program(t, "web"); prog.Source != filepath.Join(dir, "web.hcl") || !prog.IsEnabled() {
+		t.Errorf("web = %+v, want an enabled program of web.hcl", prog)
+	}
+
+	// Loading the same files again changes nothing
+	if _, changes, err = Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Created)+len(changes.Updated)+len(changes.Orphaned) > 0 {
+		t.Errorf("changes = %+v, want none", changes)
+	}
+
+	// A change is reported with the attributes that changed
+	write(t, dir, "web.hcl", `
+program "web" {
+  exec    = "nginx -g 'daemon off;'"
+  enabled = true
+}
+
+program "api" {
+  exec    = "api"
+  enabled = true
+}
+`)
+	if _, changes, err = Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changes.Updated, []string{"web"}) || !slices.Equal(changes.Fields["web"], []string{"exec"}) {
+		t.Errorf("updated = %v with %v, want [web] with [exec]", changes.Updated, changes.Fields)
+	}
+
+	// Programs no longer defined are orphaned, not deleted
+	write(t, dir, "web.hcl", `program "web" {
+  exec    = "nginx -g 'daemon off;'"
+  enabled = true
+}`)
+	if _, changes, err = Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changes.Orphaned, []string{"api"}) || !program(t, "api").Orphaned {
+		t.Errorf("orphaned = %v, want [api]", changes.Orphaned)
+	}
+
+	// The programs of a broken file are left as they are
+	write(t, dir, "web.hcl", `program "web" { exec = }`)
+	if _, changes, err = Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Orphaned) > 0 || program(t, "web").Orphaned {
+		t.Errorf("orphaned = %v, want none", changes.Orphaned)
+	}
+}
+
+func TestSyncKeepsDisabled(t *testing.T) {
+	reset(t)
+	dir := t.TempDir()
+	write(t, dir, "web.hcl", `program "web" {
+  exec    = "nginx"
+  enabled = true
+}`)
+	if _, _, err := Load(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// As hxe program disable, or an exit code in disable_exit_codes
+	if err := db.DB.Model(&models.Program{}).Where("name = ?", "web").Update("disabled", true).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	_, changes, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Updated) > 0 {
+		t.Errorf("updated = %v, want none", changes.Updated)
+	}
+	if program(t, "web").IsEnabled() {
+		t.Error("loading an unchanged file enabled a disabled program")
+	}
+
+	write(t, dir, "web.hcl", `program "web" {
+  exec    = "nginx -g 'daemon off;'"
+  enabled = true
+}`)
+	if _, _, err = Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	prog := program(t, "web")
+	if prog.IsEnabled() || prog.Exec != "nginx -g 'daemon off;'" {
+		t.Errorf("web = %q, enabled %v, want the changed program still disabled", prog.Exec, prog.IsEnabled())
+	}
+}
+
+func TestParseFilePaths(t *testing.T) {
+	dir, other := t.TempDir(), t.TempDir()
+	write(t, dir, "args.txt", "from dir")
+	write(t, other, "args.txt", "from other")
+	write(t, other, "env.tpl", "NAME=${name}")
+	write(t, dir, "a.hcl", `
+locals {
+  args = file("args.txt")
+}
+program "a" {
+  exec = "a"
+  args = [local.args, file("args.txt")]
+}`)
+	write(t, other, "b.hcl", `
+program "b" {
+  exec = "b"
+  args = [local.args, file("args.txt")]
+  env  = [templatefile("env.tpl", { name = "b" })]
+}`)
+
+	r := ParseFiles(dir, filepath.Join(dir, "a.hcl"), filepath.Join(other, "b.hcl"))
+	if r.Diags.HasErrors() {
+		t.Fatal(r.Diags.Error())
+	}
+	want := map[string][]string{
+		"a": {"from dir", "from dir"},
+		"b": {"from dir", "from other"},
+	}
+	for _, prog := range r.Programs {
+		if !slices.Equal(prog.Args, want[prog.Name]) {
+			t.Errorf("%s args = %q, want %q", prog.Name, prog.Args, want[prog.Name])
+		}
+	}
+	if b := r.Programs[1]; !slices.Equal(b.Env, []string{"NAME=b"}) {
+		t.Errorf("b env = %q, want NAME=b", b.Env)
+	}
+}
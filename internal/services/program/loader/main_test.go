@@ -0,0 +1,60 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rangertaha/hxe/internal/db"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+	"github.com/rangertaha/hxe/internal/testutil"
+)
+
+// TestMain runs the tests against a scratch database
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+// reset empties the programs table
+func reset(t *testing.T) {
+	t.Helper()
+	if err := db.DB.Where("1 = 1").Delete(&models.Program{}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// write writes a file of a programs directory
+func write(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// program reads a program from the database by name
+func program(t *testing.T, name string) *models.Program {
+	t.Helper()
+	prog := &models.Program{}
+	if err := db.DB.Where("name = ?", name).First(prog).Error; err != nil {
+		t.Fatalf("program %s: %v", name, err)
+	}
+	return prog
+}
@@ -0,0 +1,423 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package loader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rangertaha/hxe/internal/config"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// VAR_PREFIX prefixes the environment variables that set variables, for
+// example HXE_VAR_region sets var.region
+const VAR_PREFIX = "HXE_VAR_"
+
+var variableSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "default"},
+		{Name: "description"},
+	},
+}
+
+var extendsSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "extends"},
+	},
+}
+
+// template is a template block, resolved on first use
+type template struct {
+	name     string
+	file     string
+	block    *hcl.Block
+	prog     *models.Program
+	diags    hcl.Diagnostics
+	resolved bool
+	visiting bool
+}
+
+// scope collects the variables, locals and templates of all files into
+// the context programs are decoded with
+func (r *Result) scope(files []string, contents map[string]*hcl.BodyContent) {
+	vars := map[string]cty.Value{}
+	locals := map[string]*hcl.Attribute{}
+	localFiles := map[string]string{}
+	defined := map[string]hcl.Range{}
+
+	duplicate := func(kind, name string, block *hcl.Block) *hcl.Diagnostic {
+		key := kind + "." + name
+		if first, ok := defined[key]; ok {
+			return &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate " + kind,
+				Detail:   fmt.Sprintf("The %s %q was already defined at %s.", kind, name, first),
+				Subject:  block.DefRange.Ptr(),
+			}
+		}
+		defined[key] = block.DefRange
+		return nil
+	}
+
+	for _, filename := range files {
+		content, ok := contents[filename]
+		if !ok {
+			continue
+		}
+		for _, block := range content.Blocks {
+			switch block.Type {
+			case "variable":
+				name := block.Labels[0]
+				if diag := duplicate("variable", name, block); diag != nil {
+					r.fail(filename, hcl.Diagnostics{diag})
+					continue
+				}
+				value, diags := variable(name, block)
+				r.fail(filename, diags)
+				vars[name] = value
+			case "locals":
+				attrs, diags := block.Body.JustAttributes()
+				for name, attr := range attrs {
+					if first, ok := locals[name]; ok {
+						diags = diags.Append(&hcl.Diagnostic{
+							Severity: hcl.DiagError,
+							Summary:  "Duplicate local value",
+							Detail:   fmt.Sprintf("The local value %q was already defined at %s.", name, first.NameRange),
+							Subject:  attr.NameRange.Ptr(),
+						})
+						continue
+					}
+					locals[name] = attr
+					localFiles[name] = filename
+				}
+				r.fail(filename, diags)
+			case "template":
+				name := block.Labels[0]
+				if diag := duplicate("template", name, block); diag != nil {
+					r.fail(filename, hcl.Diagnostics{diag})
+					continue
+				}
+				r.templates[name] = &template{name: name, file: filename, block: block}
+			}
+		}
+	}
+
+	r.ctx = &hcl.EvalContext{
+		Functions: config.CtxFunctions.Functions,
+		Variables: map[string]cty.Value{
+			"var":   cty.ObjectVal(vars),
+			"local": cty.EmptyObjectVal,
+		},
+	}
+	r.locals(locals, localFiles)
+
+	names := []string{}
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		r.resolve(r.templates[name])
+	}
+}
+
+// variable returns the value of a variable block, from the environment
+// or its default
+func variable(name string, block *hcl.Block) (cty.Value, hcl.Diagnostics) {
+	content, diags := block.Body.Content(variableSchema)
+	if diags.HasErrors() {
+		return cty.DynamicVal, diags
+	}
+
+	value := cty.NullVal(cty.DynamicPseudoType)
+	if attr, ok := content.Attributes["default"]; ok {
+		var moreDiags hcl.Diagnostics
+		value, moreDiags = attr.Expr.Value(config.FileContext(block.DefRange.Filename))
+		diags = diags.Extend(moreDiags)
+	}
+
+	env, ok := os.LookupEnv(VAR_PREFIX + name)
+	if !ok {
+		if value.IsNull() {
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "No value for variable",
+				Detail:   fmt.Sprintf("Variable %q has no default, set it with %s%s.", name, VAR_PREFIX, name),
+				Subject:  block.DefRange.Ptr(),
+			})
+			return cty.DynamicVal, diags
+		}
+		return value, diags
+	}
+
+	if value.IsNull() {
+		return cty.StringVal(env), diags
+	}
+	converted, err := convert.Convert(cty.StringVal(env), value.Type())
+	if err != nil {
+		return cty.DynamicVal, diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid value for variable",
+			Detail:   fmt.Sprintf("%s%s: %s.", VAR_PREFIX, name, err),
+			Subject:  block.DefRange.Ptr(),
+		})
+	}
+	return converted, diags
+}
+
+// locals evaluates the local values in the order they refer to each other
+func (r *Result) locals(attrs map[string]*hcl.Attribute, files map[string]string) {
+	values := map[string]cty.Value{}
+	for len(attrs) > 0 {
+		progress := false
+		for name, attr := range attrs {
+			if !resolvable(attr.Expr, values) {
+				continue
+			}
+			value, diags := attr.Expr.Value(r.eval(files[name]))
+			r.fail(files[name], diags)
+			values[name] = value
+			r.ctx.Variables["local"] = cty.ObjectVal(values)
+			delete(attrs, name)
+			progress = true
+		}
+		if progress {
+			continue
+		}
+
+		names := []string{}
+		for name := range attrs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			attr := attrs[name]
+			r.fail(files[name], hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Unresolvable local value",
+				Detail:   fmt.Sprintf("The local value %q refers to local values that are undefined or refer back to it.", name),
+				Subject:  attr.Expr.Range().Ptr(),
+			}})
+		}
+		return
+	}
+}
+
+// resolvable reports whether the local values an expression refers to
+// have all been evaluated
+func resolvable(expr hcl.Expression, values map[string]cty.Value) bool {
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() != "local" || len(traversal) < 2 {
+			continue
+		}
+		attr, ok := traversal[1].(hcl.TraverseAttr)
+		if !ok {
+			continue
+		}
+		if _, ok := values[attr.Name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// resolve decodes a template and the templates it extends
+func (r *Result) resolve(t *template) (*models.Program, hcl.Diagnostics) {
+	if t.resolved {
+		return t.prog, t.diags
+	}
+	if t.visiting {
+		diags := hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Template cycle",
+			Detail:   fmt.Sprintf("Template %q extends itself through the templates it extends.", t.name),
+			Subject:  t.block.DefRange.Ptr(),
+		}}
+		r.fail(t.file, diags)
+		return nil, diags
+	}
+
+	t.visiting = true
+	t.prog, t.diags = r.decode(t.block.Body)
+	t.visiting, t.resolved = false, true
+	r.fail(t.file, t.diags)
+	return t.prog, t.diags
+}
+
+// decode decodes a program or template body, merged onto the template it
+// extends
+func (r *Result) decode(body hcl.Body) (*models.Program, hcl.Diagnostics) {
+	content, remain, diags := body.PartialContent(extendsSchema)
+
+	ctx := r.eval(body.MissingItemRange().Filename)
+	prog := &models.Program{}
+	diags = diags.Extend(gohcl.DecodeBody(remain, ctx, prog))
+
+	attr, ok := content.Attributes["extends"]
+	if !ok {
+		return prog, diags
+	}
+
+	var name string
+	if moreDiags := gohcl.DecodeExpression(attr.Expr, ctx, &name); moreDiags.HasErrors() {
+		return prog, diags.Extend(moreDiags)
+	}
+	t, ok := r.templates[name]
+	if !ok {
+		return prog, diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unknown template",
+			Detail:   fmt.Sprintf("There is no template named %q.", name),
+			Subject:  attr.Expr.Range().Ptr(),
+		})
+	}
+	base, baseDiags := r.resolve(t)
+	if baseDiags.HasErrors() {
+		return prog, diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid template",
+			Detail:   fmt.Sprintf("Template %q has errors.", name),
+			Subject:  attr.Expr.Range().Ptr(),
+		})
+	}
+	return merge(base, prog, set(body)), diags
+}
+
+// eval returns the context to evaluate the expressions of a file in, with
+// the variables and local values of the directory and file and
+// templatefile reading paths relative to the file
+func (r *Result) eval(filename string) *hcl.EvalContext {
+	ctx := r.ctx.NewChild()
+	ctx.Functions = config.Functions(filepath.Dir(filename))
+	return ctx
+}
+
+// set returns the attributes and blocks a body sets
+func set(body hcl.Body) map[string]bool {
+	names := map[string]bool{}
+	if body, ok := body.(*hclsyntax.Body); ok {
+		for name := range body.Attributes {
+			names[name] = true
+		}
+		for _, block := range body.Blocks {
+			names[block.Type] = true
+		}
+		return names
+	}
+
+	// JSON bodies can't tell attributes from blocks without a schema,
+	// every property is read as an attribute
+	attrs, _ := body.JustAttributes()
+	for name := range attrs {
+		names[name] = true
+	}
+	return names
+}
+
+// merge returns base with the fields child sets. Env and labels are
+// merged key by key and triggers by name, everything else is replaced.
+// Limits are separate attributes, so replacing one keeps the others.
+func merge(base, child *models.Program, fields map[string]bool) *models.Program {
+	merged := *base
+	merged.Labels = mergeLabels(base.Labels, nil)
+	merged.Env = mergeEnv(base.Env, nil)
+
+	vm, vc := reflect.ValueOf(&merged).Elem(), reflect.ValueOf(child).Elem()
+	for i := 0; i < vm.NumField(); i++ {
+		name, _, _ := strings.Cut(vm.Type().Field(i).Tag.Get("hcl"), ",")
+		if name == "" || !fields[name] {
+			continue
+		}
+		switch name {
+		case "labels":
+			merged.Labels = mergeLabels(merged.Labels, child.Labels)
+		case "env":
+			merged.Env = mergeEnv(merged.Env, child.Env)
+		case "trigger":
+			merged.Triggers = mergeTriggers(merged.Triggers, child.Triggers)
+		default:
+			vm.Field(i).Set(vc.Field(i))
+		}
+	}
+	return &merged
+}
+
+func mergeLabels(base, child map[string]string) map[string]string {
+	if base == nil && child == nil {
+		return nil
+	}
+	labels := map[string]string{}
+	for k, v := range base {
+		labels[k] = v
+	}
+	for k, v := range child {
+		labels[k] = v
+	}
+	return labels
+}
+
+// mergeEnv merges KEY=VALUE lists, keeping the order of base and
+// appending the keys only child has
+func mergeEnv(base, child []string) []string {
+	if base == nil && child == nil {
+		return nil
+	}
+	env := append([]string{}, base...)
+	index := map[string]int{}
+	for i, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		index[key] = i
+	}
+	for _, kv := range child {
+		key, _, _ := strings.Cut(kv, "=")
+		if i, ok := index[key]; ok {
+			env[i] = kv
+			continue
+		}
+		index[key] = len(env)
+		env = append(env, kv)
+	}
+	return env
+}
+
+func mergeTriggers(base, child []models.Trigger) []models.Trigger {
+	triggers := append([]models.Trigger(nil), base...)
+	for _, trigger := range child {
+		replaced := false
+		for i := range triggers {
+			if triggers[i].Name == trigger.Name {
+				triggers[i], replaced = trigger, true
+			}
+		}
+		if !replaced {
+			triggers = append(triggers, trigger)
+		}
+	}
+	return triggers
+}
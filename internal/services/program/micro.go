@@ -20,14 +20,19 @@ package program
 
 import (
 	"encoding/json"
+	"fmt"
+	"sync"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/micro"
 	"github.com/rangertaha/hxe/internal"
+	"github.com/rangertaha/hxe/internal/auth"
 	"github.com/rangertaha/hxe/internal/db"
 	"github.com/rangertaha/hxe/internal/log"
+	"github.com/rangertaha/hxe/internal/services"
 	pc "github.com/rangertaha/hxe/internal/services/program/client"
 	"github.com/rangertaha/hxe/internal/services/program/models"
+	"github.com/rangertaha/hxe/internal/services/program/supervisor"
 	"github.com/rs/zerolog"
 )
 
@@ -37,10 +42,17 @@ type Crud interface {
 
 type Microservice struct {
 	service micro.Service
+	agent   string
+	auth    *auth.Store
+	nc      *nats.Conn
+	sup     *supervisor.Supervisor
 	log     zerolog.Logger
+
+	// applyMu serializes applying plans
+	applyMu sync.Mutex
 }
 
-func NewMicroservice(nc *nats.Conn) *Microservice {
+func NewMicroservice(nc *nats.Conn, sup *supervisor.Supervisor, agent string, store *auth.Store) *Microservice {
 
 	svc, err := micro.AddService(nc, micro.Config{
 		Name:        "programs",
@@ -54,24 +66,44 @@ func NewMicroservice(nc *nats.Conn) *Microservice {
 
 	return &Microservice{
 		service: svc,
+		agent:   agent,
+		auth:    store,
+		nc:      nc,
+		sup:     sup,
 		log:     log.With().Str("service", "program").Logger(),
 	}
 }
 
+// Init registers the endpoints under program and under the program
+// group namespaced by the agent ID
 func (s *Microservice) Init() (err error) {
-	svc := s.service.AddGroup("program")
-	// svc.AddEndpoint("load", JSONHandler(e.Load))
-	svc.AddEndpoint("list", JSONHandler(s.List))
-	svc.AddEndpoint("get", JSONHandler(s.Get))
-	svc.AddEndpoint("create", JSONHandler(s.Create))
-	svc.AddEndpoint("update", JSONHandler(s.Update))
-	svc.AddEndpoint("delete", JSONHandler(s.Delete))
-	// svc.AddEndpoint("start", JSONHandler(e.Start))
-	// svc.AddEndpoint("stop", JSONHandler(e.Stop))
-	// svc.AddEndpoint("restart", JSONHandler(e.Restart))
-	// svc.AddEndpoint("status", JSONHandler(e.Status))
-	// svc.AddEndpoint("log", JSONHandler(e.Log))
-	// svc.AddEndpoint("shell", JSONHandler(e.Shell))
+	for _, group := range services.Groups(s.agent, "program") {
+		svc := s.service.AddGroup(group)
+		// svc.AddEndpoint("load", JSONHandler(e.Load))
+		svc.AddEndpoint("list", JSONHandler(s.List))
+		svc.AddEndpoint("get", JSONHandler(s.Get))
+		svc.AddEndpoint("create", JSONHandler(s.Create))
+		svc.AddEndpoint("update", JSONHandler(s.Update))
+		svc.AddEndpoint("delete", JSONHandler(s.Delete))
+		svc.AddEndpoint("start", JSONHandler(s.Start))
+		svc.AddEndpoint("stop", JSONHandler(s.Stop))
+		svc.AddEndpoint("restart", JSONHandler(s.Restart))
+		svc.AddEndpoint("signal", JSONHandler(s.Signal))
+		svc.AddEndpoint("reload", JSONHandler(s.Reload))
+		svc.AddEndpoint("enable", JSONHandler(s.Enable))
+		svc.AddEndpoint("disable", JSONHandler(s.Disable))
+		svc.AddEndpoint("status", JSONHandler(s.Status))
+		svc.AddEndpoint("history", JSONHandler(s.History))
+		svc.AddEndpoint("runs", JSONHandler(s.Runs))
+		svc.AddEndpoint("run", s.callerHandler(s.Run))
+		svc.AddEndpoint("cancel", s.callerHandler(s.Cancel))
+		svc.AddEndpoint("ps", JSONHandler(s.Ps))
+		svc.AddEndpoint("crashes", JSONHandler(s.Crashes))
+		svc.AddEndpoint("plan", JSONHandler(s.Plan))
+		svc.AddEndpoint("apply", JSONHandler(s.Apply))
+		// svc.AddEndpoint("log", JSONHandler(e.Log))
+		// svc.AddEndpoint("shell", JSONHandler(e.Shell))
+	}
 
 	return models.AutoMigrate()
 }
@@ -82,8 +114,16 @@ func (s *Microservice) Init() (err error) {
 // 	return
 // }
 
-// List all services
+// List all services, or those matching a selector
 func (s *Microservice) List(req *pc.Request) (res *pc.Response) {
+	if req.Selector != "" {
+		progs, err := models.Select(req.Selector)
+		if err != nil {
+			return &pc.Response{Error: err.Error()}
+		}
+		return &pc.Response{Programs: progs}
+	}
+
 	progs := []*models.Program{}
 	db.DB.Find(&progs)
 	return &pc.Response{Programs: progs}
@@ -98,20 +138,346 @@ func (s *Microservice) Get(req *pc.Request) (res *pc.Response) {
 
 // Create a new service
 func (s *Microservice) Create(req *pc.Request) (res *pc.Response) {
-	db.DB.Create(req.Program)
+	if req.Program == nil {
+		return &pc.Response{Error: "no program given"}
+	}
+	if err := models.ValidateName(req.Program.Name); err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+	if err := db.DB.Create(req.Program).Error; err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
 	return &pc.Response{Programs: []*models.Program{req.Program}}
 }
 
-// Update a service
+// Update the definition of a program. Whether it is disabled, its state
+// and the file it was loaded from are kept, since the supervisor and the
+// loader own them, and its process picks up the definition on its next
+// start.
 func (s *Microservice) Update(req *pc.Request) (res *pc.Response) {
-	db.DB.Save(req.Program)
-	return &pc.Response{Programs: []*models.Program{req.Program}}
+	existing, err := s.find(req)
+	if err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+	if err := models.ValidateName(req.Program.Name); err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+
+	prog := req.Program
+	prog.ID = existing.ID
+	prog.Created = existing.Created
+	prog.State = existing.State
+	prog.Disabled = existing.Disabled
+	prog.Source = existing.Source
+	prog.Orphaned = existing.Orphaned
+	if err := db.DB.Save(prog).Error; err != nil {
+		return &pc.Response{Error: fmt.Sprintf("failed to update program %s: %v", prog.Ref(), err)}
+	}
+	s.sup.Update(prog)
+	return &pc.Response{Programs: []*models.Program{prog}}
 }
 
-// Delete a service
+// Delete programs, stopping them first
 func (s *Microservice) Delete(req *pc.Request) (res *pc.Response) {
-	db.DB.Delete(req.Program)
-	return &pc.Response{Programs: []*models.Program{req.Program}}
+	return s.bulk(req, func(prog *models.Program) (*models.Status, error) {
+		status, err := s.sup.Remove(prog)
+		if err != nil {
+			return status, err
+		}
+		return status, db.DB.Delete(prog).Error
+	})
+}
+
+// Start programs
+func (s *Microservice) Start(req *pc.Request) (res *pc.Response) {
+	return s.bulk(req, s.action(s.sup.Start))
+}
+
+// Stop programs
+func (s *Microservice) Stop(req *pc.Request) (res *pc.Response) {
+	return s.bulk(req, s.action(s.sup.Stop))
+}
+
+// Signal sends a signal to programs, or to their process groups
+func (s *Microservice) Signal(req *pc.Request) (res *pc.Response) {
+	sig, err := supervisor.ParseSignal(req.Signal)
+	if err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+	return s.bulk(req, s.action(func(prog *models.Program) (*supervisor.Process, error) {
+		return s.sup.Signal(prog, sig, req.Group)
+	}))
+}
+
+// Reload programs by sending them their reload signal
+func (s *Microservice) Reload(req *pc.Request) (res *pc.Response) {
+	return s.bulk(req, s.action(s.sup.Reload))
+}
+
+// Enable programs to start automatically
+func (s *Microservice) Enable(req *pc.Request) (res *pc.Response) {
+	return s.bulk(req, s.enable(true))
+}
+
+// Disable programs from starting automatically
+func (s *Microservice) Disable(req *pc.Request) (res *pc.Response) {
+	return s.bulk(req, s.enable(false))
+}
+
+// Restart programs, or roll a restart over the programs matching a
+// selector when a rollout is given
+func (s *Microservice) Restart(req *pc.Request) (res *pc.Response) {
+	if req.Selector == "" || req.Rollout == nil {
+		return s.bulk(req, s.action(s.sup.Restart))
+	}
+
+	progs, err := s.targets(req)
+	if err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+	rollout := supervisor.Rollout(*req.Rollout)
+
+	res = &pc.Response{Programs: progs}
+	procs, err := s.sup.RollingRestart(progs, rollout)
+	if err != nil {
+		res.Error = err.Error()
+	}
+	for _, proc := range procs {
+		res.Statuses = append(res.Statuses, proc.Snapshot())
+	}
+	return res
+}
+
+// Status of a program
+func (s *Microservice) Status(req *pc.Request) (res *pc.Response) {
+	prog, err := s.find(req)
+	if err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+	return &pc.Response{
+		Programs: []*models.Program{prog},
+		Statuses: []*models.Status{s.sup.Status(prog)},
+	}
+}
+
+// History of a program's state transitions
+func (s *Microservice) History(req *pc.Request) (res *pc.Response) {
+	prog, err := s.find(req)
+	if err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+
+	history, err := models.History(prog.ID, req.Limit)
+	if err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+	return &pc.Response{Programs: []*models.Program{prog}, History: history}
+}
+
+// Runs of a program, with their exit status and restart decision, or the
+// ad-hoc runs made outside of any program when none is given
+func (s *Microservice) Runs(req *pc.Request) (res *pc.Response) {
+	if req.Program == nil {
+		runs, err := models.Runs(0, req.Limit)
+		if err != nil {
+			return &pc.Response{Error: err.Error()}
+		}
+		return &pc.Response{Runs: runs}
+	}
+
+	prog, err := s.find(req)
+	if err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+
+	runs, err := models.Runs(prog.ID, req.Limit)
+	if err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+	return &pc.Response{Programs: []*models.Program{prog}, Runs: runs}
+}
+
+// Crashes returns the crash reports of a program, newest first
+func (s *Microservice) Crashes(req *pc.Request) (res *pc.Response) {
+	prog, err := s.find(req)
+	if err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+
+	crashes, err := models.Crashes(prog.ID, req.Limit)
+	if err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+	return &pc.Response{Programs: []*models.Program{prog}, Crashes: crashes}
+}
+
+// Ps returns the process tree of a program
+func (s *Microservice) Ps(req *pc.Request) (res *pc.Response) {
+	prog, err := s.find(req)
+	if err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+
+	procs, err := s.sup.Tree(prog)
+	if err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+	return &pc.Response{Programs: []*models.Program{prog}, Processes: procs}
+}
+
+// Run executes an ad-hoc command once, in the context of a program when
+// one is given. The output and the finished run are published to the
+// request's output subject, and the started run is returned right away.
+func (s *Microservice) Run(req *pc.Request) (res *pc.Response) {
+	prog := &models.Program{}
+	if req.Program != nil {
+		var err error
+		if prog, err = s.find(req); err != nil {
+			return &pc.Response{Error: err.Error()}
+		}
+	}
+	if req.Output == "" {
+		return &pc.Response{Error: "no output subject given"}
+	}
+	if req.MaxRuntime > 0 {
+		prog.MaxRuntime = req.MaxRuntime
+	}
+
+	publish := func(out *pc.Output) {
+		data, err := json.Marshal(out)
+		if err != nil {
+			s.log.Error().Err(err).Msg("failed to marshal run output")
+			return
+		}
+		if err := s.nc.Publish(req.Output, data); err != nil {
+			s.log.Error().Err(err).Str("subject", req.Output).Msg("failed to publish run output")
+		}
+	}
+
+	run, done, err := s.sup.Exec(prog, req.Command, req.Caller, func(stream, line string) {
+		publish(&pc.Output{Stream: stream, Line: line})
+	})
+	if err != nil {
+		return &pc.Response{Error: fmt.Sprintf("failed to run command: %v", err)}
+	}
+	go func() {
+		publish(&pc.Output{Run: <-done})
+	}()
+	return &pc.Response{Runs: []*models.Run{run}}
+}
+
+// Cancel stops an ad-hoc run that is still going, if the caller started it
+func (s *Microservice) Cancel(req *pc.Request) (res *pc.Response) {
+	if err := s.sup.Cancel(req.RunID, req.Caller); err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+	return &pc.Response{}
+}
+
+// bulk applies an action to every program a request targets, reporting
+// the outcome for each of them
+func (s *Microservice) bulk(req *pc.Request, action func(*models.Program) (*models.Status, error)) (res *pc.Response) {
+	progs, err := s.targets(req)
+	if err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+
+	res = &pc.Response{
+		Programs: progs,
+		Statuses: make([]*models.Status, len(progs)),
+		Results:  make([]*pc.Result, len(progs)),
+	}
+
+	var wg sync.WaitGroup
+	for i, prog := range progs {
+		wg.Add(1)
+		go func(i int, prog *models.Program) {
+			defer wg.Done()
+
+			status, err := action(prog)
+			res.Statuses[i] = status
+			res.Results[i] = &pc.Result{ProgramID: prog.ID, Name: prog.Name, Ok: err == nil}
+			if err != nil {
+				res.Results[i].Error = err.Error()
+			}
+		}(i, prog)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, result := range res.Results {
+		if !result.Ok {
+			failed++
+			res.Error = result.Error
+		}
+	}
+	if failed > 0 && req.Selector != "" {
+		res.Error = fmt.Sprintf("%d of %d programs failed", failed, len(progs))
+	}
+	return res
+}
+
+// action adapts a supervisor action to a bulk action
+func (s *Microservice) action(fn func(*models.Program) (*supervisor.Process, error)) func(*models.Program) (*models.Status, error) {
+	return func(prog *models.Program) (*models.Status, error) {
+		proc, err := fn(prog)
+		return proc.Snapshot(), err
+	}
+}
+
+// enable returns a bulk action that enables programs, or disables them
+// until they are enabled again. Programs that are not enabled in their
+// file are disabled again when it is next loaded.
+func (s *Microservice) enable(enabled bool) func(*models.Program) (*models.Status, error) {
+	return func(prog *models.Program) (*models.Status, error) {
+		prog.Disabled = !enabled
+		updates := map[string]any{"disabled": prog.Disabled}
+		if enabled {
+			prog.Enabled = true
+			updates["enabled"] = true
+		}
+		err := db.DB.Model(prog).Updates(updates).Error
+		return s.sup.Status(prog), err
+	}
+}
+
+// targets resolves the programs a request applies to: those matching its
+// selector, or the single program it names
+func (s *Microservice) targets(req *pc.Request) ([]*models.Program, error) {
+	if req.Selector == "" {
+		prog, err := s.find(req)
+		if err != nil {
+			return nil, err
+		}
+		return []*models.Program{prog}, nil
+	}
+
+	progs, err := models.Select(req.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(progs) == 0 {
+		return nil, fmt.Errorf("no programs match selector %s", req.Selector)
+	}
+	return progs, nil
+}
+
+// find looks up the requested program by ID, or by name
+func (s *Microservice) find(req *pc.Request) (prog *models.Program, err error) {
+	if req.Program == nil {
+		return nil, fmt.Errorf("no program given")
+	}
+
+	prog = &models.Program{}
+	if req.Program.ID != 0 {
+		err = db.DB.First(prog, "id = ?", req.Program.ID).Error
+	} else {
+		err = db.DB.First(prog, "name = ?", req.Program.Name).Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("program %s not found", req.Program.Ref())
+	}
+	return prog, nil
 }
 
 // // Start a service
@@ -157,6 +523,25 @@ func (s *Microservice) Delete(req *pc.Request) (res *pc.Response) {
 // 	return
 // }
 
+// callerHandler is a JSONHandler that sets the caller of requests to the
+// user that signed them. Without decentralized authentication callers
+// can't be told apart and are anonymous.
+func (s *Microservice) callerHandler(handler func(*pc.Request) *pc.Response) micro.HandlerFunc {
+	return func(msg micro.Request) {
+		JSONHandler(func(req *pc.Request) *pc.Response {
+			req.Caller = models.ANONYMOUS
+			if s.auth != nil {
+				caller, err := s.auth.Verify(nats.Header(msg.Headers()), msg.Subject(), msg.Data())
+				if err != nil {
+					return &pc.Response{Error: err.Error()}
+				}
+				req.Caller = caller
+			}
+			return handler(req)
+		})(msg)
+	}
+}
+
 // JSONHandler wraps a handler function with automatic marshaling/unmarshaling
 func JSONHandler(handler func(*pc.Request) *pc.Response) micro.HandlerFunc {
 	return func(msg micro.Request) {
@@ -0,0 +1,192 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package program
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rangertaha/hxe/internal/db"
+	pc "github.com/rangertaha/hxe/internal/services/program/client"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+func TestUpdate(t *testing.T) {
+	web := &models.Program{Name: "web", Exec: "web", Disabled: true, Source: "/etc/hxe/programs/web.hcl", State: models.FATAL}
+	s := newPlanner(t, web)
+	proc := s.sup.Process(web)
+
+	res := s.Update(&pc.Request{Program: &models.Program{ID: web.ID, Name: "www", Exec: "web --v2", Orphaned: true, State: models.READY}})
+	if res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	got := &models.Program{}
+	if err := db.DB.First(got, web.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "www" || got.Exec != "web --v2" {
+		t.Errorf("program = %s %q, want www %q", got.Name, got.Exec, "web --v2")
+	}
+	if !got.Disabled || got.Source != web.Source || got.Orphaned || got.State != models.FATAL {
+		t.Errorf("update changed disabled %t, source %q, orphaned %t or state %s", got.Disabled, got.Source, got.Orphaned, got.State)
+	}
+	if name := proc.Name(); name != "www" {
+		t.Errorf("process is %s, want the updated definition www", name)
+	}
+
+	tests := []struct {
+		name string
+		prog *models.Program
+	}{
+		{name: "no program"},
+		{name: "unknown program", prog: &models.Program{ID: web.ID + 100, Name: "api"}},
+		{name: "invalid name", prog: &models.Program{ID: web.ID, Name: "web.v2"}},
+	}
+	for _, tt := range tests {
+		if res := s.Update(&pc.Request{Program: tt.prog}); res.Error == "" {
+			t.Errorf("%s: update succeeded", tt.name)
+		}
+	}
+}
+
+// trapping returns a program that records the signals it receives in a
+// file, which holds "ready" once its traps are set
+func trapping(dir, name, team string) *models.Program {
+	file := filepath.Join(dir, name)
+	return &models.Program{
+		Name:   name,
+		Labels: map[string]string{"team": team},
+		Exec: fmt.Sprintf("for sig in HUP USR1 USR2; do trap \"echo $sig >> %[1]s\" $sig; done; "+
+			"echo ready > %[1]s; while :; do sleep 0.1; done", file),
+		Restart: models.RESTART_NO,
+	}
+}
+
+// received waits for a program to record the given signals, clearing
+// what it recorded
+func received(t *testing.T, dir, name string, want []string) {
+	t.Helper()
+	file := filepath.Join(dir, name)
+	// Signals that should not arrive get a moment to show up anyway
+	wait := 2 * time.Second
+	if len(want) == 0 {
+		wait = 100 * time.Millisecond
+	}
+	var got []string
+	for deadline := time.Now().Add(wait); ; time.Sleep(20 * time.Millisecond) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = strings.Fields(string(data))
+		if (len(want) > 0 && slices.Equal(got, want)) || time.Now().After(deadline) {
+			break
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("%s received %q, want %q", name, got, want)
+	}
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startTrapping starts programs made by trapping and waits for their traps
+func startTrapping(t *testing.T, s *Microservice, dir string, progs ...*models.Program) {
+	t.Helper()
+	for _, prog := range progs {
+		if _, err := s.sup.Start(prog); err != nil {
+			t.Fatal(err)
+		}
+		received(t, dir, prog.Name, []string{"ready"})
+	}
+}
+
+func TestSignal(t *testing.T) {
+	dir := t.TempDir()
+	web, api, idle := trapping(dir, "web", "web"), trapping(dir, "api", "web"), trapping(dir, "idle", "ops")
+	s := newPlanner(t, web, api, idle)
+	defer s.sup.StopAll()
+	startTrapping(t, s, dir, web, api)
+
+	tests := []struct {
+		name     string
+		req      *pc.Request
+		received map[string][]string
+		err      bool
+	}{
+		{"name", &pc.Request{Program: &models.Program{Name: "web"}, Signal: "USR1"}, map[string][]string{"web": {"USR1"}}, false},
+		{"prefixed name", &pc.Request{Program: &models.Program{Name: "api"}, Signal: "SIGUSR2"}, map[string][]string{"api": {"USR2"}}, false},
+		{"number", &pc.Request{Program: &models.Program{Name: "web"}, Signal: "1"}, map[string][]string{"web": {"HUP"}}, false},
+		{"process group", &pc.Request{Program: &models.Program{Name: "api"}, Signal: "usr1", Group: true}, map[string][]string{"api": {"USR1"}}, false},
+		{"selector", &pc.Request{Selector: "team=web", Signal: "HUP"}, map[string][]string{"web": {"HUP"}, "api": {"HUP"}}, false},
+		{"not running", &pc.Request{Program: &models.Program{Name: "idle"}, Signal: "HUP"}, nil, true},
+		{"unknown signal", &pc.Request{Program: &models.Program{Name: "web"}, Signal: "BOGUS"}, nil, true},
+		{"unknown program", &pc.Request{Program: &models.Program{Name: "db"}, Signal: "HUP"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := s.Signal(tt.req)
+			if (res.Error != "") != tt.err {
+				t.Errorf("signal error = %q, want error %v", res.Error, tt.err)
+			}
+			for _, name := range []string{"web", "api"} {
+				received(t, dir, name, tt.received[name])
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	web, api, worker, idle := trapping(dir, "web", "web"), trapping(dir, "api", "web"), trapping(dir, "worker", "web"), trapping(dir, "idle", "ops")
+	api.ReloadSignal = "USR2"
+	worker.ReloadSignal = "NOPE"
+	s := newPlanner(t, web, api, worker, idle)
+	defer s.sup.StopAll()
+	startTrapping(t, s, dir, web, api, worker)
+
+	tests := []struct {
+		name     string
+		prog     string
+		received []string
+		err      bool
+	}{
+		{"hangup by default", "web", []string{"HUP"}, false},
+		{"reload signal", "api", []string{"USR2"}, false},
+		{"invalid reload signal", "worker", nil, true},
+		{"not running", "idle", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := s.Reload(&pc.Request{Program: &models.Program{Name: tt.prog}})
+			if (res.Error != "") != tt.err {
+				t.Errorf("reload error = %q, want error %v", res.Error, tt.err)
+			}
+			if tt.prog != "idle" {
+				received(t, dir, tt.prog, tt.received)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import (
+	"time"
+
+	"github.com/rangertaha/hxe/internal/db"
+)
+
+// Crash is a report of a program that died from a crash signal
+type Crash struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	ProgramID uint   `json:"program" gorm:"column:program_id;index"`
+	RunID     uint   `json:"run" gorm:"column:run_id"`
+	Pid       int    `json:"pid" gorm:"column:pid"`
+	Signal    string `json:"signal" gorm:"column:signal"`
+	Timestamp int64  `json:"timestamp" gorm:"column:timestamp;autoCreateTime:milli"`
+
+	// Binary that crashed and its sha256
+	Binary       string `json:"binary" gorm:"column:binary"`
+	BinarySHA256 string `json:"binarySha256" gorm:"column:binary_sha256"`
+
+	// Environment fingerprint
+	Hostname string `json:"hostname" gorm:"column:hostname"`
+	Kernel   string `json:"kernel" gorm:"column:kernel"`
+	Arch     string `json:"arch" gorm:"column:arch"`
+	Version  string `json:"version" gorm:"column:version"`
+	EnvHash  string `json:"envHash" gorm:"column:env_hash"`
+
+	// Last lines of output before the crash
+	Stdout []string `json:"stdout" gorm:"column:stdout;serializer:json"`
+	Stderr []string `json:"stderr" gorm:"column:stderr;serializer:json"`
+
+	// Core file collected for the crash, if the kernel wrote one
+	CoreFile string `json:"coreFile" gorm:"column:core_file"`
+	CoreSize int64  `json:"coreSize" gorm:"column:core_size"`
+
+	// Message explains what could not be collected
+	Message string `json:"message" gorm:"column:message"`
+}
+
+// Time returns when the crash happened
+func (c *Crash) Time() time.Time {
+	return time.UnixMilli(c.Timestamp)
+}
+
+// Crashes returns the most recent crash reports of a program, newest first
+func Crashes(programID uint, limit int) (crashes []*Crash, err error) {
+	query := db.DB.Where("program_id = ?", programID).Order("id desc")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err = query.Find(&crashes).Error
+	return
+}
+
+// PruneCrashes deletes the crash reports from before a time, and all but
+// the newest keep reports of each program, returning what was deleted
+func PruneCrashes(before time.Time, keep int) (pruned []*Crash, err error) {
+	crashes := []*Crash{}
+	if err = db.DB.Order("program_id, id desc").Find(&crashes).Error; err != nil {
+		return nil, err
+	}
+
+	count := map[uint]int{}
+	ids := []uint{}
+	for _, crash := range crashes {
+		count[crash.ProgramID]++
+		if crash.Time().Before(before) || (keep > 0 && count[crash.ProgramID] > keep) {
+			pruned = append(pruned, crash)
+			ids = append(ids, crash.ID)
+		}
+	}
+	if len(ids) > 0 {
+		err = db.DB.Delete(&Crash{}, ids).Error
+	}
+	return pruned, err
+}
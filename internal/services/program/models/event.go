@@ -0,0 +1,76 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import "fmt"
+
+const (
+	// EVENT_SUBJECT is the subject prefix program events are published
+	// under, namespaced by the agent ID as hxe.<id>.events.program
+	EVENT_SUBJECT = "events.program"
+
+	// LOG_SUBJECT is the subject prefix the output of programs is
+	// published under, namespaced by the agent ID as hxe.<id>.logs.program
+	LOG_SUBJECT = "logs.program"
+)
+
+// EventType is the kind of a program event
+type EventType string
+
+const (
+	EVENT_TRIGGER EventType = "trigger"
+	EVENT_TIMEOUT EventType = "timeout"
+	EVENT_CRASH   EventType = "crash"
+	EVENT_STATE   EventType = "state"
+)
+
+// Event is published on the bus when something happens to a program,
+// or to an ad-hoc run when RunID is set
+type Event struct {
+	Type      EventType `json:"type"`
+	ProgramID uint      `json:"program"`
+	Name      string    `json:"name"`
+	RunID     uint      `json:"run,omitempty"`
+	Trigger   string    `json:"trigger,omitempty"`
+	Stream    string    `json:"stream,omitempty"`
+	Line      string    `json:"line,omitempty"`
+	From      *State    `json:"from,omitempty"`
+	To        *State    `json:"to,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp int64     `json:"timestamp"`
+}
+
+// Line is a line of output of a program
+type Line struct {
+	ProgramID uint   `json:"program"`
+	Name      string `json:"name"`
+	Stream    string `json:"stream"`
+	Line      string `json:"line"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Subject returns the default subject of the event
+func (e *Event) Subject() string {
+	return fmt.Sprintf("%s.%s.%s", EVENT_SUBJECT, e.Name, e.Type)
+}
+
+// Subject returns the subject of the line
+func (l *Line) Subject() string {
+	return fmt.Sprintf("%s.%s.%s", LOG_SUBJECT, l.Name, l.Stream)
+}
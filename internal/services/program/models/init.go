@@ -10,6 +10,9 @@ func AutoMigrate() (err error) {
 	// Auto migrate models
 	if err = db.AutoMigrate(
 		Program{},
+		Transition{},
+		Run{},
+		Crash{},
 	); err != nil {
 		log.Fatal().Err(err).Msg("failed to migrate models")
 	}
@@ -0,0 +1,32 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package models
+
+// ProcInfo describes a process in a program's process tree
+type ProcInfo struct {
+	Pid     int     `json:"pid"`
+	PPid    int     `json:"ppid"`
+	Pgid    int     `json:"pgid"`
+	User    string  `json:"user"`
+	State   string  `json:"state"`
+	CPU     float64 `json:"cpu"`
+	RSS     int64   `json:"rss"`
+	Command string  `json:"command"`
+	Depth   int     `json:"depth"`
+}
@@ -1,6 +1,11 @@
 package models
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -11,22 +16,125 @@ type Program struct {
 	Deleted gorm.DeletedAt `json:"deleted" gorm:"index"`
 
 	// Basic Info
-	Name string `json:"name" gorm:"column:name"`
-	Desc string `json:"desc" gorm:"column:description"`
+	Name   string            `json:"name" gorm:"column:name" hcl:"name,label"`
+	Desc   string            `json:"desc" gorm:"column:description" hcl:"description,optional"`
+	Labels map[string]string `json:"labels" gorm:"column:labels;serializer:json" hcl:"labels,optional"`
 
 	// Runtime configurations
-	Dir   string   `json:"dir" gorm:"column:dir"`
-	Path  string   `json:"path" gorm:"column:path"`
-	User  string   `json:"user" gorm:"column:user"`
-	Group string   `json:"group" gorm:"column:group"`
-	Args  []string `json:"args" gorm:"column:args;serializer:json"`
-	Env   []string `json:"env" gorm:"column:env;serializer:json"`
-
-	PreExec  string `json:"preExec" gorm:"column:preExec"`
-	Exec     string `json:"exec" gorm:"column:cmdExec"`
-	PostExec string `json:"postExec" gorm:"column:postExec"`
-
-	Autostart bool `json:"autostart"`
-	Retries   int  `json:"retries"`
-	Enabled   bool `json:"enabled"`
+	Dir   string   `json:"dir" gorm:"column:dir" hcl:"directory,optional"`
+	Path  string   `json:"path" gorm:"column:path" hcl:"path,optional"`
+	User  string   `json:"user" gorm:"column:user" hcl:"user,optional"`
+	Group string   `json:"group" gorm:"column:group" hcl:"group,optional"`
+	Args  []string `json:"args" gorm:"column:args;serializer:json" hcl:"args,optional"`
+	Env   []string `json:"env" gorm:"column:env;serializer:json" hcl:"env,optional"`
+
+	PreExec  string `json:"preExec" gorm:"column:preExec" hcl:"pre_exec,optional"`
+	Exec     string `json:"exec" gorm:"column:cmdExec" hcl:"exec,optional"`
+	PostExec string `json:"postExec" gorm:"column:postExec" hcl:"post_exec,optional"`
+
+	Autostart bool `json:"autostart" hcl:"autostart,optional"`
+	Retries   int  `json:"retries" hcl:"retries,optional"`
+	Enabled   bool `json:"enabled" hcl:"enabled,optional"`
+
+	// Disabled is set by hxe program disable and by disable_exit_codes,
+	// and is kept when the program is loaded from its file again
+	Disabled bool `json:"disabled" gorm:"column:disabled"`
+
+	// ReloadSignal is sent by a reload, SIGHUP when empty
+	ReloadSignal string `json:"reloadSignal" gorm:"column:reload_signal" hcl:"reload_signal,optional"`
+
+	// MaxRuntime limits how long a single run may take, and Deadline how
+	// long the program may take overall, across restarts
+	MaxRuntime time.Duration `json:"maxRuntime" gorm:"column:max_runtime" hcl:"max_runtime,optional"`
+	Deadline   time.Duration `json:"deadline" gorm:"column:deadline" hcl:"deadline,optional"`
+
+	// RlimitCore is the core file size limit in bytes, -1 for unlimited,
+	// inherited from the agent when unset
+	RlimitCore *int64 `json:"rlimitCore" gorm:"column:rlimit_core" hcl:"rlimit_core,optional"`
+
+	// Restart rules
+	Restart            RestartPolicy `json:"restart" gorm:"column:restart" hcl:"restart,optional"`
+	SuccessExitCodes   []int         `json:"successExitCodes" gorm:"column:success_exit_codes;serializer:json" hcl:"success_exit_codes,optional"`
+	RestartOnExitCodes []int         `json:"restartOnExitCodes" gorm:"column:restart_on_exit_codes;serializer:json" hcl:"restart_on_exit_codes,optional"`
+	NoRestartOnSignals []string      `json:"noRestartOnSignals" gorm:"column:no_restart_on_signals;serializer:json" hcl:"no_restart_on_signals,optional"`
+	DisableExitCodes   []int         `json:"disableExitCodes" gorm:"column:disable_exit_codes;serializer:json" hcl:"disable_exit_codes,optional"`
+
+	// Output pattern triggers
+	Triggers []Trigger `json:"triggers" gorm:"column:triggers;serializer:json" hcl:"trigger,block"`
+
+	// Source is the file the program was loaded from, empty when it was
+	// created through the API. Orphaned programs were loaded from a file
+	// that no longer defines them.
+	Source   string `json:"source" gorm:"column:source"`
+	Orphaned bool   `json:"orphaned" gorm:"column:orphaned"`
+
+	// Current lifecycle state, mirrored from the supervisor
+	State State `json:"state" gorm:"column:state;default:0"`
+}
+
+// RestartPolicy decides whether a program is brought back after it exits
+type RestartPolicy string
+
+const (
+	RESTART_NO         RestartPolicy = "no"
+	RESTART_ALWAYS     RestartPolicy = "always"
+	RESTART_ON_FAILURE RestartPolicy = "on-failure"
+)
+
+// TriggerAction is what a trigger does when its pattern matches
+type TriggerAction string
+
+const (
+	TRIGGER_RESTART TriggerAction = "restart"
+	TRIGGER_STOP    TriggerAction = "stop"
+	TRIGGER_SIGNAL  TriggerAction = "signal"
+	TRIGGER_PUBLISH TriggerAction = "publish"
+	TRIGGER_EXEC    TriggerAction = "exec"
+	TRIGGER_READY   TriggerAction = "ready"
+)
+
+// Trigger fires an action when a line of program output matches a pattern
+type Trigger struct {
+	Name      string        `json:"name" hcl:"name,label"`
+	Pattern   string        `json:"pattern" hcl:"pattern"`
+	Stream    string        `json:"stream" hcl:"stream,optional"`
+	Action    TriggerAction `json:"action" hcl:"action"`
+	Signal    string        `json:"signal" hcl:"signal,optional"`
+	Subject   string        `json:"subject" hcl:"subject,optional"`
+	Command   string        `json:"command" hcl:"command,optional"`
+	RateLimit time.Duration `json:"rateLimit" hcl:"rate_limit,optional"`
+}
+
+// IsEnabled reports whether the program is enabled by its definition and
+// not disabled since
+func (p *Program) IsEnabled() bool {
+	return p.Enabled && !p.Disabled
+}
+
+// Ref returns the name of the program, or its ID if it has no name
+func (p *Program) Ref() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return strconv.FormatUint(uint64(p.ID), 10)
+}
+
+// ValidateName checks that a program name can be used as a token of the
+// subjects its events and output are published on
+func ValidateName(name string) error {
+	if name == "" || strings.ContainsAny(name, ".*> \t\r\n") {
+		return fmt.Errorf("invalid program name %q, it may not be empty or contain dots, spaces, * or >", name)
+	}
+	return nil
+}
+
+// Status is a snapshot of a supervised program
+type Status struct {
+	ProgramID uint          `json:"program"`
+	Name      string        `json:"name"`
+	State     State         `json:"state"`
+	Pid       int           `json:"pid"`
+	Uptime    time.Duration `json:"uptime"`
+	Message   string        `json:"message"`
+	Progress  int32         `json:"progress"`
 }
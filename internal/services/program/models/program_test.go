@@ -0,0 +1,47 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import "testing"
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name string
+		err  bool
+	}{
+		{name: "web"},
+		{name: "web-2_api"},
+		{name: "", err: true},
+		{name: "web.v2", err: true},
+		{name: "web api", err: true},
+		{name: "web*", err: true},
+		{name: ">", err: true},
+		{name: "web\n", err: true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateName(tt.name)
+		if tt.err && err == nil {
+			t.Errorf("ValidateName(%q) succeeded, want an error", tt.name)
+		}
+		if !tt.err && err != nil {
+			t.Errorf("ValidateName(%q): %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import (
+	"time"
+
+	"github.com/rangertaha/hxe/internal/db"
+)
+
+// RunResult is how a single execution of a program ended
+type RunResult string
+
+const (
+	RUN_RUNNING   RunResult = "RUNNING"
+	RUN_SUCCEEDED RunResult = "SUCCEEDED"
+	RUN_FAILED    RunResult = "FAILED"
+	RUN_KILLED    RunResult = "KILLED"
+	RUN_STOPPED   RunResult = "STOPPED"
+	RUN_DISABLED  RunResult = "DISABLED"
+	RUN_TIMED_OUT RunResult = "TIMED_OUT"
+)
+
+// ANONYMOUS is the caller of ad-hoc runs on agents that can't
+// authenticate who asked for them
+const ANONYMOUS = "anonymous"
+
+// Run is a persisted record of one execution of a program
+type Run struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ProgramID uint      `json:"program" gorm:"column:program_id;index"`
+	Pid       int       `json:"pid" gorm:"column:pid"`
+	Started   int64     `json:"started" gorm:"column:started;autoCreateTime:milli"`
+	Finished  int64     `json:"finished" gorm:"column:finished"`
+	ExitCode  int       `json:"exitCode" gorm:"column:exit_code"`
+	Signal    string    `json:"signal" gorm:"column:signal"`
+	Result    RunResult `json:"result" gorm:"column:result"`
+	Restart   bool      `json:"restart" gorm:"column:restart"`
+	Reason    string    `json:"reason" gorm:"column:reason"`
+	Command   string    `json:"command" gorm:"column:command"`
+	Caller    string    `json:"caller,omitempty" gorm:"column:caller"`
+}
+
+// Duration returns how long the run lasted, or has lasted so far
+func (r *Run) Duration() time.Duration {
+	if r.Finished == 0 {
+		return time.Since(time.UnixMilli(r.Started))
+	}
+	return time.Duration(r.Finished-r.Started) * time.Millisecond
+}
+
+// StartRun records the start of a program execution. The caller is who
+// asked for an ad-hoc run, and is empty for supervised runs.
+func StartRun(programID uint, pid int, command, caller string) (run *Run, err error) {
+	run = &Run{ProgramID: programID, Pid: pid, Command: command, Caller: caller, Result: RUN_RUNNING}
+	err = db.DB.Create(run).Error
+	return
+}
+
+// Finish records the end of a program execution
+func (r *Run) Finish() error {
+	r.Finished = time.Now().UnixMilli()
+	return db.DB.Save(r).Error
+}
+
+// Runs returns the most recent runs of a program, newest first. Ad-hoc
+// runs made outside of any program are listed under program 0.
+func Runs(programID uint, limit int) (runs []*Run, err error) {
+	query := db.DB.Where("program_id = ?", programID).Order("id desc")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err = query.Find(&runs).Error
+	return
+}
@@ -0,0 +1,128 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rangertaha/hxe/internal/db"
+)
+
+// Requirement is a single label condition of a selector
+type Requirement struct {
+	Key      string
+	Value    string
+	Negate   bool
+	Existing bool
+}
+
+// Selector matches programs by their labels, such as "env=prod,tier!=db"
+type Selector []Requirement
+
+// ParseSelector parses a comma separated list of key=value, key!=value,
+// key and !key requirements. Only the empty string parses to the empty
+// selector, which matches every program.
+func ParseSelector(s string) (sel Selector, err error) {
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var req Requirement
+		switch {
+		case strings.Contains(part, "!="):
+			kv := strings.SplitN(part, "!=", 2)
+			req = Requirement{Key: kv[0], Value: kv[1], Negate: true}
+		case strings.Contains(part, "=="):
+			kv := strings.SplitN(part, "==", 2)
+			req = Requirement{Key: kv[0], Value: kv[1]}
+		case strings.Contains(part, "="):
+			kv := strings.SplitN(part, "=", 2)
+			req = Requirement{Key: kv[0], Value: kv[1]}
+		case strings.HasPrefix(part, "!"):
+			req = Requirement{Key: part[1:], Existing: true, Negate: true}
+		default:
+			req = Requirement{Key: part, Existing: true}
+		}
+
+		req.Key = strings.TrimSpace(req.Key)
+		req.Value = strings.TrimSpace(req.Value)
+		if req.Key == "" {
+			return nil, fmt.Errorf("invalid selector requirement: %q", part)
+		}
+		sel = append(sel, req)
+	}
+	if s != "" && len(sel) == 0 {
+		return nil, fmt.Errorf("invalid selector %q: no requirements", s)
+	}
+	return sel, nil
+}
+
+// Matches reports whether the labels satisfy every requirement
+func (sel Selector) Matches(labels map[string]string) bool {
+	for _, req := range sel {
+		value, ok := labels[req.Key]
+		match := ok
+		if !req.Existing {
+			match = ok && value == req.Value
+		}
+		if match == req.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (sel Selector) String() string {
+	parts := make([]string, 0, len(sel))
+	for _, req := range sel {
+		switch {
+		case req.Existing && req.Negate:
+			parts = append(parts, "!"+req.Key)
+		case req.Existing:
+			parts = append(parts, req.Key)
+		case req.Negate:
+			parts = append(parts, req.Key+"!="+req.Value)
+		default:
+			parts = append(parts, req.Key+"="+req.Value)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Select returns the programs whose labels match the selector, ordered by ID
+func Select(selector string) (progs []*Program, err error) {
+	sel, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	all := []*Program{}
+	if err = db.DB.Order("id").Find(&all).Error; err != nil {
+		return nil, err
+	}
+	for _, prog := range all {
+		if sel.Matches(prog.Labels) {
+			progs = append(progs, prog)
+		}
+	}
+	return progs, nil
+}
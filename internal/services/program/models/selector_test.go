@@ -0,0 +1,86 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import "testing"
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "", want: ""},
+		{in: "env=prod", want: "env=prod"},
+		{in: "env==prod", want: "env=prod"},
+		{in: " env = prod , tier!=db ", want: "env=prod,tier!=db"},
+		{in: "canary,!legacy", want: "canary,!legacy"},
+		{in: "env=", want: "env="},
+		{in: "a=1,,b=2", want: "a=1,b=2"},
+		{in: ",", err: true},
+		{in: " ", err: true},
+		{in: " , ,", err: true},
+		{in: "=prod", err: true},
+		{in: "!", err: true},
+		{in: "env=prod,!=x", err: true},
+	}
+
+	for _, tt := range tests {
+		sel, err := ParseSelector(tt.in)
+		switch {
+		case tt.err && err == nil:
+			t.Errorf("ParseSelector(%q) = %q, want an error", tt.in, sel)
+		case !tt.err && err != nil:
+			t.Errorf("ParseSelector(%q): %v", tt.in, err)
+		case !tt.err && sel.String() != tt.want:
+			t.Errorf("ParseSelector(%q) = %q, want %q", tt.in, sel, tt.want)
+		}
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"env": "prod", "tier": "web", "canary": ""}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"env=prod", true},
+		{"env=dev", false},
+		{"env!=dev", true},
+		{"env!=prod", false},
+		{"canary", true},
+		{"!canary", false},
+		{"region", false},
+		{"!region", true},
+		{"region!=eu", true},
+		{"env=prod,tier=web", true},
+		{"env=prod,tier=db", false},
+	}
+
+	for _, tt := range tests {
+		sel, err := ParseSelector(tt.selector)
+		if err != nil {
+			t.Fatalf("ParseSelector(%q): %v", tt.selector, err)
+		}
+		if got := sel.Matches(labels); got != tt.want {
+			t.Errorf("%q matches %v = %v, want %v", tt.selector, labels, got, tt.want)
+		}
+	}
+}
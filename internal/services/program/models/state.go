@@ -0,0 +1,139 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rangertaha/hxe/internal/db"
+	"gorm.io/gorm"
+)
+
+// State is the lifecycle state of a program
+type State int32
+
+const (
+	STOPPED State = iota
+	STARTING
+	RUNNING
+	READY
+	STOPPING
+	EXITED
+	BACKOFF
+	FATAL
+)
+
+var stateNames = map[State]string{
+	STOPPED:  "STOPPED",
+	STARTING: "STARTING",
+	RUNNING:  "RUNNING",
+	READY:    "READY",
+	STOPPING: "STOPPING",
+	EXITED:   "EXITED",
+	BACKOFF:  "BACKOFF",
+	FATAL:    "FATAL",
+}
+
+// transitions lists the states each state is allowed to move to
+var transitions = map[State][]State{
+	STOPPED:  {STARTING},
+	STARTING: {RUNNING, BACKOFF, FATAL, STOPPING},
+	RUNNING:  {READY, STOPPING, EXITED, BACKOFF},
+	READY:    {STOPPING, EXITED},
+	STOPPING: {STOPPED},
+	EXITED:   {STARTING, BACKOFF, STOPPED},
+	BACKOFF:  {STARTING, FATAL, STOPPED},
+	FATAL:    {STARTING, STOPPED},
+}
+
+func (s State) String() string {
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("STATE(%d)", int32(s))
+}
+
+// CanTransition reports whether moving from s to the given state is legal
+func (s State) CanTransition(to State) bool {
+	for _, state := range transitions[s] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether a process exists, or is being brought up, for this state
+func (s State) Active() bool {
+	return s == STARTING || s == RUNNING || s == READY || s == STOPPING
+}
+
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *State) UnmarshalText(text []byte) error {
+	name := strings.ToUpper(string(text))
+	for state, n := range stateNames {
+		if n == name {
+			*s = state
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown program state: %s", text)
+}
+
+// Transition is a persisted change of a program's state
+type Transition struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	ProgramID uint   `json:"program" gorm:"column:program_id;index"`
+	From      State  `json:"from" gorm:"column:from_state"`
+	To        State  `json:"to" gorm:"column:to_state"`
+	Reason    string `json:"reason" gorm:"column:reason"`
+	Timestamp int64  `json:"timestamp" gorm:"column:timestamp;autoCreateTime:milli"`
+}
+
+// Time returns the time the transition happened
+func (t *Transition) Time() time.Time {
+	return time.UnixMilli(t.Timestamp)
+}
+
+// RecordTransition persists a transition and the program's new current state
+func RecordTransition(programID uint, from, to State, reason string) (t *Transition, err error) {
+	t = &Transition{ProgramID: programID, From: from, To: to, Reason: reason}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(t).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Program{}).Where("id = ?", programID).Update("state", to).Error
+	})
+	return
+}
+
+// History returns the most recent transitions of a program, newest first
+func History(programID uint, limit int) (history []*Transition, err error) {
+	query := db.DB.Where("program_id = ?", programID).Order("id desc")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err = query.Find(&history).Error
+	return
+}
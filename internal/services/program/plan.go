@@ -0,0 +1,213 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package program
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/rangertaha/hxe/internal/db"
+	pc "github.com/rangertaha/hxe/internal/services/program/client"
+	"github.com/rangertaha/hxe/internal/services/program/loader"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+	"gorm.io/gorm"
+)
+
+// Plan diffs the desired programs against the database and the
+// supervised processes
+func (s *Microservice) Plan(req *pc.Request) (res *pc.Response) {
+	if req.Plan == nil {
+		return &pc.Response{Error: "no plan given"}
+	}
+	plan, _, err := s.plan(db.DB, req.Plan)
+	if err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+	return &pc.Response{Plan: plan}
+}
+
+// Apply executes a plan in a single transaction, refusing it when the
+// programs changed since it was made, then starts, restarts and removes
+// the affected processes
+func (s *Microservice) Apply(req *pc.Request) (res *pc.Response) {
+	if req.Plan == nil {
+		return &pc.Response{Error: "no plan given"}
+	}
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+
+	var (
+		plan    *pc.Plan
+		current map[string]*models.Program
+	)
+	desired := map[string]*models.Program{}
+	err := db.DB.Transaction(func(tx *gorm.DB) (err error) {
+		if plan, current, err = s.plan(tx, req.Plan); err != nil {
+			return err
+		}
+		if plan.Serial != req.Plan.Serial {
+			return fmt.Errorf("the programs changed since the plan was made, run plan again")
+		}
+
+		for _, prog := range plan.Programs {
+			desired[prog.Name] = prog
+		}
+		for _, change := range plan.Changes {
+			prog, existing := desired[change.Name], current[change.Name]
+			switch change.Action {
+			case pc.PLAN_CREATE:
+				err = tx.Create(prog).Error
+			case pc.PLAN_UPDATE, pc.PLAN_RESTART:
+				prog.ID = existing.ID
+				prog.Created = existing.Created
+				prog.State = existing.State
+				prog.Disabled = existing.Disabled
+				err = tx.Save(prog).Error
+			case pc.PLAN_DELETE:
+				err = tx.Delete(existing).Error
+			}
+			if err != nil {
+				return fmt.Errorf("failed to %s program %s: %w", change.Action, change.Name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return &pc.Response{Error: err.Error()}
+	}
+
+	for _, change := range plan.Changes {
+		prog := desired[change.Name]
+		switch change.Action {
+		case pc.PLAN_CREATE:
+			if prog.IsEnabled() && prog.Autostart {
+				_, err = s.sup.Start(prog)
+			}
+		case pc.PLAN_UPDATE:
+			s.sup.Update(prog)
+		case pc.PLAN_RESTART:
+			_, err = s.sup.Restart(prog)
+		case pc.PLAN_DELETE:
+			_, err = s.sup.Remove(current[change.Name])
+		}
+		if err != nil {
+			s.log.Error().Err(err).Str("program", change.Name).Msgf("failed to %s applied program", change.Action)
+		}
+		s.log.Info().Str("program", change.Name).Strs("fields", change.Fields).Msgf("program %s applied", change.Action)
+	}
+	return &pc.Response{Plan: plan}
+}
+
+// plan diffs the desired programs of req against the programs in tx.
+// Programs loaded from the programs directory can not be planned. A
+// pruning plan deletes the programs created through the API that match
+// its selector and are not desired, other plans never delete. The serial
+// is a hash of the current programs the plan depends on and of its
+// changes.
+func (s *Microservice) plan(tx *gorm.DB, req *pc.Plan) (plan *pc.Plan, current map[string]*models.Program, err error) {
+	desired := req.Programs
+	sel, err := models.ParseSelector(req.Selector)
+	if err != nil {
+		return nil, nil, err
+	}
+	all := []*models.Program{}
+	if err = tx.Order("id").Find(&all).Error; err != nil {
+		return nil, nil, err
+	}
+	byName := map[string]*models.Program{}
+	for _, prog := range all {
+		byName[prog.Name] = prog
+	}
+
+	plan = &pc.Plan{Programs: desired, Selector: req.Selector, Prune: req.Prune, Changes: []*pc.PlanChange{}}
+	current = map[string]*models.Program{}
+	for _, prog := range desired {
+		if err := models.ValidateName(prog.Name); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := current[prog.Name]; ok {
+			return nil, nil, fmt.Errorf("program %s is defined more than once", prog.Name)
+		}
+		prog.ID, prog.Source, prog.Orphaned = 0, "", false
+
+		existing, ok := byName[prog.Name]
+		current[prog.Name] = existing
+		if !ok {
+			plan.Changes = append(plan.Changes, &pc.PlanChange{Action: pc.PLAN_CREATE, Name: prog.Name})
+			continue
+		}
+		if existing.Source != "" {
+			return nil, nil, fmt.Errorf("program %s is defined in %s of the programs directory", prog.Name, existing.Source)
+		}
+
+		fields := loader.Diff(existing, prog)
+		if len(fields) == 0 {
+			continue
+		}
+		change := &pc.PlanChange{Action: pc.PLAN_UPDATE, Name: prog.Name, Fields: fields}
+		if s.restartRequired(existing, fields) {
+			change.Action = pc.PLAN_RESTART
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	for _, prog := range all {
+		if _, ok := current[prog.Name]; ok || !req.Prune || prog.Source != "" || !sel.Matches(prog.Labels) {
+			continue
+		}
+		current[prog.Name] = prog
+		plan.Changes = append(plan.Changes, &pc.PlanChange{Action: pc.PLAN_DELETE, Name: prog.Name})
+	}
+
+	hash := sha256.New()
+	enc := json.NewEncoder(hash)
+	for _, prog := range all {
+		if current[prog.Name] != prog {
+			continue
+		}
+		state := *prog
+		state.State, state.Updated = 0, 0
+		if err = enc.Encode(state); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err = enc.Encode(plan.Changes); err != nil {
+		return nil, nil, err
+	}
+	plan.Serial = hex.EncodeToString(hash.Sum(nil))
+	return plan, current, nil
+}
+
+// restartRequired reports whether changing fields of a program restarts
+// its process, which is the case when the process is running and one of
+// the fields only takes effect on start
+func (s *Microservice) restartRequired(prog *models.Program, fields []string) bool {
+	proc, ok := s.sup.Lookup(prog.ID)
+	if !ok || !models.State(proc.State()).Active() {
+		return false
+	}
+	for _, field := range fields {
+		if restartFields[field] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package program
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/rangertaha/hxe/internal/db"
+	pc "github.com/rangertaha/hxe/internal/services/program/client"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+	"github.com/rangertaha/hxe/internal/services/program/supervisor"
+	"github.com/rs/zerolog"
+)
+
+// newPlanner returns a microservice without NATS over a database with
+// the given programs
+func newPlanner(t *testing.T, progs ...*models.Program) *Microservice {
+	t.Helper()
+	if err := db.DB.Where("1 = 1").Delete(&models.Program{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, prog := range progs {
+		if err := db.DB.Create(prog).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Microservice{sup: supervisor.New(nil, ""), log: zerolog.Nop()}
+}
+
+// changes formats the changes of a plan as action name pairs
+func changes(plan *pc.Plan) (got []string) {
+	for _, change := range plan.Changes {
+		got = append(got, string(change.Action)+" "+change.Name)
+	}
+	return got
+}
+
+func TestPlan(t *testing.T) {
+	existing := func() []*models.Program {
+		return []*models.Program{
+			{Name: "web", Exec: "web", Labels: map[string]string{"team": "web"}},
+			{Name: "api", Exec: "api", Labels: map[string]string{"team": "web"}},
+			{Name: "db", Exec: "db", Labels: map[string]string{"team": "db"}},
+			{Name: "cron", Exec: "cron", Source: "/etc/hxe/programs/cron.hcl"},
+		}
+	}
+	tests := []struct {
+		name    string
+		desired []*models.Program
+		plan    pc.Plan
+		want    []string
+		err     bool
+	}{
+		{
+			name:    "no prune keeps other programs",
+			desired: []*models.Program{{Name: "web", Exec: "web --v2", Labels: map[string]string{"team": "web"}}, {Name: "worker", Exec: "worker"}},
+			want:    []string{"update web", "create worker"},
+		},
+		{
+			name:    "unchanged",
+			desired: []*models.Program{{Name: "web", Exec: "web", Labels: map[string]string{"team": "web"}}},
+		},
+		{
+			name:    "prune deletes api programs",
+			desired: []*models.Program{{Name: "web", Exec: "web", Labels: map[string]string{"team": "web"}}},
+			plan:    pc.Plan{Prune: true},
+			want:    []string{"delete api", "delete db"},
+		},
+		{
+			name:    "prune limited by selector",
+			desired: []*models.Program{{Name: "web", Exec: "web", Labels: map[string]string{"team": "web"}}},
+			plan:    pc.Plan{Prune: true, Selector: "team=web"},
+			want:    []string{"delete api"},
+		},
+		{
+			name:    "programs directory",
+			desired: []*models.Program{{Name: "cron", Exec: "cron"}},
+			err:     true,
+		},
+		{
+			name:    "duplicate",
+			desired: []*models.Program{{Name: "web", Exec: "a"}, {Name: "web", Exec: "b"}},
+			err:     true,
+		},
+		{
+			name:    "invalid name",
+			desired: []*models.Program{{Name: "web.v2", Exec: "web"}},
+			err:     true,
+		},
+		{
+			name:    "invalid selector",
+			desired: []*models.Program{{Name: "web", Exec: "web"}},
+			plan:    pc.Plan{Prune: true, Selector: "=web"},
+			err:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newPlanner(t, existing()...)
+			req := tt.plan
+			req.Programs = tt.desired
+			plan, _, err := s.plan(db.DB, &req)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("plan = %v, want an error", changes(plan))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := changes(plan); !slices.Equal(got, tt.want) {
+				t.Errorf("changes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanRestart(t *testing.T) {
+	web := &models.Program{Name: "web", Exec: "sleep 10"}
+	s := newPlanner(t, web)
+	if _, err := s.sup.Start(web); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.sup.Remove(web) })
+
+	desired := []*models.Program{
+		{Name: "web", Exec: "sleep 20"},
+	}
+	plan, _, err := s.plan(db.DB, &pc.Plan{Programs: desired})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Action != pc.PLAN_RESTART || !slices.Equal(plan.Changes[0].Fields, []string{"exec"}) {
+		t.Errorf("changes = %+v, want a restart for exec", plan.Changes)
+	}
+
+	desired[0] = &models.Program{Name: "web", Exec: "sleep 10", Desc: "web server"}
+	if plan, _, err = s.plan(db.DB, &pc.Plan{Programs: desired}); err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Action != pc.PLAN_UPDATE {
+		t.Errorf("changes = %+v, want an update for the description", plan.Changes)
+	}
+
+	desired[0] = &models.Program{Name: "web", Exec: "sleep 10", Triggers: []models.Trigger{{Name: "up", Pattern: "up", Action: models.TRIGGER_READY}}}
+	if plan, _, err = s.plan(db.DB, &pc.Plan{Programs: desired}); err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Action != pc.PLAN_RESTART || !slices.Equal(plan.Changes[0].Fields, []string{"trigger"}) {
+		t.Errorf("changes = %+v, want a restart for the trigger", plan.Changes)
+	}
+}
+
+func TestApplySerial(t *testing.T) {
+	s := newPlanner(t,
+		&models.Program{Name: "web", Exec: "web"},
+		&models.Program{Name: "db", Exec: "db"},
+	)
+	desired := func() []*models.Program {
+		return []*models.Program{{Name: "web", Exec: "web --v2"}, {Name: "worker", Exec: "worker"}}
+	}
+
+	first, _, err := s.plan(db.DB, &pc.Plan{Programs: desired()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _, err := s.plan(db.DB, &pc.Plan{Programs: desired()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Serial == "" || first.Serial != again.Serial {
+		t.Fatalf("serials %q and %q of the same state differ", first.Serial, again.Serial)
+	}
+	pruned, _, err := s.plan(db.DB, &pc.Plan{Programs: desired(), Prune: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned.Serial == first.Serial {
+		t.Error("pruning plan has the serial of the plan without prune")
+	}
+
+	// a program the plan depends on changes in between
+	if err := db.DB.Model(&models.Program{}).Where("name = ?", "web").Update("description", "changed").Error; err != nil {
+		t.Fatal(err)
+	}
+	if resp := s.Apply(&pc.Request{Plan: first}); resp.Error == "" {
+		t.Fatal("applied a plan made against another state")
+	}
+
+	plan, _, err := s.plan(db.DB, &pc.Plan{Programs: desired()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := s.Apply(&pc.Request{Plan: plan}); resp.Error != "" {
+		t.Fatal(resp.Error)
+	}
+	var progs []*models.Program
+	if err := db.DB.Order("name").Find(&progs).Error; err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, prog := range progs {
+		got[prog.Name] = prog.Exec
+	}
+	if len(got) != 3 || got["web"] != "web --v2" || got["worker"] != "worker" || got["db"] != "db" {
+		t.Errorf("programs = %v, want web --v2, worker and db kept", got)
+	}
+
+	// the state the plan was made against is gone once applied
+	if resp := s.Apply(&pc.Request{Plan: plan}); resp.Error == "" {
+		t.Error("applied the same plan twice")
+	}
+}
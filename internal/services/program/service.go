@@ -19,32 +19,195 @@
 package program
 
 import (
+	"errors"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/nats-io/nats.go"
+	"github.com/rangertaha/hxe/internal/config"
+	"github.com/rangertaha/hxe/internal/db"
 	"github.com/rangertaha/hxe/internal/interfaces"
 	"github.com/rangertaha/hxe/internal/log"
 	"github.com/rangertaha/hxe/internal/services"
+	"github.com/rangertaha/hxe/internal/services/program/loader"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+	"github.com/rangertaha/hxe/internal/services/program/supervisor"
 	"github.com/rs/zerolog"
 )
 
+// restartFields are the program attributes that only take effect when the
+// process is started again; triggers are installed when it is spawned
+var restartFields = map[string]bool{
+	"directory": true, "path": true, "user": true, "group": true,
+	"args": true, "env": true, "pre_exec": true, "exec": true,
+	"post_exec": true, "rlimit_core": true, "trigger": true,
+}
+
 type Service struct {
+	Crash *supervisor.CrashConfig `hcl:"crash,block"`
+
+	dir   string
 	micro *Microservice
+	sup   *supervisor.Supervisor
 	log   zerolog.Logger
 }
 
 func (s *Service) Init() (err error) {
+	if s.Crash != nil {
+		s.sup.SetCrashConfig(*s.Crash)
+	}
 	return s.micro.Init()
 }
 
 func (s *Service) Start() (err error) {
-	return
+	// States left over from a previous run no longer have a process
+	if err = db.DB.Model(&models.Program{}).Where("state <> ?", models.STOPPED).
+		Update("state", models.STOPPED).Error; err != nil {
+		return err
+	}
+	if err = s.sup.PruneCrashes(); err != nil {
+		s.log.Error().Err(err).Msg("failed to prune crash reports")
+	}
+	if _, err = s.load(); err != nil {
+		s.log.Error().Err(err).Str("directory", s.dir).Msg("failed to load programs")
+	}
+
+	progs := []*models.Program{}
+	db.DB.Find(&progs, "enabled = ? AND disabled = ? AND autostart = ? AND orphaned = ?", true, false, true, false)
+	for _, prog := range progs {
+		if _, err := s.sup.Start(prog); err != nil {
+			s.log.Error().Err(err).Str("program", prog.Name).Msg("failed to autostart program")
+		}
+	}
+	return nil
+}
+
+// load syncs the program definitions of the programs directory into the
+// database, logging any problems with the files
+func (s *Service) load() (*loader.Changes, error) {
+	if s.dir == "" {
+		return &loader.Changes{}, nil
+	}
+
+	result, changes, err := loader.Load(s.dir)
+	for _, diag := range result.Diags {
+		if diag.Severity == hcl.DiagError {
+			s.log.Error().Msg(diag.Error())
+		} else {
+			s.log.Warn().Msg(diag.Error())
+		}
+	}
+	if err != nil {
+		return changes, err
+	}
+
+	s.log.Info().Str("directory", s.dir).Int("created", len(changes.Created)).
+		Int("updated", len(changes.Updated)).Int("unchanged", len(changes.Unchanged)).
+		Msg("loaded programs")
+	for _, name := range changes.Orphaned {
+		s.log.Warn().Str("program", name).Msg("program is no longer defined in the programs directory, marked orphaned")
+	}
+	return changes, nil
+}
+
+// ReloadConfig applies a new service block and reloads the programs
+// directory, restarting only the running programs whose execution
+// changed
+func (s *Service) ReloadConfig(cfg *config.Service) ([]services.Change, error) {
+	s.Crash = nil
+	if diags := gohcl.DecodeBody(cfg.Config, config.FileContext(cfg.Config.MissingItemRange().Filename), s); diags.HasErrors() {
+		return nil, errors.New(diags.Error())
+	}
+	s.dir = cfg.Directory
+
+	crash := supervisor.DefaultCrashConfig()
+	if s.Crash != nil {
+		crash = *s.Crash
+	}
+	s.sup.SetCrashConfig(crash)
+
+	changes, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(changes), nil
+}
+
+// apply brings the supervised processes in line with the changes of a load
+func (s *Service) apply(changes *loader.Changes) (applied []services.Change) {
+	find := func(name string) (*models.Program, bool) {
+		prog := &models.Program{}
+		db.DB.Where("name = ?", name).Limit(1).Find(prog)
+		return prog, prog.ID != 0
+	}
+
+	for _, name := range changes.Created {
+		applied = append(applied, services.Change{Service: "programs", Kind: "added", Name: name})
+		prog, ok := find(name)
+		if !ok || !prog.IsEnabled() || !prog.Autostart {
+			continue
+		}
+		if _, err := s.sup.Start(prog); err != nil {
+			s.log.Error().Err(err).Str("program", name).Msg("failed to start added program")
+		}
+	}
+
+	for _, name := range changes.Updated {
+		change := services.Change{Service: "programs", Kind: "changed", Name: name, Fields: changes.Fields[name]}
+		prog, ok := find(name)
+		if !ok {
+			continue
+		}
+
+		restart := false
+		for _, field := range change.Fields {
+			restart = restart || restartFields[field]
+		}
+		proc, running := s.sup.Lookup(prog.ID)
+		if restart && running && models.State(proc.State()).Active() {
+			change.Kind = "restarted"
+			if _, err := s.sup.Restart(prog); err != nil {
+				s.log.Error().Err(err).Str("program", name).Msg("failed to restart changed program")
+			}
+		} else {
+			s.sup.Update(prog)
+		}
+		applied = append(applied, change)
+	}
+
+	for _, name := range changes.Orphaned {
+		applied = append(applied, services.Change{Service: "programs", Kind: "removed", Name: name})
+		prog, ok := find(name)
+		if !ok {
+			continue
+		}
+		if _, running := s.sup.Lookup(prog.ID); !running {
+			continue
+		}
+		if _, err := s.sup.Stop(prog); err != nil {
+			s.log.Error().Err(err).Str("program", name).Msg("failed to stop removed program")
+		}
+	}
+
+	for _, change := range applied {
+		s.log.Info().Str("program", change.Name).Strs("fields", change.Fields).Msgf("program %s", change.Kind)
+	}
+	return applied
 }
 
 func (s *Service) Stop() (err error) {
+	s.sup.StopAll()
 	return
 }
 
+// Reload the programs directory with the current configuration
 func (s *Service) Reload() (err error) {
-	return
+	changes, err := s.load()
+	if err != nil {
+		return err
+	}
+	s.apply(changes)
+	return nil
 }
 
 func (s *Service) Restart() (err error) {
@@ -57,10 +220,13 @@ func (s *Service) Status() (status string) {
 
 // Register the service
 func init() {
-	services.Add("programs", func(nc *nats.Conn) interfaces.Service {
+	services.Add("programs", func(nc *nats.Conn, cfg *config.Service) interfaces.Service {
+		sup := supervisor.New(nc, cfg.Agent)
 		return &Service{
+			dir:   cfg.Directory,
 			log:   log.With().Logger(),
-			micro: NewMicroservice(nc),
+			sup:   sup,
+			micro: NewMicroservice(nc, sup, cfg.Agent, cfg.Auth),
 		}
 	})
 }
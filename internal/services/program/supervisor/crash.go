@@ -0,0 +1,361 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package supervisor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rangertaha/hxe/internal"
+	"github.com/rangertaha/hxe/internal/config"
+	"github.com/rangertaha/hxe/internal/db"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+	"golang.org/x/sys/unix"
+)
+
+// CrashConfig configures the crash reports of programs
+type CrashConfig struct {
+	// Directory crash reports keep their core files in
+	Directory string `hcl:"directory,optional"`
+
+	// CoreDir is where the kernel writes core files, the program's
+	// working directory when empty
+	CoreDir string `hcl:"core_dir,optional"`
+
+	// Lines of stdout and stderr kept for a report
+	Lines int `hcl:"lines,optional"`
+
+	// Retention is how long reports are kept
+	Retention time.Duration `hcl:"retention,optional"`
+
+	// MaxReports is how many reports are kept for each program
+	MaxReports int `hcl:"max_reports,optional"`
+}
+
+// DefaultCrashConfig returns the crash report defaults
+func DefaultCrashConfig() CrashConfig {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return CrashConfig{
+		Directory:  filepath.Join(dir, config.CONFIG_DIR, "crashes"),
+		Lines:      50,
+		Retention:  30 * 24 * time.Hour,
+		MaxReports: 20,
+	}
+}
+
+// crashSignals are the signals a program is considered crashed by
+var crashSignals = []syscall.Signal{
+	syscall.SIGSEGV,
+	syscall.SIGABRT,
+	syscall.SIGBUS,
+	syscall.SIGFPE,
+	syscall.SIGILL,
+	syscall.SIGSYS,
+	syscall.SIGTRAP,
+}
+
+func (e exit) crashed() bool {
+	return e.Signaled && slices.Contains(crashSignals, e.Signal)
+}
+
+// SetCrashConfig configures crash reports, using the defaults for
+// anything left unset
+func (s *Supervisor) SetCrashConfig(c CrashConfig) {
+	defaults := DefaultCrashConfig()
+	if c.Directory == "" {
+		c.Directory = defaults.Directory
+	}
+	if c.Lines <= 0 {
+		c.Lines = defaults.Lines
+	}
+	if c.Retention <= 0 {
+		c.Retention = defaults.Retention
+	}
+	if c.MaxReports <= 0 {
+		c.MaxReports = defaults.MaxReports
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.crash = c
+	for _, proc := range s.procs {
+		proc.mu.Lock()
+		proc.crash = c
+		proc.mu.Unlock()
+	}
+}
+
+// PruneCrashes deletes the crash reports, and their core files, that are
+// past retention
+func (s *Supervisor) PruneCrashes() error {
+	s.mu.Lock()
+	c := s.crash
+	s.mu.Unlock()
+	return pruneCrashes(c)
+}
+
+func pruneCrashes(c CrashConfig) error {
+	pruned, err := models.PruneCrashes(time.Now().Add(-c.Retention), c.MaxReports)
+	for _, crash := range pruned {
+		if crash.CoreFile != "" {
+			os.Remove(crash.CoreFile)
+		}
+	}
+	return err
+}
+
+// reportCrash starts a crash report for a run that died from a crash
+// signal. The lock must be held.
+func (p *Process) reportCrash(cmd *exec.Cmd, run *models.Run, e exit) {
+	crash := &models.Crash{
+		ProgramID: p.prog.ID,
+		Signal:    SignalName(e.Signal),
+		Binary:    binaryOf(cmd),
+		Arch:      runtime.GOOS + "/" + runtime.GOARCH,
+		Version:   internal.VERSION,
+		EnvHash:   envHash(cmd.Env),
+		Stdout:    p.stdout.Lines(),
+		Stderr:    p.stderr.Lines(),
+	}
+	crash.Hostname, _ = os.Hostname()
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err == nil {
+		crash.Kernel = unix.ByteSliceToString(uts.Release[:])
+	}
+
+	started := p.started
+	if run != nil {
+		crash.RunID = run.ID
+		started = time.UnixMilli(run.Started)
+	}
+	if cmd.Process != nil {
+		crash.Pid = cmd.Process.Pid
+	}
+
+	coreDir := p.crash.CoreDir
+	if coreDir == "" {
+		coreDir = cmd.Dir
+	}
+	go p.collectCrash(crash, p.crash, coreDir, started)
+}
+
+// collectCrash hashes the binary, collects the core file and saves the
+// report, which may take a while for large core files
+func (p *Process) collectCrash(crash *models.Crash, c CrashConfig, coreDir string, started time.Time) {
+	var problems []string
+
+	sum, err := sha256File(crash.Binary)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("binary not hashed: %v", err))
+	}
+	crash.BinarySHA256 = sum
+
+	core, err := findCore(coreDir, crash.Pid, started)
+	switch {
+	case err != nil:
+		problems = append(problems, fmt.Sprintf("core file not collected: %v", err))
+	case core == "":
+		problems = append(problems, "no core file, check rlimit_core and core_pattern")
+	default:
+		dest := filepath.Join(c.Directory, fmt.Sprintf("core.%d.%d.%d", crash.ProgramID, crash.Pid, time.Now().Unix()))
+		if crash.CoreSize, err = moveFile(core, dest); err != nil {
+			problems = append(problems, fmt.Sprintf("core file not collected: %v", err))
+		} else {
+			crash.CoreFile = dest
+		}
+	}
+	crash.Message = strings.Join(problems, "; ")
+
+	if err := db.DB.Create(crash).Error; err != nil {
+		p.log.Error().Err(err).Msg("failed to save crash report")
+		return
+	}
+	p.log.Error().Str("signal", crash.Signal).Str("core", crash.CoreFile).Uint("crash", crash.ID).Msg("program crashed")
+
+	p.mu.Lock()
+	event := &models.Event{
+		Type:      models.EVENT_CRASH,
+		ProgramID: p.prog.ID,
+		Name:      p.prog.Name,
+		Reason:    "killed by signal " + crash.Signal,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	p.mu.Unlock()
+	p.event(event)
+
+	if err := pruneCrashes(c); err != nil {
+		p.log.Error().Err(err).Msg("failed to prune crash reports")
+	}
+}
+
+// binaryOf returns the binary a command runs, looking through the shell
+// a program's exec line runs in when it is a simple command
+func binaryOf(cmd *exec.Cmd) string {
+	if len(cmd.Args) != 3 || cmd.Args[0] != "/bin/sh" || cmd.Args[1] != "-c" ||
+		strings.ContainsAny(cmd.Args[2], ";&|<>()$`\n") {
+		return cmd.Path
+	}
+	fields := strings.Fields(cmd.Args[2])
+	if len(fields) == 0 {
+		return cmd.Path
+	}
+	name := fields[0]
+	if strings.Contains(name, "/") {
+		if !filepath.IsAbs(name) && cmd.Dir != "" {
+			name = filepath.Join(cmd.Dir, name)
+		}
+		return name
+	}
+	if path, err := exec.LookPath(name); err == nil {
+		return path
+	}
+	return name
+}
+
+// envHash fingerprints an environment regardless of its order
+func envHash(env []string) string {
+	env = slices.Clone(env)
+	slices.Sort(env)
+	sum := sha256.Sum256([]byte(strings.Join(env, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// findCore returns the newest core file of a process written to dir,
+// matching the default "core" and "core.<pid>" names and core_pattern
+// names with the pid as a dot separated part, such as "core.app.1234"
+func findCore(dir string, pid int, since time.Time) (string, error) {
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return "", err
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		core   string
+		newest time.Time
+	)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "core") {
+			continue
+		}
+		if name != "core" && !slices.Contains(strings.Split(name, "."), strconv.Itoa(pid)) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().Before(since) || info.ModTime().Before(newest) {
+			continue
+		}
+		core, newest = filepath.Join(dir, name), info.ModTime()
+	}
+	return core, nil
+}
+
+// moveFile moves a file, copying it when it is on another filesystem
+func moveFile(src, dest string) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return 0, err
+	}
+	if err = os.Rename(src, dest); err == nil {
+		return info.Size(), nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dest)
+		return 0, err
+	}
+	return n, os.Remove(src)
+}
+
+// setCoreLimit applies a program's rlimit_core to its started process,
+// -1 meaning unlimited. The limit cannot be set for the child alone
+// before it execs, so a crash in its first instants keeps the inherited
+// limit.
+func setCoreLimit(pid int, limit int64) error {
+	var cur unix.Rlimit
+	if err := unix.Prlimit(pid, unix.RLIMIT_CORE, nil, &cur); err != nil {
+		return err
+	}
+
+	want := uint64(unix.RLIM_INFINITY)
+	if limit >= 0 {
+		want = uint64(limit)
+	}
+	lim := unix.Rlimit{Cur: want, Max: max(cur.Max, want)}
+	if err := unix.Prlimit(pid, unix.RLIMIT_CORE, &lim, nil); err == nil || want <= cur.Max {
+		return err
+	}
+
+	// Raising the hard limit takes CAP_SYS_RESOURCE, so settle for it
+	lim = unix.Rlimit{Cur: cur.Max, Max: cur.Max}
+	if err := unix.Prlimit(pid, unix.RLIMIT_CORE, &lim, nil); err != nil {
+		return err
+	}
+	return fmt.Errorf("rlimit_core capped at the hard limit of %d bytes", cur.Max)
+}
@@ -0,0 +1,175 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package supervisor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+func TestTail(t *testing.T) {
+	tests := []struct {
+		name  string
+		size  int
+		lines []string
+		want  []string
+	}{
+		{"empty", 3, nil, nil},
+		{"partial", 3, []string{"a", "b"}, []string{"a", "b"}},
+		{"full", 3, []string{"a", "b", "c"}, []string{"a", "b", "c"}},
+		{"wrapped", 3, []string{"a", "b", "c", "d", "e"}, []string{"c", "d", "e"}},
+		{"wrapped twice", 2, []string{"a", "b", "c", "d", "e"}, []string{"d", "e"}},
+		{"no lines kept", 0, []string{"a"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tail := newTail(tt.size)
+			for _, line := range tt.lines {
+				tail.add(line)
+			}
+			if got := tail.Lines(); !slices.Equal(got, tt.want) {
+				t.Errorf("Lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindCore(t *testing.T) {
+	since := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name  string
+		files map[string]time.Duration // age of each file
+		want  string
+	}{
+		{"default name", map[string]time.Duration{"core": 0}, "core"},
+		{"pid suffix", map[string]time.Duration{"core.1234": 0}, "core.1234"},
+		{"core pattern", map[string]time.Duration{"core.app.1234": 0}, "core.app.1234"},
+		{"other pid", map[string]time.Duration{"core.4321": 0, "core.12345": 0}, ""},
+		{"before the run", map[string]time.Duration{"core.1234": time.Hour}, ""},
+		{"newest", map[string]time.Duration{"core.1234": 30 * time.Second, "core.app.1234": 0}, "core.app.1234"},
+		{"not a core file", map[string]time.Duration{"dump.1234": 0}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, age := range tt.files {
+				path := filepath.Join(dir, name)
+				if err := os.WriteFile(path, []byte("core"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				mtime := time.Now().Add(-age)
+				if err := os.Chtimes(path, mtime, mtime); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			core, err := findCore(dir, 1234, since)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want != "" {
+				tt.want = filepath.Join(dir, tt.want)
+			}
+			if core != tt.want {
+				t.Errorf("findCore = %q, want %q", core, tt.want)
+			}
+		})
+	}
+
+	if _, err := findCore(filepath.Join(t.TempDir(), "missing"), 1234, since); err == nil {
+		t.Error("findCore in a missing directory succeeded")
+	}
+}
+
+func TestBinaryOf(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep is not installed")
+	}
+	tests := []struct {
+		name string
+		args []string
+		dir  string
+		want string
+	}{
+		{"simple command", []string{"/bin/sh", "-c", "sleep 30"}, "", sleep},
+		{"relative path", []string{"/bin/sh", "-c", "./bin/app --port 80"}, "/srv", "/srv/bin/app"},
+		{"absolute path", []string{"/usr/local/bin/app"}, "/srv", "/usr/local/bin/app"},
+		{"shell line", []string{"/bin/sh", "-c", "sleep 30 && sleep 30"}, "", "/bin/sh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &exec.Cmd{Path: tt.args[0], Args: tt.args, Dir: tt.dir}
+			if got := binaryOf(cmd); got != tt.want {
+				t.Errorf("binaryOf = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportCrash(t *testing.T) {
+	prog := &models.Program{
+		ID:      500,
+		Name:    "segv",
+		Exec:    "echo one; echo two; echo three; echo oops >&2; kill -SEGV $$",
+		Restart: models.RESTART_NO,
+	}
+	s := New(nil, "")
+	s.SetCrashConfig(CrashConfig{Directory: t.TempDir(), CoreDir: t.TempDir(), Lines: 2})
+	p := s.Process(prog)
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, p, models.EXITED)
+
+	var crashes []*models.Crash
+	deadline := time.Now().Add(5 * time.Second)
+	for len(crashes) == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		var err error
+		if crashes, err = models.Crashes(prog.ID, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(crashes) != 1 {
+		t.Fatalf("%d crash reports, want 1", len(crashes))
+	}
+
+	crash := crashes[0]
+	if crash.Signal != "SIGSEGV" || crash.Binary != "/bin/sh" || crash.BinarySHA256 == "" {
+		t.Errorf("crash = %s in %s (%s), want SIGSEGV in a hashed /bin/sh",
+			crash.Signal, crash.Binary, crash.BinarySHA256)
+	}
+	if !slices.Equal(crash.Stdout, []string{"two", "three"}) || !slices.Equal(crash.Stderr, []string{"oops"}) {
+		t.Errorf("output = %q and %q, want the last 2 lines", crash.Stdout, crash.Stderr)
+	}
+	if crash.CoreFile != "" || !strings.Contains(crash.Message, "no core file") {
+		t.Errorf("core %q (%s), want none", crash.CoreFile, crash.Message)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+// Exec runs a one-off command with the user, directory, environment and
+// time limits of a program, which may be a blank one. Every line the
+// command writes is logged and passed to output. It returns the started
+// run and a channel that receives the run once the command has finished.
+func (s *Supervisor) Exec(prog *models.Program, command []string, caller string, output func(stream, line string)) (*models.Run, <-chan *models.Run, error) {
+	if len(command) == 0 {
+		return nil, nil, errors.New("no command to run")
+	}
+
+	s.mu.Lock()
+	p := newProcess(prog, s)
+	s.mu.Unlock()
+	p.log = p.log.With().Str("caller", caller).Logger()
+
+	cmd := exec.Command(command[0], command[1:]...)
+	if err := p.prepare(cmd); err != nil {
+		return nil, nil, err
+	}
+	stdout := newLineWriter(func(line string) {
+		p.log.Debug().Str("stream", "stdout").Msg(line)
+		output("stdout", line)
+	})
+	stderr := newLineWriter(func(line string) {
+		p.log.Debug().Str("stream", "stderr").Msg(line)
+		output("stderr", line)
+	})
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+	run, err := models.StartRun(prog.ID, cmd.Process.Pid, commandLine(cmd), caller)
+	if err != nil {
+		p.log.Error().Err(err).Msg("failed to record run")
+		run = &models.Run{ProgramID: prog.ID, Pid: cmd.Process.Pid, Command: commandLine(cmd), Caller: caller}
+	}
+	p.log.Info().Int("pid", cmd.Process.Pid).Str("command", run.Command).Msg("ad-hoc run started")
+	started := *run
+
+	// Events of the run carry its ID, and a name when it has no program
+	p.mu.Lock()
+	p.adhoc, p.caller = run.ID, caller
+	if p.prog.Name == "" {
+		p.prog.Name = fmt.Sprintf("run-%d", run.ID)
+	}
+	p.cmd, p.state, p.done = cmd, models.RUNNING, make(chan struct{})
+	if prog.Deadline > 0 {
+		p.deadline = time.Now().Add(prog.Deadline)
+	}
+	if limit, name, ok := p.limit(); ok {
+		go p.enforce(cmd, p.done, limit, name)
+	}
+	p.mu.Unlock()
+
+	s.mu.Lock()
+	s.runs[run.ID] = p
+	s.mu.Unlock()
+
+	done := make(chan *models.Run, 1)
+	go func() {
+		e := exitOf(cmd, cmd.Wait())
+		stdout.flush()
+		stderr.flush()
+
+		s.mu.Lock()
+		delete(s.runs, run.ID)
+		s.mu.Unlock()
+
+		p.mu.Lock()
+		close(p.done)
+		p.cmd = nil
+		timeout := p.timeout
+		p.mu.Unlock()
+
+		run.ExitCode = e.Code
+		run.Reason = e.String()
+		if e.Signaled {
+			run.Signal = SignalName(e.Signal)
+		}
+		switch {
+		case timeout != "":
+			run.Result = models.RUN_TIMED_OUT
+			run.Reason = fmt.Sprintf("%s; %s exceeded", run.Reason, timeout)
+		case e.Signaled:
+			run.Result = models.RUN_KILLED
+		case e.Code == 0:
+			run.Result = models.RUN_SUCCEEDED
+		default:
+			run.Result = models.RUN_FAILED
+		}
+		if err := run.Finish(); err != nil {
+			p.log.Error().Err(err).Msg("failed to record run")
+		}
+		p.log.Info().Str("result", string(run.Result)).Msg(run.Reason)
+		done <- run
+	}()
+	return &started, done, nil
+}
+
+// Cancel stops an ad-hoc run that is still going, sending its process
+// group SIGTERM and SIGKILL if it has not exited after StopTimeout. Only
+// the caller that started the run may cancel it.
+func (s *Supervisor) Cancel(id uint, caller string) error {
+	s.mu.Lock()
+	p, ok := s.runs[id]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("run %d is not running", id)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.caller != caller {
+		return fmt.Errorf("run %d was started by another caller", id)
+	}
+	if p.cmd == nil {
+		return fmt.Errorf("run %d is not running", id)
+	}
+	p.log.Info().Msg("ad-hoc run cancelled")
+	p.signal(syscall.SIGTERM)
+
+	cmd, done := p.cmd, p.done
+	go func() {
+		select {
+		case <-done:
+		case <-time.After(StopTimeout):
+			p.mu.Lock()
+			if p.cmd == cmd {
+				p.log.Warn().Dur("timeout", StopTimeout).Msg("process did not stop in time, killing it")
+				p.signal(syscall.SIGKILL)
+			}
+			p.mu.Unlock()
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package supervisor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+func TestExec(t *testing.T) {
+	tests := []struct {
+		name    string
+		prog    models.Program
+		command []string
+		result  models.RunResult
+		lines   []string
+	}{
+		{"success", models.Program{}, []string{"echo", "hello"}, models.RUN_SUCCEEDED, []string{"stdout: hello"}},
+		{"failure", models.Program{}, []string{"sh", "-c", "echo oops >&2; exit 2"}, models.RUN_FAILED, []string{"stderr: oops"}},
+		{"max runtime", models.Program{MaxRuntime: 100 * time.Millisecond}, []string{"sleep", "5"}, models.RUN_TIMED_OUT, nil},
+		{"program environment", models.Program{ID: 7, Name: "env", Env: []string{"GREETING=hi"}}, []string{"sh", "-c", "echo $GREETING"}, models.RUN_SUCCEEDED, []string{"stdout: hi"}},
+	}
+
+	s := New(nil, "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []string
+			run, done, err := s.Exec(&tt.prog, tt.command, "alice", func(stream, line string) {
+				lines = append(lines, stream+": "+line)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if run.ID == 0 || run.Caller != "alice" || run.ProgramID != tt.prog.ID {
+				t.Errorf("run = %+v, want a recorded run of program %d by alice", run, tt.prog.ID)
+			}
+
+			select {
+			case run = <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("run did not finish")
+			}
+			if run.Result != tt.result {
+				t.Errorf("result = %s (%s), want %s", run.Result, run.Reason, tt.result)
+			}
+			if len(lines) != len(tt.lines) || (len(lines) > 0 && lines[0] != tt.lines[0]) {
+				t.Errorf("output = %q, want %q", lines, tt.lines)
+			}
+			if _, ok := s.Lookup(tt.prog.ID); ok {
+				t.Error("an ad-hoc run replaced the process of its program")
+			}
+		})
+	}
+}
+
+func TestCancel(t *testing.T) {
+	s := New(nil, "")
+	run, done, err := s.Exec(&models.Program{}, []string{"sleep", "30"}, "alice", func(stream, line string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Cancel(run.ID, "bob"); err == nil {
+		t.Error("bob cancelled the run of alice")
+	}
+	if err := s.Cancel(run.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case run = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled run did not finish")
+	}
+	if run.Result != models.RUN_KILLED || run.Signal != "SIGTERM" {
+		t.Errorf("run = %s (%s), want killed by SIGTERM", run.Result, run.Reason)
+	}
+	if err := s.Cancel(run.ID, "alice"); err == nil {
+		t.Error("cancelled a finished run")
+	}
+}
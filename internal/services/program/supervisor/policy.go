@@ -0,0 +1,157 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package supervisor
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+	"golang.org/x/sys/unix"
+)
+
+// exit describes how a process ended
+type exit struct {
+	Code     int
+	Signal   syscall.Signal
+	Signaled bool
+	Err      error
+}
+
+func exitOf(cmd *exec.Cmd, err error) exit {
+	state := cmd.ProcessState
+	if state == nil {
+		return exit{Code: -1, Err: err}
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return exit{Code: -1, Signal: ws.Signal(), Signaled: true}
+	}
+	return exit{Code: state.ExitCode()}
+}
+
+func (e exit) String() string {
+	switch {
+	case e.Err != nil:
+		return fmt.Sprintf("process failed: %v", e.Err)
+	case e.Signaled:
+		return fmt.Sprintf("killed by signal %s", SignalName(e.Signal))
+	default:
+		return fmt.Sprintf("exited with code %d", e.Code)
+	}
+}
+
+// decision is the outcome of applying a program's restart rules to an exit
+type decision struct {
+	Result  models.RunResult
+	Restart bool
+	Reason  string
+}
+
+// decide applies the restart rules of a program to the way it exited
+func decide(prog *models.Program, e exit) decision {
+	if !e.Signaled && slices.Contains(prog.DisableExitCodes, e.Code) {
+		return decision{models.RUN_DISABLED, false, fmt.Sprintf("exit code %d disables the program", e.Code)}
+	}
+
+	result := models.RUN_FAILED
+	switch {
+	case e.Signaled:
+		result = models.RUN_KILLED
+	case slices.Contains(successCodes(prog), e.Code):
+		result = models.RUN_SUCCEEDED
+	}
+
+	if e.Signaled {
+		for _, name := range prog.NoRestartOnSignals {
+			if sig, err := ParseSignal(name); err == nil && sig == e.Signal {
+				return decision{result, false, fmt.Sprintf("no restart on signal %s", SignalName(sig))}
+			}
+		}
+	}
+
+	switch prog.Restart {
+	case models.RESTART_NO:
+		return decision{result, false, "restart policy is no"}
+	case models.RESTART_ALWAYS:
+		return decision{result, true, "restart policy is always"}
+	}
+
+	// on-failure is the default policy
+	if result == models.RUN_SUCCEEDED {
+		return decision{result, false, fmt.Sprintf("exit code %d is a success", e.Code)}
+	}
+	if len(prog.RestartOnExitCodes) > 0 && !e.Signaled {
+		if !slices.Contains(prog.RestartOnExitCodes, e.Code) {
+			return decision{result, false, fmt.Sprintf("exit code %d is not in restart_on_exit_codes", e.Code)}
+		}
+		return decision{result, true, fmt.Sprintf("exit code %d is in restart_on_exit_codes", e.Code)}
+	}
+	return decision{result, true, "restart policy is on-failure"}
+}
+
+// decideTimeout applies the restart policy of a program to a run that was
+// stopped for exceeding a time limit. A timeout counts as a failure, but
+// nothing is restarted once the program's deadline has passed.
+func decideTimeout(prog *models.Program, limit string, expired bool) decision {
+	reason := limit + " exceeded"
+	switch {
+	case expired:
+		return decision{models.RUN_TIMED_OUT, false, reason}
+	case prog.Restart == models.RESTART_NO:
+		return decision{models.RUN_TIMED_OUT, false, reason + "; restart policy is no"}
+	}
+	return decision{models.RUN_TIMED_OUT, true, reason}
+}
+
+func successCodes(prog *models.Program) []int {
+	if len(prog.SuccessExitCodes) == 0 {
+		return []int{0}
+	}
+	return prog.SuccessExitCodes
+}
+
+// ParseSignal parses a signal given as a name (HUP, SIGHUP) or number
+func ParseSignal(name string) (syscall.Signal, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if num, err := strconv.Atoi(name); err == nil {
+		if unix.SignalName(syscall.Signal(num)) == "" {
+			return 0, fmt.Errorf("unknown signal: %s", name)
+		}
+		return syscall.Signal(num), nil
+	}
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if sig := unix.SignalNum(name); sig != 0 {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal: %s", name)
+}
+
+// SignalName returns the name of a signal, such as SIGHUP
+func SignalName(sig syscall.Signal) string {
+	if name := unix.SignalName(sig); name != "" {
+		return name
+	}
+	return sig.String()
+}
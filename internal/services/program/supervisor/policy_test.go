@@ -0,0 +1,90 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package supervisor
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		name    string
+		prog    models.Program
+		exit    exit
+		result  models.RunResult
+		restart bool
+	}{
+		{"success", models.Program{}, exit{Code: 0}, models.RUN_SUCCEEDED, false},
+		{"failure", models.Program{}, exit{Code: 1}, models.RUN_FAILED, true},
+		{"killed", models.Program{}, exit{Code: -1, Signal: syscall.SIGKILL, Signaled: true}, models.RUN_KILLED, true},
+		{"on-failure success", models.Program{Restart: models.RESTART_ON_FAILURE}, exit{Code: 0}, models.RUN_SUCCEEDED, false},
+		{"no", models.Program{Restart: models.RESTART_NO}, exit{Code: 1}, models.RUN_FAILED, false},
+		{"always", models.Program{Restart: models.RESTART_ALWAYS}, exit{Code: 0}, models.RUN_SUCCEEDED, true},
+		{"success exit code", models.Program{SuccessExitCodes: []int{0, 2}}, exit{Code: 2}, models.RUN_SUCCEEDED, false},
+		{"zero not a success", models.Program{SuccessExitCodes: []int{2}}, exit{Code: 0}, models.RUN_FAILED, true},
+		{"restart on exit code", models.Program{RestartOnExitCodes: []int{3}}, exit{Code: 3}, models.RUN_FAILED, true},
+		{"not a restart exit code", models.Program{RestartOnExitCodes: []int{3}}, exit{Code: 4}, models.RUN_FAILED, false},
+		{"restart codes ignore signals", models.Program{RestartOnExitCodes: []int{3}}, exit{Code: -1, Signal: syscall.SIGSEGV, Signaled: true}, models.RUN_KILLED, true},
+		{"no restart on signal", models.Program{Restart: models.RESTART_ALWAYS, NoRestartOnSignals: []string{"TERM"}}, exit{Code: -1, Signal: syscall.SIGTERM, Signaled: true}, models.RUN_KILLED, false},
+		{"other signal", models.Program{NoRestartOnSignals: []string{"SIGTERM"}}, exit{Code: -1, Signal: syscall.SIGKILL, Signaled: true}, models.RUN_KILLED, true},
+		{"disable exit code", models.Program{Restart: models.RESTART_ALWAYS, DisableExitCodes: []int{78}}, exit{Code: 78}, models.RUN_DISABLED, false},
+		{"disable codes ignore signals", models.Program{DisableExitCodes: []int{78}}, exit{Code: -1, Signal: syscall.SIGKILL, Signaled: true}, models.RUN_KILLED, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := decide(&tt.prog, tt.exit)
+			if d.Result != tt.result || d.Restart != tt.restart {
+				t.Errorf("decide = %s, restart %v (%s), want %s, restart %v",
+					d.Result, d.Restart, d.Reason, tt.result, tt.restart)
+			}
+			if d.Reason == "" {
+				t.Error("decision has no reason")
+			}
+		})
+	}
+}
+
+func TestDecideTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  models.RestartPolicy
+		expired bool
+		restart bool
+	}{
+		{"default", "", false, true},
+		{"on-failure", models.RESTART_ON_FAILURE, false, true},
+		{"always", models.RESTART_ALWAYS, false, true},
+		{"no", models.RESTART_NO, false, false},
+		{"deadline passed", models.RESTART_ALWAYS, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := decideTimeout(&models.Program{Restart: tt.policy}, "max_runtime of 1s", tt.expired)
+			if d.Result != models.RUN_TIMED_OUT || d.Restart != tt.restart {
+				t.Errorf("decideTimeout = %s, restart %v, want %s, restart %v",
+					d.Result, d.Restart, models.RUN_TIMED_OUT, tt.restart)
+			}
+		})
+	}
+}
@@ -0,0 +1,761 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rangertaha/hxe/internal/db"
+	"github.com/rangertaha/hxe/internal/services"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// StartGrace is how long a process must stay up before it is READY
+	StartGrace = 1 * time.Second
+
+	// StopTimeout is how long a process gets to exit before it is killed
+	StopTimeout = 10 * time.Second
+
+	// MaxBackoff caps the delay between start retries
+	MaxBackoff = 30 * time.Second
+
+	// DefaultRetries is how many times a program that sets no retries is
+	// restarted before it becomes FATAL, unless its restart policy is no
+	DefaultRetries = 3
+)
+
+// Process supervises a single program and drives its state machine
+type Process struct {
+	mu       sync.Mutex
+	prog     models.Program
+	state    models.State
+	message  string
+	progress int32
+	started  time.Time
+	retries  int
+	deadline time.Time
+	timeout  string
+	cmd      *exec.Cmd
+	run      *models.Run
+	adhoc    uint
+	caller   string
+	crash    CrashConfig
+	stdout   *tail
+	stderr   *tail
+	done     chan struct{}
+	changed  chan struct{}
+	nc       *nats.Conn
+	agent    string
+	log      zerolog.Logger
+}
+
+// newProcess creates the process of a program. The supervisor's lock
+// must be held.
+func newProcess(prog *models.Program, s *Supervisor) *Process {
+	return &Process{
+		prog:    *prog,
+		state:   models.STOPPED,
+		crash:   s.crash,
+		changed: make(chan struct{}),
+		nc:      s.nc,
+		agent:   s.agent,
+		log:     s.log.With().Str("program", prog.Name).Logger(),
+	}
+}
+
+// update replaces the program definition used by the next start
+func (p *Process) update(prog *models.Program) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prog = *prog
+}
+
+func (p *Process) Name() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.prog.Name
+}
+
+func (p *Process) State() int32 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return int32(p.state)
+}
+
+func (p *Process) Uptime() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.uptime()
+}
+
+func (p *Process) Message() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.message
+}
+
+func (p *Process) Progress() int32 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.progress
+}
+
+// Snapshot returns the current status of the process
+func (p *Process) Snapshot() *models.Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := &models.Status{
+		ProgramID: p.prog.ID,
+		Name:      p.prog.Name,
+		State:     p.state,
+		Uptime:    p.uptime(),
+		Message:   p.message,
+		Progress:  p.progress,
+	}
+	if p.cmd != nil && p.cmd.Process != nil {
+		status.Pid = p.cmd.Process.Pid
+	}
+	return status
+}
+
+func (p *Process) uptime() time.Duration {
+	if p.state != models.RUNNING && p.state != models.READY {
+		return 0
+	}
+	return time.Since(p.started)
+}
+
+// Start the program unless it is already active
+func (p *Process) Start() error {
+	return p.start("start requested")
+}
+
+// Stop the program, waiting for the process to exit
+func (p *Process) Stop() error {
+	return p.stop("stop requested")
+}
+
+// Restart the program
+func (p *Process) Restart() error {
+	return p.restart("restart requested")
+}
+
+// Signal sends a signal to the main process, or to its whole process
+// group when group is set
+func (p *Process) Signal(sig syscall.Signal, group bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cmd == nil || p.cmd.Process == nil {
+		return fmt.Errorf("%s is not running", p.prog.Name)
+	}
+	pid := p.cmd.Process.Pid
+	if group {
+		pid = -pid
+	}
+	if err := syscall.Kill(pid, sig); err != nil {
+		return fmt.Errorf("failed to send %s to %s: %w", SignalName(sig), p.prog.Name, err)
+	}
+	p.log.Info().Str("signal", SignalName(sig)).Bool("group", group).Msg("signal sent")
+	return nil
+}
+
+// Reload sends the program's reload signal to its main process
+func (p *Process) Reload() error {
+	p.mu.Lock()
+	name := p.prog.ReloadSignal
+	p.mu.Unlock()
+
+	sig := syscall.SIGHUP
+	if name != "" {
+		var err error
+		if sig, err = ParseSignal(name); err != nil {
+			return fmt.Errorf("invalid reload signal: %w", err)
+		}
+	}
+	return p.Signal(sig, false)
+}
+
+// WaitReady waits until the program is READY, failing as soon as it
+// ends up in a state it will not become ready from on its own
+func (p *Process) WaitReady(ctx context.Context) error {
+	for {
+		p.mu.Lock()
+		state, message, changed := p.state, p.message, p.changed
+		p.mu.Unlock()
+
+		switch state {
+		case models.READY:
+			return nil
+		case models.STOPPED, models.EXITED, models.BACKOFF, models.FATAL:
+			return fmt.Errorf("%s is %s: %s", p.Name(), state, message)
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return fmt.Errorf("%s did not become ready: %w", p.Name(), ctx.Err())
+		}
+	}
+}
+
+// setProgress reports the progress of an operation on the program
+func (p *Process) setProgress(progress int32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.progress = progress
+}
+
+func (p *Process) start(reason string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.state.Active() {
+		return fmt.Errorf("%s is already %s", p.prog.Name, p.state)
+	}
+	p.retries = 0
+	p.deadline = time.Time{}
+	if p.prog.Deadline > 0 {
+		p.deadline = time.Now().Add(p.prog.Deadline)
+	}
+	return p.spawn(reason)
+}
+
+func (p *Process) restart(reason string) error {
+	if err := p.stop(reason); err != nil {
+		return err
+	}
+	return p.start(reason)
+}
+
+func (p *Process) stop(reason string) error {
+	p.mu.Lock()
+	switch p.state {
+	case models.STOPPED:
+		p.mu.Unlock()
+		return nil
+	case models.EXITED, models.BACKOFF, models.FATAL:
+		err := p.transition(models.STOPPED, reason)
+		p.mu.Unlock()
+		return err
+	case models.STARTING:
+		if err := p.transition(models.STOPPING, reason); err != nil {
+			p.mu.Unlock()
+			return err
+		}
+	case models.RUNNING, models.READY:
+		if err := p.transition(models.STOPPING, reason); err != nil {
+			p.mu.Unlock()
+			return err
+		}
+		p.signal(syscall.SIGTERM)
+	}
+	done := p.done
+	p.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(StopTimeout):
+		p.mu.Lock()
+		p.log.Warn().Dur("timeout", StopTimeout).Msg("process did not stop in time, killing it")
+		p.signal(syscall.SIGKILL)
+		p.mu.Unlock()
+		<-done
+	}
+	return nil
+}
+
+// spawn starts a new process for the program. The lock must be held;
+// it is released while the pre-exec hook runs, so a stop requested in
+// the meantime aborts the start.
+func (p *Process) spawn(reason string) error {
+	if err := p.transition(models.STARTING, reason); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	p.done = done
+
+	if p.prog.PreExec != "" {
+		prog := p.prog
+		p.mu.Unlock()
+		err := p.hook(&prog, prog.PreExec)
+		p.mu.Lock()
+
+		if p.state != models.STARTING {
+			close(done)
+			p.transition(models.STOPPED, "stopped before the process started")
+			return fmt.Errorf("%s was stopped before it started", p.prog.Name)
+		}
+		if err != nil {
+			close(done)
+			p.fail(fmt.Sprintf("pre-exec failed: %v", err))
+			return err
+		}
+	}
+
+	m := newMatcher(p, p.prog.Triggers)
+	p.stdout, p.stderr = newTail(p.crash.Lines), newTail(p.crash.Lines)
+	cmd, err := p.command(m)
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		close(done)
+		p.fail(fmt.Sprintf("failed to start: %v", err))
+		return err
+	}
+
+	if p.prog.RlimitCore != nil {
+		if err := setCoreLimit(cmd.Process.Pid, *p.prog.RlimitCore); err != nil {
+			p.log.Warn().Err(err).Msg("failed to apply rlimit_core")
+		}
+	}
+
+	p.cmd = cmd
+	p.started = time.Now()
+	p.timeout = ""
+	if p.run, err = models.StartRun(p.prog.ID, cmd.Process.Pid, commandLine(cmd), ""); err != nil {
+		p.log.Error().Err(err).Msg("failed to record run")
+	}
+	p.transition(models.RUNNING, fmt.Sprintf("process started with pid %d", cmd.Process.Pid))
+
+	go p.wait(cmd, done)
+	if m != nil {
+		go m.run(done)
+	}
+	if !readyTrigger(p.prog.Triggers) {
+		go p.ready(cmd, done)
+	}
+	if limit, name, ok := p.limit(); ok {
+		go p.enforce(cmd, done, limit, name)
+	}
+	return nil
+}
+
+// fail moves a process that could not come up, or is being restarted,
+// into BACKOFF and schedules a retry or gives up with FATAL. The lock
+// must be held.
+func (p *Process) fail(reason string) {
+	p.transition(models.BACKOFF, reason)
+	if p.expired() {
+		p.transition(models.FATAL, fmt.Sprintf("deadline of %s exceeded", p.prog.Deadline))
+		return
+	}
+	limit := p.maxRetries()
+	if p.retries >= limit {
+		p.transition(models.FATAL, fmt.Sprintf("giving up after %d retries", p.retries))
+		return
+	}
+
+	p.retries++
+	delay := backoff(p.retries)
+	p.log.Debug().Dur("delay", delay).Int("retry", p.retries).Msg("scheduling restart")
+	time.AfterFunc(delay, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		switch {
+		case p.state != models.BACKOFF:
+		case p.expired():
+			p.transition(models.FATAL, fmt.Sprintf("deadline of %s exceeded", p.prog.Deadline))
+		default:
+			p.spawn(fmt.Sprintf("retry %d of %d", p.retries, limit))
+		}
+	})
+}
+
+// maxRetries returns how many times the program is restarted in a row
+// before it becomes FATAL. The count is reset whenever it becomes READY.
+// The lock must be held.
+func (p *Process) maxRetries() int {
+	switch {
+	case p.prog.Retries > 0:
+		return p.prog.Retries
+	case p.prog.Restart == models.RESTART_NO:
+		return 0
+	}
+	return DefaultRetries
+}
+
+// wait reaps the process and records how it ended. The post-exec hook
+// runs before anyone waiting on done is released, but without the lock,
+// so the state is only decided once it has finished.
+func (p *Process) wait(cmd *exec.Cmd, done chan struct{}) {
+	err := cmd.Wait()
+
+	p.mu.Lock()
+	prog, current := p.prog, p.cmd == cmd
+	if current {
+		p.cmd = nil
+	}
+	p.mu.Unlock()
+	if current {
+		if err := p.hook(&prog, prog.PostExec); err != nil {
+			p.log.Error().Err(err).Msg("post-exec failed")
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer close(done)
+
+	if !current {
+		return
+	}
+
+	for _, w := range []io.Writer{cmd.Stdout, cmd.Stderr} {
+		if w, ok := w.(*lineWriter); ok {
+			w.flush()
+		}
+	}
+
+	e := exitOf(cmd, err)
+	if e.crashed() {
+		p.reportCrash(cmd, p.run, e)
+	}
+	d := decision{Result: models.RUN_STOPPED, Reason: "stopped by request"}
+	switch {
+	case p.state == models.STOPPING:
+	case p.timeout != "":
+		d = decideTimeout(&p.prog, p.timeout, p.expired())
+	default:
+		d = decide(&p.prog, e)
+	}
+	p.finish(e, d)
+
+	reason := e.String()
+	switch {
+	case p.state == models.STOPPING:
+		p.transition(models.STOPPED, reason)
+	case d.Restart && p.state == models.RUNNING:
+		p.fail(fmt.Sprintf("%s; %s", reason, d.Reason))
+	case d.Restart:
+		p.transition(models.EXITED, reason)
+		p.fail(d.Reason)
+	default:
+		p.transition(models.EXITED, fmt.Sprintf("%s; %s", reason, d.Reason))
+	}
+
+	if d.Result == models.RUN_DISABLED {
+		p.disable()
+	}
+}
+
+// finish records the end of the current run. The lock must be held.
+func (p *Process) finish(e exit, d decision) {
+	if p.run == nil {
+		return
+	}
+	p.run.ExitCode = e.Code
+	if e.Signaled {
+		p.run.Signal = SignalName(e.Signal)
+	}
+	p.run.Result = d.Result
+	p.run.Restart = d.Restart
+	p.run.Reason = d.Reason
+	if err := p.run.Finish(); err != nil {
+		p.log.Error().Err(err).Msg("failed to record run")
+	}
+	p.run = nil
+}
+
+// disable marks the program as intentionally disabled. The lock must be held.
+func (p *Process) disable() {
+	p.prog.Disabled = true
+	if err := db.DB.Model(&models.Program{}).Where("id = ?", p.prog.ID).
+		Update("disabled", true).Error; err != nil {
+		p.log.Error().Err(err).Msg("failed to disable program")
+	}
+	p.log.Warn().Msg("program disabled by its exit code")
+}
+
+// ready promotes a process that stayed up for StartGrace to READY,
+// unless a trigger decides when the program is ready
+func (p *Process) ready(cmd *exec.Cmd, done chan struct{}) {
+	timer := time.NewTimer(StartGrace)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return
+	case <-timer.C:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cmd == cmd && p.state == models.RUNNING {
+		p.retries = 0
+		p.transition(models.READY, fmt.Sprintf("process up for %s", StartGrace))
+	}
+}
+
+// limit returns how long the next run may take and which limit that is,
+// reporting false when the program has no time limit. The lock must be held.
+func (p *Process) limit() (time.Duration, string, bool) {
+	limit, name := p.prog.MaxRuntime, fmt.Sprintf("max_runtime of %s", p.prog.MaxRuntime)
+	if !p.deadline.IsZero() {
+		if left := time.Until(p.deadline); limit <= 0 || left < limit {
+			limit, name = max(left, 0), fmt.Sprintf("deadline of %s", p.prog.Deadline)
+		}
+	}
+	return limit, name, limit > 0 || !p.deadline.IsZero()
+}
+
+// expired reports whether the program's deadline has passed. The lock
+// must be held.
+func (p *Process) expired() bool {
+	return !p.deadline.IsZero() && !time.Now().Before(p.deadline)
+}
+
+// enforce stops a run that exceeds its time limit with the graceful stop
+// sequence, leaving what happens next to the restart policy
+func (p *Process) enforce(cmd *exec.Cmd, done chan struct{}, limit time.Duration, name string) {
+	timer := time.NewTimer(limit)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return
+	case <-timer.C:
+	}
+
+	p.mu.Lock()
+	if p.cmd != cmd || (p.state != models.RUNNING && p.state != models.READY) {
+		p.mu.Unlock()
+		return
+	}
+	p.timeout = name
+	p.log.Warn().Msgf("%s exceeded, stopping process", name)
+	event := &models.Event{
+		Type:      models.EVENT_TIMEOUT,
+		ProgramID: p.prog.ID,
+		Name:      p.prog.Name,
+		RunID:     p.adhoc,
+		Reason:    name + " exceeded",
+		Timestamp: time.Now().UnixMilli(),
+	}
+	p.event(event)
+	p.signal(syscall.SIGTERM)
+	p.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(StopTimeout):
+		p.mu.Lock()
+		if p.cmd == cmd {
+			p.log.Warn().Dur("timeout", StopTimeout).Msg("process did not stop in time, killing it")
+			p.signal(syscall.SIGKILL)
+		}
+		p.mu.Unlock()
+	}
+}
+
+// transition moves the process to a new state and persists the change.
+// The lock must be held.
+func (p *Process) transition(to models.State, reason string) error {
+	from := p.state
+	if !from.CanTransition(to) {
+		return fmt.Errorf("%s: illegal state transition %s -> %s", p.prog.Name, from, to)
+	}
+
+	p.state = to
+	p.message = reason
+	close(p.changed)
+	p.changed = make(chan struct{})
+	if _, err := models.RecordTransition(p.prog.ID, from, to, reason); err != nil {
+		p.log.Error().Err(err).Msg("failed to persist state transition")
+	}
+
+	p.log.Info().Str("from", from.String()).Str("to", to.String()).Msg(reason)
+	p.event(&models.Event{
+		Type:      models.EVENT_STATE,
+		ProgramID: p.prog.ID,
+		Name:      p.prog.Name,
+		From:      &from,
+		To:        &to,
+		Reason:    reason,
+		Timestamp: time.Now().UnixMilli(),
+	})
+	return nil
+}
+
+// signal sends a signal to the process group. The lock must be held.
+func (p *Process) signal(sig syscall.Signal) {
+	if p.cmd == nil || p.cmd.Process == nil {
+		return
+	}
+	if err := syscall.Kill(-p.cmd.Process.Pid, sig); err != nil {
+		p.log.Error().Err(err).Str("signal", sig.String()).Msg("failed to signal process")
+	}
+}
+
+// command builds the command for the program, feeding its output to
+// the matcher that runs its triggers and publishes it
+func (p *Process) command(m *matcher) (cmd *exec.Cmd, err error) {
+	switch {
+	case p.prog.Exec != "" && len(p.prog.Args) == 0:
+		cmd = exec.Command("/bin/sh", "-c", p.prog.Exec)
+	case p.prog.Exec != "":
+		cmd = exec.Command(p.prog.Exec, p.prog.Args...)
+	case p.prog.Path != "":
+		cmd = exec.Command(p.prog.Path, p.prog.Args...)
+	default:
+		return nil, errors.New("program has no command to execute")
+	}
+
+	if err = p.prepare(cmd); err != nil {
+		return nil, err
+	}
+
+	stdout, stderr := p.stdout, p.stderr
+	cmd.Stdout = newLineWriter(func(line string) {
+		p.log.Debug().Str("stream", "stdout").Msg(line)
+		stdout.add(line)
+		m.feed("stdout", line)
+	})
+	cmd.Stderr = newLineWriter(func(line string) {
+		p.log.Debug().Str("stream", "stderr").Msg(line)
+		stderr.add(line)
+		m.feed("stderr", line)
+	})
+	return cmd, nil
+}
+
+// commandLine returns the command a process was started with, without
+// the shell a program's exec line runs in
+func commandLine(cmd *exec.Cmd) string {
+	if len(cmd.Args) == 3 && cmd.Args[0] == "/bin/sh" && cmd.Args[1] == "-c" {
+		return cmd.Args[2]
+	}
+	return strings.Join(cmd.Args, " ")
+}
+
+// event publishes an event on its subject, namespaced by the agent ID
+func (p *Process) event(event *models.Event) {
+	p.publish(services.Subject(p.agent, event.Subject()), event)
+}
+
+// output publishes a line of output on its subject, namespaced by the
+// agent ID
+func (p *Process) output(line *models.Line) {
+	p.publish(services.Subject(p.agent, line.Subject()), line)
+}
+
+// publish sends an event or line of output to the bus
+func (p *Process) publish(subject string, v any) {
+	if p.nc == nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		p.log.Error().Err(err).Msg("failed to marshal event")
+		return
+	}
+	if err := p.nc.Publish(subject, data); err != nil {
+		p.log.Error().Err(err).Str("subject", subject).Msg("failed to publish event")
+	}
+}
+
+// hook runs a pre or post exec script in the environment of prog. It
+// must be called without the lock, as the script may take a while.
+func (p *Process) hook(prog *models.Program, script string) error {
+	if script == "" {
+		return nil
+	}
+
+	cmd := exec.Command("/bin/sh", "-c", script)
+	if err := prepare(cmd, prog); err != nil {
+		return err
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, out)
+	}
+	return nil
+}
+
+// prepare applies the program's directory, environment and credentials
+func (p *Process) prepare(cmd *exec.Cmd) error {
+	return prepare(cmd, &p.prog)
+}
+
+// prepare applies the directory, environment and credentials of prog
+func prepare(cmd *exec.Cmd, prog *models.Program) error {
+	cred, err := credential(prog.User, prog.Group)
+	if err != nil {
+		return err
+	}
+
+	cmd.Dir = prog.Dir
+	cmd.Env = append(os.Environ(), prog.Env...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Credential: cred}
+	return nil
+}
+
+// credential resolves the user and group a program runs as
+func credential(username, group string) (*syscall.Credential, error) {
+	if username == "" && group == "" {
+		return nil, nil
+	}
+
+	uid, gid := os.Getuid(), os.Getgid()
+	if username != "" {
+		u, err := user.Lookup(username)
+		if err != nil {
+			return nil, err
+		}
+		uid, _ = strconv.Atoi(u.Uid)
+		gid, _ = strconv.Atoi(u.Gid)
+	}
+	if group != "" {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			return nil, err
+		}
+		gid, _ = strconv.Atoi(g.Gid)
+	}
+
+	if uid == os.Getuid() && gid == os.Getgid() {
+		return nil, nil
+	}
+	return &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}, nil
+}
+
+// backoff returns the delay before the given retry
+func backoff(retry int) time.Duration {
+	delay := time.Duration(1<<uint(retry-1)) * time.Second
+	if delay > MaxBackoff || delay <= 0 {
+		return MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,256 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+// waitState waits for a process to reach a state
+func waitState(t *testing.T, p *Process, state models.State) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for models.State(p.State()) != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s is %s, want %s", p.Name(), models.State(p.State()), state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// history returns the states a program moved through, oldest first
+func history(t *testing.T, id uint) (states []models.State) {
+	t.Helper()
+	transitions, err := models.History(id, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tr := range transitions {
+		states = append(states, tr.To)
+	}
+	slices.Reverse(states)
+	return states
+}
+
+func TestProcessTransitions(t *testing.T) {
+	tests := []struct {
+		name string
+		prog models.Program
+		want []models.State
+	}{
+		{
+			name: "exit without restart",
+			prog: models.Program{Exec: "exit 3", Restart: models.RESTART_NO},
+			want: []models.State{models.STARTING, models.RUNNING, models.EXITED},
+		},
+		{
+			name: "failure without restarts",
+			prog: models.Program{Exec: "exit 1", Restart: models.RESTART_NO},
+			want: []models.State{models.STARTING, models.RUNNING, models.EXITED},
+		},
+		{
+			name: "failure exhausting retries",
+			prog: models.Program{Exec: "exit 1", Retries: 1},
+			want: []models.State{models.STARTING, models.RUNNING, models.BACKOFF, models.STARTING, models.RUNNING, models.BACKOFF, models.FATAL},
+		},
+		{
+			name: "pre-exec failure",
+			prog: models.Program{PreExec: "exit 1", Exec: "sleep 30", Restart: models.RESTART_NO},
+			want: []models.State{models.STARTING, models.BACKOFF, models.FATAL},
+		},
+		{
+			name: "no command",
+			prog: models.Program{Restart: models.RESTART_NO},
+			want: []models.State{models.STARTING, models.BACKOFF, models.FATAL},
+		},
+	}
+
+	s := New(nil, "")
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog := tt.prog
+			prog.ID, prog.Name = uint(100+i), tt.name
+			p := s.Process(&prog)
+
+			p.Start()
+			last := tt.want[len(tt.want)-1]
+			waitState(t, p, last)
+			if got := history(t, prog.ID); !slices.Equal(got, tt.want) {
+				t.Errorf("transitions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessRestartAlways(t *testing.T) {
+	prog := &models.Program{ID: 300, Name: "always", Exec: "sleep 1.2", Restart: models.RESTART_ALWAYS}
+	p := New(nil, "").Process(prog)
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	want := []models.State{models.STARTING, models.RUNNING, models.READY, models.EXITED, models.BACKOFF, models.STARTING}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		got := history(t, prog.ID)
+		if len(got) >= len(want) {
+			if !slices.Equal(got[:len(want)], want) {
+				t.Fatalf("transitions = %v, want %v", got, want)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("transitions = %v, want %v", got, want)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestProcessStartStop(t *testing.T) {
+	prog := &models.Program{ID: 1, Name: "sleeper", Exec: "sleep 30"}
+	p := New(nil, "").Process(prog)
+
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Start(); err == nil {
+		t.Error("starting a running program succeeded")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.WaitReady(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if pid := p.Snapshot().Pid; pid == 0 {
+		t.Error("ready program has no pid")
+	}
+
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	want := []models.State{models.STARTING, models.RUNNING, models.READY, models.STOPPING, models.STOPPED}
+	if got := history(t, prog.ID); !slices.Equal(got, want) {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+}
+
+func TestProcessOutput(t *testing.T) {
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns.Start()
+	defer ns.Shutdown()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+	nc, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	prog := &models.Program{ID: 200, Name: "echo", Exec: "echo a; echo b; echo c", Restart: models.RESTART_NO}
+	sub, err := nc.SubscribeSync((&models.Line{Name: prog.Name, Stream: "stdout"}).Subject())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	p := New(nc, "").Process(prog)
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for range 3 {
+		msg, err := sub.NextMsg(5 * time.Second)
+		if err != nil {
+			t.Fatalf("lines = %v: %v", got, err)
+		}
+		var line models.Line
+		if err := json.Unmarshal(msg.Data, &line); err != nil {
+			t.Fatal(err)
+		}
+		if line.ProgramID != prog.ID || line.Timestamp == 0 {
+			t.Errorf("line = %+v, want program %d with a timestamp", line, prog.ID)
+		}
+		got = append(got, line.Line)
+	}
+	if want := []string{"a", "b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("lines = %v, want %v", got, want)
+	}
+	waitState(t, p, models.EXITED)
+}
+
+func TestProcessStopDuringPreExec(t *testing.T) {
+	prog := &models.Program{ID: 2, Name: "slow-hook", PreExec: "sleep 1", Exec: "sleep 30"}
+	p := New(nil, "").Process(prog)
+
+	started := make(chan error, 1)
+	go func() { started <- p.Start() }()
+
+	// the state can be read while the hook runs, as it runs without the lock
+	waitState(t, p, models.STARTING)
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-started; err == nil {
+		t.Error("start succeeded after the program was stopped")
+	}
+
+	status := p.Snapshot()
+	if status.State != models.STOPPED || status.Pid != 0 {
+		t.Errorf("status = %s with pid %d, want STOPPED without a process", status.State, status.Pid)
+	}
+	want := []models.State{models.STARTING, models.STOPPING, models.STOPPED}
+	if got := history(t, prog.ID); !slices.Equal(got, want) {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+}
+
+func TestProcessPostExecWithoutLock(t *testing.T) {
+	prog := &models.Program{ID: 3, Name: "slow-post", Exec: "exit 0", PostExec: "sleep 1", Restart: models.RESTART_NO}
+	p := New(nil, "").Process(prog)
+
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	begin := time.Now()
+	if state := models.State(p.State()); state != models.RUNNING {
+		t.Errorf("state while post-exec runs = %s, want RUNNING", state)
+	}
+	if elapsed := time.Since(begin); elapsed > 100*time.Millisecond {
+		t.Errorf("reading the state took %s while post-exec ran", elapsed)
+	}
+	waitState(t, p, models.EXITED)
+}
@@ -0,0 +1,219 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+// clockTicks is the kernel's USER_HZ, the unit of CPU times in /proc
+const clockTicks = 100
+
+// procDir is where the process information is read from
+var procDir = "/proc"
+
+// stat is what the process tree needs from /proc/<pid>/stat
+type stat struct {
+	pid, ppid, pgid int
+	state           string
+	comm            string
+	cpu             float64 // seconds
+	started         float64 // seconds after boot
+	rss             int64   // bytes
+}
+
+// Tree returns the processes running under a program: the descendants of
+// its main process, and processes left in its process group by parents
+// that have exited, ordered depth first
+func (s *Supervisor) Tree(prog *models.Program) ([]*models.ProcInfo, error) {
+	status := s.Status(prog)
+	if status.Pid == 0 {
+		return nil, fmt.Errorf("%s is not running", prog.Name)
+	}
+	return processTree(status.Pid)
+}
+
+func processTree(root int) ([]*models.ProcInfo, error) {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processes: %w", err)
+	}
+	uptime, err := readUptime()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := map[int]*stat{}
+	children := map[int][]int{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		st, err := readStat(pid)
+		if err != nil {
+			// The process exited while we were looking
+			continue
+		}
+		stats[pid] = st
+		children[st.ppid] = append(children[st.ppid], pid)
+	}
+	if _, ok := stats[root]; !ok {
+		return nil, fmt.Errorf("process %d is not running", root)
+	}
+
+	var (
+		tree []*models.ProcInfo
+		seen = map[int]bool{}
+		walk func(pid, depth int)
+	)
+	walk = func(pid, depth int) {
+		seen[pid] = true
+		tree = append(tree, procInfo(stats[pid], uptime, depth))
+		kids := children[pid]
+		slices.Sort(kids)
+		for _, kid := range kids {
+			walk(kid, depth+1)
+		}
+	}
+	walk(root, 0)
+
+	// Processes that were reparented away from the tree but are still in
+	// the program's process group, such as double forking daemons
+	left := map[int]bool{}
+	for pid, st := range stats {
+		if st.pgid == root && !seen[pid] {
+			left[pid] = true
+		}
+	}
+	var orphans []int
+	for pid := range left {
+		if !left[stats[pid].ppid] {
+			orphans = append(orphans, pid)
+		}
+	}
+	slices.Sort(orphans)
+	for _, pid := range orphans {
+		walk(pid, 0)
+	}
+	return tree, nil
+}
+
+func procInfo(st *stat, uptime float64, depth int) *models.ProcInfo {
+	info := &models.ProcInfo{
+		Pid:     st.pid,
+		PPid:    st.ppid,
+		Pgid:    st.pgid,
+		User:    procUser(st.pid),
+		State:   st.state,
+		RSS:     st.rss,
+		Command: procCommand(st.pid, st.comm),
+		Depth:   depth,
+	}
+	if elapsed := uptime - st.started; elapsed > 0 {
+		info.CPU = 100 * st.cpu / elapsed
+	}
+	return info
+}
+
+// readStat parses /proc/<pid>/stat
+func readStat(pid int) (*stat, error) {
+	data, err := os.ReadFile(filepath.Join(procDir, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The command name is in parentheses and may itself contain spaces
+	// and parentheses, so the fields are counted from the last one
+	text := string(data)
+	open, end := strings.IndexByte(text, '('), strings.LastIndexByte(text, ')')
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("malformed stat for process %d", pid)
+	}
+	fields := strings.Fields(text[end+1:])
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("malformed stat for process %d", pid)
+	}
+
+	// field returns the nth field of stat(5), counting from 1
+	field := func(n int) int64 {
+		v, _ := strconv.ParseInt(fields[n-3], 10, 64)
+		return v
+	}
+	return &stat{
+		pid:     pid,
+		comm:    text[open+1 : end],
+		state:   fields[0],
+		ppid:    int(field(4)),
+		pgid:    int(field(5)),
+		cpu:     float64(field(14)+field(15)) / clockTicks,
+		started: float64(field(22)) / clockTicks,
+		rss:     field(24) * int64(os.Getpagesize()),
+	}, nil
+}
+
+// readUptime returns the seconds since boot
+func readUptime() (float64, error) {
+	data, err := os.ReadFile(filepath.Join(procDir, "uptime"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read uptime: %w", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("malformed /proc/uptime")
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
+
+// procUser returns the name of the real user of a process
+func procUser(pid int) string {
+	data, err := os.ReadFile(filepath.Join(procDir, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "Uid:" {
+			continue
+		}
+		if u, err := user.LookupId(fields[1]); err == nil {
+			return u.Username
+		}
+		return fields[1]
+	}
+	return ""
+}
+
+// procCommand returns the command line of a process, or its name in
+// brackets when it has none, like ps does
+func procCommand(pid int, comm string) string {
+	data, err := os.ReadFile(filepath.Join(procDir, strconv.Itoa(pid), "cmdline"))
+	if err != nil || len(data) == 0 {
+		return "[" + comm + "]"
+	}
+	return strings.Join(strings.Split(strings.TrimRight(string(data), "\x00"), "\x00"), " ")
+}
@@ -0,0 +1,252 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+// statLine formats /proc/<pid>/stat with the fields the tree reads
+func statLine(pid int, comm, state string, ppid, pgid int) string {
+	return fmt.Sprintf("%d (%s) %s %d %d 0 0 -1 0 0 0 0 0 500 500 0 0 20 0 1 0 90000 1000 25\n",
+		pid, comm, state, ppid, pgid)
+}
+
+// fakeProc points procDir at a temporary directory holding files
+// relative to /proc
+func fakeProc(t *testing.T, files map[string]string) {
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := procDir
+	procDir = dir
+	t.Cleanup(func() { procDir = old })
+}
+
+func TestReadStat(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		comm  string
+		state string
+		ppid  int
+		pgid  int
+		err   bool
+	}{
+		{"plain", statLine(10, "sleep", "S", 1, 10), "sleep", "S", 1, 10, false},
+		{"spaces", statLine(10, "my worker", "R", 2, 3), "my worker", "R", 2, 3, false},
+		{"parentheses", statLine(10, "a) (b) S 9 9", "Z", 4, 5), "a) (b) S 9 9", "Z", 4, 5, false},
+		{"no command", "10 S 1 10 0 0\n", "", "", 0, 0, true},
+		{"too few fields", "10 (sleep) S 1 10 0 0\n", "", "", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeProc(t, map[string]string{"10/stat": tt.data})
+			st, err := readStat(10)
+			if tt.err {
+				if err == nil {
+					t.Errorf("readStat = %+v, want an error", st)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if st.comm != tt.comm || st.state != tt.state || st.ppid != tt.ppid || st.pgid != tt.pgid {
+				t.Errorf("readStat = %q %s ppid %d pgid %d, want %q %s ppid %d pgid %d",
+					st.comm, st.state, st.ppid, st.pgid, tt.comm, tt.state, tt.ppid, tt.pgid)
+			}
+			if st.cpu != 10 || st.started != 900 || st.rss != 25*int64(os.Getpagesize()) {
+				t.Errorf("readStat = cpu %v started %v rss %d", st.cpu, st.started, st.rss)
+			}
+		})
+	}
+}
+
+func TestProcUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		user   string
+	}{
+		{"root", "Name:\tsleep\nUid:\t0\t0\t0\t0\nGid:\t0\t0\t0\t0\n", "root"},
+		{"unknown uid", "Name:\tsleep\nUid:\t64999\t64999\t64999\t64999\n", "64999"},
+		{"no uid", "Name:\tsleep\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeProc(t, map[string]string{"10/status": tt.status})
+			if got := procUser(10); got != tt.user {
+				t.Errorf("procUser = %q, want %q", got, tt.user)
+			}
+		})
+	}
+}
+
+func TestProcCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmdline string
+		command string
+	}{
+		{"arguments", "sleep\x0030\x00", "sleep 30"},
+		{"kernel thread", "", "[kworker]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeProc(t, map[string]string{"10/cmdline": tt.cmdline})
+			if got := procCommand(10, "kworker"); got != tt.command {
+				t.Errorf("procCommand = %q, want %q", got, tt.command)
+			}
+		})
+	}
+}
+
+func TestProcessTree(t *testing.T) {
+	type node struct{ pid, depth int }
+	tests := []struct {
+		name  string
+		stats map[int]string
+		root  int
+		tree  []node
+		err   bool
+	}{
+		{
+			name: "descendants",
+			stats: map[int]string{
+				100: statLine(100, "sh", "S", 1, 100),
+				103: statLine(103, "sleep", "S", 100, 100),
+				101: statLine(101, "sh", "S", 100, 100),
+				102: statLine(102, "sleep", "S", 101, 100),
+				300: statLine(300, "other", "S", 1, 300),
+			},
+			root: 100,
+			tree: []node{{100, 0}, {101, 1}, {102, 2}, {103, 1}},
+		},
+		{
+			name: "orphans in the process group",
+			stats: map[int]string{
+				100: statLine(100, "sh", "S", 1, 100),
+				200: statLine(200, "daemon", "S", 1, 100),
+				201: statLine(201, "worker", "S", 200, 100),
+				300: statLine(300, "other", "S", 1, 300),
+			},
+			root: 100,
+			tree: []node{{100, 0}, {200, 0}, {201, 1}},
+		},
+		{
+			name:  "not running",
+			stats: map[int]string{300: statLine(300, "other", "S", 1, 300)},
+			root:  100,
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{"uptime": "1000.00 4000.00\n", "self/stat": "not a process"}
+			for pid, line := range tt.stats {
+				files[strconv.Itoa(pid)+"/stat"] = line
+			}
+			fakeProc(t, files)
+
+			tree, err := processTree(tt.root)
+			if tt.err {
+				if err == nil {
+					t.Errorf("processTree = %d processes, want an error", len(tree))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []node
+			for _, info := range tree {
+				got = append(got, node{info.Pid, info.Depth})
+				if info.CPU != 10 {
+					t.Errorf("process %d cpu = %v%%, want 10%%", info.Pid, info.CPU)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.tree) {
+				t.Errorf("tree = %v, want %v", got, tt.tree)
+			}
+		})
+	}
+}
+
+func TestTree(t *testing.T) {
+	prog := &models.Program{ID: 400, Name: "forker", Exec: "sleep 30 & sleep 30 & wait"}
+	s := New(nil, "")
+	if _, err := s.Tree(prog); err == nil {
+		t.Error("tree of a stopped program succeeded")
+	}
+
+	p := s.Process(prog)
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.WaitReady(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	pid := p.Snapshot().Pid
+	var tree []*models.ProcInfo
+	for range 50 {
+		var err error
+		if tree, err = s.Tree(prog); err != nil {
+			t.Fatal(err)
+		}
+		if len(tree) == 3 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if len(tree) != 3 {
+		t.Fatalf("tree has %d processes, want 3", len(tree))
+	}
+	if tree[0].Pid != pid || tree[0].Depth != 0 {
+		t.Errorf("tree starts at %d depth %d, want %d depth 0", tree[0].Pid, tree[0].Depth, pid)
+	}
+	for _, info := range tree[1:] {
+		if info.PPid != pid || info.Depth != 1 || info.Command != "sleep 30" {
+			t.Errorf("child %+v, want a sleep 30 under %d", info, pid)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+// Rollout configures a rolling restart
+type Rollout struct {
+	// MaxUnavailable is how many programs are restarted at once
+	MaxUnavailable int
+
+	// WaitReady waits for each batch to become READY before the next one
+	WaitReady bool
+
+	// Timeout bounds the whole rollout
+	Timeout time.Duration
+}
+
+// RollingRestart restarts programs in batches, stopping at the first
+// batch that fails. The progress of the rollout is reported through the
+// Progress of every program taking part in it.
+func (s *Supervisor) RollingRestart(progs []*models.Program, opts Rollout) ([]*Process, error) {
+	size := opts.MaxUnavailable
+	if size < 1 {
+		size = 1
+	}
+
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	procs := make([]*Process, len(progs))
+	for i, prog := range progs {
+		procs[i] = s.Process(prog)
+		procs[i].setProgress(0)
+	}
+
+	total := len(procs)
+	batches := (total + size - 1) / size
+	for start, batch := 0, 1; start < total; start, batch = start+size, batch+1 {
+		end := min(start+size, total)
+		s.log.Info().Int("batch", batch).Int("batches", batches).Msg("rolling restart")
+
+		if err := s.restartBatch(ctx, procs[start:end], opts.WaitReady, batch, batches); err != nil {
+			return procs, fmt.Errorf("rolling restart stopped at batch %d of %d: %w", batch, batches, err)
+		}
+
+		progress := int32(end * 100 / total)
+		for _, proc := range procs {
+			proc.setProgress(progress)
+		}
+	}
+	return procs, nil
+}
+
+func (s *Supervisor) restartBatch(ctx context.Context, procs []*Process, waitReady bool, batch, batches int) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	reason := fmt.Sprintf("rolling restart batch %d of %d", batch, batches)
+	for _, proc := range procs {
+		wg.Add(1)
+		go func(p *Process) {
+			defer wg.Done()
+
+			err := p.restart(reason)
+			if err == nil && waitReady {
+				err = p.WaitReady(ctx)
+			}
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(proc)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
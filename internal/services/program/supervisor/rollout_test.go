@@ -0,0 +1,129 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package supervisor
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+func TestRollingRestart(t *testing.T) {
+	tests := []struct {
+		name    string
+		execs   []string
+		opts    Rollout
+		batches []string // the batch each program restarted in, if any
+		waited  bool     // the last batch started after the first was READY
+		err     bool
+	}{
+		{
+			name:    "batches",
+			execs:   []string{"sleep 30", "sleep 30", "sleep 30"},
+			opts:    Rollout{MaxUnavailable: 2},
+			batches: []string{"batch 1 of 2", "batch 1 of 2", "batch 2 of 2"},
+		},
+		{
+			name:    "one at a time by default",
+			execs:   []string{"sleep 30", "sleep 30"},
+			batches: []string{"batch 1 of 2", "batch 2 of 2"},
+		},
+		{
+			name:    "wait ready",
+			execs:   []string{"sleep 30", "sleep 30"},
+			opts:    Rollout{MaxUnavailable: 1, WaitReady: true},
+			batches: []string{"batch 1 of 2", "batch 2 of 2"},
+			waited:  true,
+		},
+		{
+			name:    "abort on failure",
+			execs:   []string{"exit 1", "sleep 30"},
+			opts:    Rollout{MaxUnavailable: 1, WaitReady: true},
+			batches: []string{"batch 1 of 2", ""},
+			err:     true,
+		},
+		{
+			name:    "timeout",
+			execs:   []string{"sleep 30", "sleep 30"},
+			opts:    Rollout{MaxUnavailable: 1, WaitReady: true, Timeout: 200 * time.Millisecond},
+			batches: []string{"batch 1 of 2", ""},
+			err:     true,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(nil, "")
+			defer s.StopAll()
+
+			var progs []*models.Program
+			for j, exec := range tt.execs {
+				progs = append(progs, &models.Program{
+					ID:      uint(600 + 10*i + j),
+					Name:    tt.name,
+					Exec:    exec,
+					Restart: models.RESTART_NO,
+				})
+			}
+
+			procs, err := s.RollingRestart(progs, tt.opts)
+			if (err != nil) != tt.err {
+				t.Fatalf("RollingRestart error = %v, want error %v", err, tt.err)
+			}
+			if !tt.err {
+				waitState(t, procs[0], models.READY)
+			}
+
+			started := map[int]int64{}
+			ready := map[int]int64{}
+			for j, prog := range progs {
+				transitions, err := models.History(prog.ID, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var batch string
+				for _, tr := range transitions {
+					switch {
+					case tr.To == models.STARTING && strings.HasPrefix(tr.Reason, "rolling restart "):
+						batch, started[j] = strings.TrimPrefix(tr.Reason, "rolling restart "), tr.Timestamp
+					case tr.To == models.READY:
+						ready[j] = tr.Timestamp
+					}
+				}
+				if batch != tt.batches[j] {
+					t.Errorf("%s restarted in %q, want %q", prog.Name, batch, tt.batches[j])
+				}
+			}
+
+			if !tt.err {
+				last := len(progs) - 1
+				if waited := started[last] >= ready[0]; waited != tt.waited {
+					t.Errorf("last batch waited for the first = %v, want %v", waited, tt.waited)
+				}
+				for _, proc := range procs {
+					if progress := proc.Progress(); progress != 100 {
+						t.Errorf("%s progress = %d, want 100", proc.Name(), progress)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package supervisor
+
+import (
+	"sync"
+	"syscall"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rangertaha/hxe/internal/log"
+	"github.com/rangertaha/hxe/internal/services/program/models"
+	"github.com/rs/zerolog"
+)
+
+// Supervisor owns the running processes of all programs
+type Supervisor struct {
+	mu    sync.Mutex
+	procs map[uint]*Process
+	runs  map[uint]*Process
+	crash CrashConfig
+	nc    *nats.Conn
+	agent string
+	log   zerolog.Logger
+}
+
+// New creates a new Supervisor, publishing program events and output on
+// nc under the subjects of the agent
+func New(nc *nats.Conn, agent string) *Supervisor {
+	return &Supervisor{
+		procs: make(map[uint]*Process),
+		runs:  make(map[uint]*Process),
+		crash: DefaultCrashConfig(),
+		nc:    nc,
+		agent: agent,
+		log:   log.With().Str("service", "supervisor").Logger(),
+	}
+}
+
+// Process returns the process of a program, creating it if needed
+func (s *Supervisor) Process(prog *models.Program) *Process {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	proc, ok := s.procs[prog.ID]
+	if !ok {
+		proc = newProcess(prog, s)
+		s.procs[prog.ID] = proc
+	}
+	proc.update(prog)
+	return proc
+}
+
+// Update replaces the definition of a program's process, if it has one,
+// taking effect on its next start
+func (s *Supervisor) Update(prog *models.Program) {
+	if proc, ok := s.Lookup(prog.ID); ok {
+		proc.update(prog)
+	}
+}
+
+// Lookup returns the process of a program if it has one
+func (s *Supervisor) Lookup(id uint) (*Process, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	proc, ok := s.procs[id]
+	return proc, ok
+}
+
+// Start a program
+func (s *Supervisor) Start(prog *models.Program) (*Process, error) {
+	proc := s.Process(prog)
+	return proc, proc.Start()
+}
+
+// Stop a program
+func (s *Supervisor) Stop(prog *models.Program) (*Process, error) {
+	proc := s.Process(prog)
+	return proc, proc.Stop()
+}
+
+// Restart a program
+func (s *Supervisor) Restart(prog *models.Program) (*Process, error) {
+	proc := s.Process(prog)
+	return proc, proc.Restart()
+}
+
+// Signal sends a signal to a program's process, or its process group
+func (s *Supervisor) Signal(prog *models.Program, sig syscall.Signal, group bool) (*Process, error) {
+	proc := s.Process(prog)
+	return proc, proc.Signal(sig, group)
+}
+
+// Reload sends a program its reload signal
+func (s *Supervisor) Reload(prog *models.Program) (*Process, error) {
+	proc := s.Process(prog)
+	return proc, proc.Reload()
+}
+
+// Remove stops a program and forgets its process
+func (s *Supervisor) Remove(prog *models.Program) (*models.Status, error) {
+	proc, ok := s.Lookup(prog.ID)
+	if !ok {
+		return s.Status(prog), nil
+	}
+	if err := proc.Stop(); err != nil {
+		return proc.Snapshot(), err
+	}
+
+	s.mu.Lock()
+	delete(s.procs, prog.ID)
+	s.mu.Unlock()
+	return proc.Snapshot(), nil
+}
+
+// Status returns a snapshot of a program's process
+func (s *Supervisor) Status(prog *models.Program) *models.Status {
+	if proc, ok := s.Lookup(prog.ID); ok {
+		return proc.Snapshot()
+	}
+	return &models.Status{ProgramID: prog.ID, Name: prog.Name, State: models.STOPPED}
+}
+
+// StopAll stops every supervised process
+func (s *Supervisor) StopAll() {
+	s.mu.Lock()
+	procs := make([]*Process, 0, len(s.procs))
+	for _, proc := range s.procs {
+		procs = append(procs, proc)
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, proc := range procs {
+		wg.Add(1)
+		go func(p *Process) {
+			defer wg.Done()
+			if err := p.Stop(); err != nil {
+				s.log.Error().Err(err).Str("program", p.Name()).Msg("failed to stop program")
+			}
+		}(proc)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,224 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package supervisor
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/rangertaha/hxe/internal/services/program/models"
+)
+
+// lineBuffer is how many output lines may wait for the matcher before
+// new lines are dropped, so that matching and publishing never slow the
+// output down
+const lineBuffer = 1024
+
+type line struct {
+	stream string
+	text   string
+	time   int64
+}
+
+type rule struct {
+	models.Trigger
+	re   *regexp.Regexp
+	last time.Time
+}
+
+// matcher runs a program's triggers against its output lines and
+// publishes them, off the goroutines that read the output
+type matcher struct {
+	proc    *Process
+	id      uint
+	name    string
+	publish bool
+	rules   []*rule
+	lines   chan line
+	dropped atomic.Int64
+}
+
+// newMatcher compiles the triggers of a program, returning nil if it has
+// none and there is no bus to publish its output on. The lock must be
+// held.
+func newMatcher(p *Process, triggers []models.Trigger) *matcher {
+	m := &matcher{
+		proc:    p,
+		id:      p.prog.ID,
+		name:    p.prog.Name,
+		publish: p.nc != nil,
+		lines:   make(chan line, lineBuffer),
+	}
+	for _, t := range triggers {
+		re, err := regexp.Compile(t.Pattern)
+		if err != nil {
+			p.log.Error().Err(err).Str("trigger", t.Name).Msg("invalid trigger pattern")
+			continue
+		}
+		m.rules = append(m.rules, &rule{Trigger: t, re: re})
+	}
+	if len(m.rules) == 0 && !m.publish {
+		return nil
+	}
+	return m
+}
+
+// feed queues a line for matching and publishing without blocking the
+// output
+func (m *matcher) feed(stream, text string) {
+	if m == nil {
+		return
+	}
+	select {
+	case m.lines <- line{stream, text, time.Now().UnixMilli()}:
+	default:
+		m.dropped.Add(1)
+	}
+}
+
+// run matches lines until the process is done
+func (m *matcher) run(done chan struct{}) {
+	for {
+		select {
+		case l := <-m.lines:
+			m.handle(l)
+		case <-done:
+			for {
+				select {
+				case l := <-m.lines:
+					m.handle(l)
+				default:
+					if dropped := m.dropped.Load(); dropped > 0 {
+						m.proc.log.Warn().Int64("lines", dropped).Msg("output fell behind and skipped lines")
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// handle publishes a line and runs the triggers that match it
+func (m *matcher) handle(l line) {
+	if m.publish {
+		m.proc.output(&models.Line{ProgramID: m.id, Name: m.name, Stream: l.stream, Line: l.text, Timestamp: l.time})
+	}
+	m.match(l)
+}
+
+func (m *matcher) match(l line) {
+	for _, r := range m.rules {
+		if r.Stream != "" && r.Stream != l.stream {
+			continue
+		}
+		if !r.re.MatchString(l.text) {
+			continue
+		}
+		if r.RateLimit > 0 && time.Since(r.last) < r.RateLimit {
+			continue
+		}
+		r.last = time.Now()
+		m.fire(r, l)
+	}
+}
+
+// fire runs the action of a matched trigger
+func (m *matcher) fire(r *rule, l line) {
+	p := m.proc
+	reason := fmt.Sprintf("trigger %s matched %s", r.Name, l.stream)
+	p.log.Info().Str("trigger", r.Name).Str("action", string(r.Action)).Str("line", l.text).Msg("trigger matched")
+
+	switch r.Action {
+	case models.TRIGGER_RESTART:
+		go func() {
+			if err := p.restart(reason); err != nil {
+				p.log.Error().Err(err).Str("trigger", r.Name).Msg("trigger failed to restart program")
+			}
+		}()
+	case models.TRIGGER_STOP:
+		go func() {
+			if err := p.stop(reason); err != nil {
+				p.log.Error().Err(err).Str("trigger", r.Name).Msg("trigger failed to stop program")
+			}
+		}()
+	case models.TRIGGER_SIGNAL:
+		sig, err := ParseSignal(r.Signal)
+		if err != nil {
+			p.log.Error().Err(err).Str("trigger", r.Name).Msg("invalid trigger signal")
+			return
+		}
+		p.mu.Lock()
+		p.signal(sig)
+		p.mu.Unlock()
+	case models.TRIGGER_PUBLISH:
+		p.mu.Lock()
+		event := &models.Event{
+			Type:      models.EVENT_TRIGGER,
+			ProgramID: p.prog.ID,
+			Name:      p.prog.Name,
+			Trigger:   r.Name,
+			Stream:    l.stream,
+			Line:      l.text,
+			Timestamp: time.Now().UnixMilli(),
+		}
+		p.mu.Unlock()
+		if r.Subject == "" {
+			p.event(event)
+		} else {
+			p.publish(r.Subject, event)
+		}
+	case models.TRIGGER_EXEC:
+		go func() {
+			cmd := exec.Command("/bin/sh", "-c", r.Command)
+			p.mu.Lock()
+			err := p.prepare(cmd)
+			p.mu.Unlock()
+			if err != nil {
+				p.log.Error().Err(err).Str("trigger", r.Name).Msg("trigger command failed")
+				return
+			}
+			cmd.Env = append(cmd.Env, "HXE_TRIGGER="+r.Name, "HXE_LINE="+l.text)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				p.log.Error().Err(err).Str("trigger", r.Name).Str("output", string(out)).Msg("trigger command failed")
+			}
+		}()
+	case models.TRIGGER_READY:
+		p.mu.Lock()
+		if p.state == models.RUNNING {
+			p.retries = 0
+			p.transition(models.READY, reason)
+		}
+		p.mu.Unlock()
+	default:
+		p.log.Error().Str("trigger", r.Name).Msgf("unknown trigger action: %s", r.Action)
+	}
+}
+
+// readyTrigger reports whether readiness is signalled by a trigger
+func readyTrigger(triggers []models.Trigger) bool {
+	for _, t := range triggers {
+		if t.Action == models.TRIGGER_READY {
+			return true
+		}
+	}
+	return false
+}
@@ -4,11 +4,13 @@ import (
 	"fmt"
 
 	"github.com/nats-io/nats.go"
+	"github.com/rangertaha/hxe/internal/config"
 	"github.com/rangertaha/hxe/internal/interfaces"
 )
 
 
-type Creator func(nc *nats.Conn) interfaces.Service
+// Creator creates a service from its configuration block
+type Creator func(nc *nats.Conn, cfg *config.Service) interfaces.Service
 
 var Services = map[string]Creator{}
 
@@ -23,3 +25,17 @@ func Get(name string) (Creator, error) {
 
 	return nil, fmt.Errorf("unable to locate service: %s", name)
 }
+
+// Change is something a configuration reload changed in a service
+type Change struct {
+	Service string   `json:"service"`
+	Kind    string   `json:"kind"`
+	Name    string   `json:"name"`
+	Fields  []string `json:"fields,omitempty"`
+}
+
+// Reloader is a service that takes a new configuration block on reload
+// and reports what the reload changed
+type Reloader interface {
+	ReloadConfig(cfg *config.Service) ([]Change, error)
+}
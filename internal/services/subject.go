@@ -0,0 +1,42 @@
+/*
+ * HXE - Host-based Process Execution Engine
+ * Copyright (C) 2025 Rangertaha <rangertaha@gmail.com>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package services
+
+// SUBJECT_PREFIX namespaces the endpoints of every agent by its ID, e.g.
+// hxe.web-03.program.list
+const SUBJECT_PREFIX = "hxe"
+
+// Subject returns the subject of an endpoint of an agent. Without an
+// agent it is the plain subject, answered by the agent embedded in the
+// server the client is connected to.
+func Subject(agent, subject string) string {
+	if agent == "" {
+		return subject
+	}
+	return SUBJECT_PREFIX + "." + agent + "." + subject
+}
+
+// Groups returns the names of the endpoint groups a service registers:
+// the plain group and the group namespaced by the agent ID
+func Groups(agent, group string) []string {
+	if agent == "" {
+		return []string{group}
+	}
+	return []string{group, Subject(agent, group)}
+}